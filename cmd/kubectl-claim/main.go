@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const usage = `Inspect claims managed by claim-controller.
+
+Usage:
+  kubectl claim get [id] [--all] [-A]
+  kubectl claim describe <id>
+  kubectl claim release <id> --server <api url> [--token <token>]
+
+Flags:
+`
+
+type resourceReadiness struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Ready     bool   `json:"ready"`
+	Message   string `json:"message"`
+}
+
+func main() {
+	flags := flag.NewFlagSet("kubectl-claim", flag.ExitOnError)
+	kubeconfig := flags.String("kubeconfig", "", "path to the kubeconfig file")
+	kubeContext := flags.String("context", "", "kubeconfig context to use")
+	namespace := flags.String("namespace", "", "namespace of the claims (defaults to the context namespace)")
+	flags.StringVar(namespace, "n", "", "shorthand for --namespace")
+	allNamespaces := flags.Bool("all-namespaces", false, "list claims across all namespaces")
+	flags.BoolVar(allNamespaces, "A", false, "shorthand for --all-namespaces")
+	includePool := flags.Bool("all", false, "include pre-provisioned pool claims")
+	server := flags.String("server", os.Getenv("CLAIM_CONTROLLER_URL"), "claim API base URL used by release (defaults to $CLAIM_CONTROLLER_URL)")
+	token := flags.String("token", os.Getenv("CLAIM_CONTROLLER_TOKEN"), "bearer token or API key for the claim API (defaults to $CLAIM_CONTROLLER_TOKEN)")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if len(os.Args) < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	args := parseInterspersed(flags, os.Args[2:])
+
+	// Releasing goes through the API so quotas, audit, tombstones and
+	// notifications see it; it does not need the kubeconfig.
+	if command == "release" {
+		if len(args) != 1 {
+			fail(fmt.Errorf("exactly one claim id is required"))
+		}
+		if *server == "" {
+			fail(fmt.Errorf("--server (or CLAIM_CONTROLLER_URL) is required to release a claim"))
+		}
+		if err := releaseClaim(context.Background(), *server, *token, args[0]); err != nil {
+			fail(err)
+		}
+		fmt.Printf("claim %s released\n", args[0])
+		return
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = *kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: *kubeContext})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		fail(fmt.Errorf("load kubeconfig: %w", err))
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		fail(fmt.Errorf("create kube client: %w", err))
+	}
+	if *namespace == "" {
+		if *namespace, _, err = clientConfig.Namespace(); err != nil {
+			fail(fmt.Errorf("resolve namespace: %w", err))
+		}
+	}
+	if *allNamespaces {
+		*namespace = metav1.NamespaceAll
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "get":
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+		claims, err := listClaims(ctx, kubeClient, *namespace, id)
+		if err != nil {
+			fail(err)
+		}
+		printClaims(os.Stdout, claims, *includePool || id != "", *allNamespaces)
+	case "describe":
+		claims := requireClaims(ctx, kubeClient, *namespace, args)
+		for i := range claims {
+			if i > 0 {
+				fmt.Println()
+			}
+			describeClaim(os.Stdout, &claims[i])
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+func releaseClaim(ctx context.Context, server, token, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoint, err := url.JoinPath(server, "v1", "release", url.PathEscape(id))
+	if err != nil {
+		return fmt.Errorf("build release URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("build release request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("release claim %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var problem struct {
+			Detail string `json:"detail"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&problem)
+		if problem.Detail == "" {
+			problem.Detail = resp.Status
+		}
+		return fmt.Errorf("release claim %s: %s", id, problem.Detail)
+	}
+	return nil
+}
+
+func parseInterspersed(flags *flag.FlagSet, arguments []string) []string {
+	var args []string
+	for {
+		_ = flags.Parse(arguments)
+		arguments = flags.Args()
+		if len(arguments) == 0 {
+			return args
+		}
+		args = append(args, arguments[0])
+		arguments = arguments[1:]
+	}
+}
+
+func requireClaims(ctx context.Context, kubeClient kubernetes.Interface, namespace string, args []string) []corev1.ConfigMap {
+	if len(args) != 1 {
+		fail(fmt.Errorf("exactly one claim id is required"))
+	}
+	claims, err := listClaims(ctx, kubeClient, namespace, args[0])
+	if err != nil {
+		fail(err)
+	}
+	if len(claims) == 0 {
+		fail(fmt.Errorf("claim %q not found", args[0]))
+	}
+	return claims
+}
+
+func listClaims(ctx context.Context, kubeClient kubernetes.Interface, namespace, id string) ([]corev1.ConfigMap, error) {
+	selector := labels.Set{controller.ManagedByLabelKey: controller.ManagedByLabelValue}
+	if id != "" {
+		selector[controller.ClaimLabelKeyId] = id
+	}
+	list, err := kubeClient.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("list claims: %w", err)
+	}
+	claims := list.Items
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[i].CreationTimestamp.Before(&claims[j].CreationTimestamp)
+	})
+	return claims, nil
+}
+
+func printClaims(out io.Writer, claims []corev1.ConfigMap, includePool, withNamespace bool) {
+	w := tabwriter.NewWriter(out, 0, 4, 3, ' ', 0)
+	defer w.Flush()
+
+	if withNamespace {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "ID\tSTATUS\tPROFILE\tOWNER\tEXPIRES IN\tAGE")
+	now := time.Now()
+	for i := range claims {
+		claim := &claims[i]
+		if isPreProvisioned(claim) && !includePool {
+			continue
+		}
+		if withNamespace {
+			fmt.Fprintf(w, "%s\t", claim.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			orNone(claim.Labels[controller.ClaimLabelKeyId]),
+			claimStatus(claim),
+			orNone(claim.Labels[controller.ProfileLabelKey]),
+			orNone(claim.Annotations[controller.OwnerAnnotationKey]),
+			expiresIn(claim, now),
+			humanDuration(now.Sub(claim.CreationTimestamp.Time)),
+		)
+	}
+}
+
+func describeClaim(out io.Writer, claim *corev1.ConfigMap) {
+	now := time.Now()
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", name, value)
+		}
+	}
+	field("ID", claim.Labels[controller.ClaimLabelKeyId])
+	field("Name", claim.Name)
+	field("Namespace", claim.Namespace)
+	field("Status", claimStatus(claim))
+	field("Message", claim.Data[controller.ClaimStatusMessageDataKey])
+	field("Profile", claim.Labels[controller.ProfileLabelKey])
+	field("Team", claim.Labels[controller.TeamLabelKey])
+	field("Tenant", claim.Labels[controller.TenantLabelKey])
+	field("Owner", claim.Annotations[controller.OwnerAnnotationKey])
+	field("Cluster", claim.Annotations[controller.ClusterAnnotationKey])
+	field("Claim namespace", claim.Annotations[controller.ClaimNamespaceAnnotationKey])
+	field("Pre-provisioned", claim.Annotations[controller.PreProvisionedAnnotationKey])
+	field("Created", fmt.Sprintf("%s (%s ago)", claim.CreationTimestamp.UTC().Format(time.RFC3339), humanDuration(now.Sub(claim.CreationTimestamp.Time))))
+	field("Claimed at", claim.Annotations[controller.ClaimedAtAnnotationKey])
+	field("Start at", claim.Annotations[controller.StartAtAnnotationKey])
+	if expiresAt := claim.Annotations[controller.ExpiresAtAnnotationKey]; expiresAt != "" {
+		field("Expires at", fmt.Sprintf("%s (in %s)", expiresAt, expiresIn(claim, now)))
+	}
+	field("Suspended until", claim.Annotations[controller.SuspendedUntilAnnotationKey])
+	field("Released at", claim.Annotations[controller.ReleasedAtAnnotationKey])
+	field("Template", strings.TrimSpace(claim.Annotations[controller.TemplateSourceAnnotationKey]+" "+claim.Annotations[controller.TemplateHashAnnotationKey]))
+	field("Values", strings.TrimSpace(claim.Annotations[controller.ValuesSourceAnnotationKey]+" "+claim.Annotations[controller.ValuesHashAnnotationKey]))
+	if claim.DeletionTimestamp != nil {
+		field("Deleting since", claim.DeletionTimestamp.UTC().Format(time.RFC3339))
+		field("Finalizers", strings.Join(claim.Finalizers, ", "))
+	}
+	w.Flush()
+
+	var returnValues map[string]string
+	if raw := strings.TrimSpace(claim.Data[controller.ReturnValuesDataKey]); raw != "" && json.Unmarshal([]byte(raw), &returnValues) == nil && len(returnValues) > 0 {
+		fmt.Fprintln(out, "Return values:")
+		keys := make([]string, 0, len(returnValues))
+		for key := range returnValues {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(out, "  %s: %s\n", key, returnValues[key])
+		}
+	}
+
+	var resources []resourceReadiness
+	if raw := strings.TrimSpace(claim.Data[controller.ClaimResourcesStatusDataKey]); raw != "" && json.Unmarshal([]byte(raw), &resources) == nil && len(resources) > 0 {
+		fmt.Fprintln(out, "Resources:")
+		w := tabwriter.NewWriter(out, 0, 4, 3, ' ', 0)
+		fmt.Fprintln(w, "  KIND\tNAME\tREADY\tMESSAGE")
+		for _, resource := range resources {
+			name := resource.Name
+			if resource.Namespace != "" {
+				name = resource.Namespace + "/" + name
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%t\t%s\n", resource.Kind, name, resource.Ready, resource.Message)
+		}
+		w.Flush()
+	}
+}
+
+func claimStatus(claim *corev1.ConfigMap) string {
+	if claim.DeletionTimestamp != nil {
+		return controller.ClaimStatusDeleting
+	}
+	if status := claim.Data[controller.ClaimStatusDataKey]; status != "" {
+		return status
+	}
+	return "pending"
+}
+
+func isPreProvisioned(claim *corev1.ConfigMap) bool {
+	return strings.EqualFold(claim.Annotations[controller.PreProvisionedAnnotationKey], "true")
+}
+
+func expiresIn(claim *corev1.ConfigMap, now time.Time) string {
+	expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey])
+	if err != nil {
+		return "<none>"
+	}
+	if !expiresAt.After(now) {
+		return "expired"
+	}
+	return humanDuration(expiresAt.Sub(now))
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%02dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	os.Exit(1)
+}
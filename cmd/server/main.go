@@ -4,16 +4,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
 	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -21,78 +28,367 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/nonot/claim-controller/internal/api"
+	"github.com/nonot/claim-controller/internal/audit"
+	"github.com/nonot/claim-controller/internal/cluster"
 	"github.com/nonot/claim-controller/internal/config"
 	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/helmrelease"
+	"github.com/nonot/claim-controller/internal/notify"
+	"github.com/nonot/claim-controller/internal/template"
 	"github.com/nonot/claim-controller/internal/values"
 )
 
+const (
+	defaultNamespace         = "default"
+	defaultTemplatePath      = "config/template/resources.yaml"
+	defaultValuesPath        = "/values/values.yaml"
+	defaultAPIAddr           = "0.0.0.0:8080"
+	defaultMetricsAddr       = "0.0.0.0:8081"
+	defaultProbeAddr         = "0.0.0.0:8082"
+	defaultTTLValue          = 3 * time.Minute
+	defaultMaxTTLValue       = 10 * time.Minute
+	defaultPreProvisionCount = 0
+	defaultReconcileInterval = 30 * time.Second
+	defaultHTTPProbeTimeout  = 5 * time.Second
+	defaultReleaseWait       = 2 * time.Minute
+	defaultClaimReadyWait    = 2 * time.Minute
+)
+
 func main() {
 	var (
 		configPath          string
 		namespace           string
+		watchNamespaces     string
 		templatePath        string
+		templateRenderer    string
+		resourceBackend     string
+		templateRef         string
+		templateCacheDir    string
+		templateRegSecret   string
+		profilesPath        string
+		quotasPath          string
+		tenantsPath         string
+		credentialKeys      string
 		valuesPath          string
 		valuesConfigMapName string
 		valuesConfigMapKey  string
+		valuesMerge         bool
+		valuesSchemaPath    string
+		valuesAllowlist     string
 		apiAddr             string
+		grpcAddr            string
+		apiTLSCert          string
+		apiTLSKey           string
+		apiClientCA         string
 		metricsAddr         string
 		defaultTTL          time.Duration
 		maxTTL              time.Duration
 		preProvisionCount   int
+		maxActiveClaims     int
+		poolReserve         int
+		poolMaxIdleAge      time.Duration
+		provisioningMode    string
+		poolReplenishEvery  time.Duration
+		claimQueueTimeout   time.Duration
+		namespacePerClaim   bool
+		rerenderRate        int
+		idempotencyKeyTTL   time.Duration
+		claimRatePerMinute  int
+		claimRateBurst      int
 		reconcileInterval   time.Duration
+		configReloadEvery   time.Duration
+		httpProbeTimeout    time.Duration
+		crashLoopRestarts   int
+		failedRetention     time.Duration
+		releaseWaitTimeout  time.Duration
+		readyTimeout        time.Duration
+		maxReadyTimeout     time.Duration
+		releaseRetention    time.Duration
+		historyRetention    time.Duration
+		maxSuspendDuration  time.Duration
+		maxStartAtHorizon   time.Duration
+		expiryWarning       time.Duration
+		workingHours        string
+		expiryJitter        time.Duration
+		workingHoursTZ      string
+		webhookURLs         string
+		webhookSecret       string
+		slackWebhookURL     string
+		slackEvents         string
+		publicURL           string
+		apiKeys             string
+		authTokenReview     bool
+		authAudiences       string
+		authRoutes          string
+		adminSubjects       string
+		oidcIssuer          string
+		oidcAudience        string
+		oidcUsernameClaim   string
 		probeAddr           string
+		pprofAddr           string
+		apiDocs             bool
+		accessLog           bool
+		accessLogProbes     bool
+		auditLog            bool
+		auditWebhookURL     string
+		maxRequestBodyBytes int
+		renderCheckEvery    time.Duration
+		drainTimeout        time.Duration
+		corsAllowedOrigins  string
+		corsAllowedMethods  string
+		corsAllowedHeaders  string
 		controllerLogLevel  int
 	)
 
-	const (
-		defaultNamespace         = "default"
-		defaultTemplatePath      = "config/template/resources.yaml"
-		defaultValuesPath        = "/values/values.yaml"
-		defaultAPIAddr           = "0.0.0.0:8080"
-		defaultMetricsAddr       = "0.0.0.0:8081"
-		defaultProbeAddr         = "0.0.0.0:8082"
-		defaultTTLValue          = 3 * time.Minute
-		defaultMaxTTLValue       = 10 * time.Minute
-		defaultPreProvisionCount = 0
-		defaultReconcileInterval = 30 * time.Second
-	)
+	configPath = configPathFromArgs(os.Args[1:], getEnv("CONFIG_PATH", ""))
+	fileConfig, err := config.Load(configPath)
+	if err != nil {
+		panic(err)
+	}
+
+	var problems configProblems
+	namespaceDefault := resolveString("NAMESPACE", fileConfig.Namespace, defaultNamespace)
+	watchNamespacesDefault := resolveString("WATCH_NAMESPACES", fileConfig.WatchNamespaces, "")
+	valuesPathDefault := resolveString("VALUES_PATH", fileConfig.ValuesPath, defaultValuesPath)
+	valuesConfigMapNameDefault := resolveString("VALUES_CONFIGMAP_NAME", fileConfig.ValuesConfigMapName, "")
+	valuesConfigMapKeyDefault := resolveString("VALUES_CONFIGMAP_KEY", fileConfig.ValuesConfigMapKey, "")
+	valuesSchemaPathDefault := resolveString("VALUES_SCHEMA_PATH", fileConfig.ValuesSchemaPath, "")
+	valuesAllowlistDefault := resolveString("VALUES_OVERRIDE_ALLOWLIST", fileConfig.ValuesOverrideAllowlist, "")
+	valuesMergeDefault := problems.resolveBool("VALUES_MERGE", fileConfig.ValuesMerge, false)
+	templatePathDefault := resolveString("TEMPLATE_PATH", fileConfig.TemplatePath, defaultTemplatePath)
+	credentialKeysDefault := resolveString("CREDENTIAL_KEYS", fileConfig.CredentialKeys, "")
+	quotasPathDefault := resolveString("QUOTAS_PATH", fileConfig.QuotasPath, "")
+	tenantsPathDefault := resolveString("TENANTS_PATH", fileConfig.TenantsPath, "")
+	profilesPathDefault := resolveString("PROFILES_PATH", fileConfig.ProfilesPath, "")
+	templateRendererDefault := resolveString("TEMPLATE_RENDERER", fileConfig.TemplateRenderer, template.RendererHelm)
+	resourceBackendDefault := resolveString("RESOURCE_BACKEND", fileConfig.ResourceBackend, helmrelease.BackendApply)
+	templateRefDefault := resolveString("TEMPLATE_REF", fileConfig.TemplateRef, "")
+	templateCacheDirDefault := resolveString("TEMPLATE_CACHE_DIR", fileConfig.TemplateCacheDir, "")
+	templateRegSecretDefault := resolveString("TEMPLATE_REGISTRY_SECRET", fileConfig.TemplateRegistrySecret, "")
+	apiAddrDefault := resolveString("API_ADDR", fileConfig.APIAddr, defaultAPIAddr)
+	grpcAddrDefault := resolveString("GRPC_ADDR", fileConfig.GRPCAddr, "")
+	apiTLSCertDefault := resolveString("API_TLS_CERT", fileConfig.APITLSCert, "")
+	apiTLSKeyDefault := resolveString("API_TLS_KEY", fileConfig.APITLSKey, "")
+	apiClientCADefault := resolveString("API_CLIENT_CA", fileConfig.APIClientCA, "")
+	metricsAddrDefault := resolveString("METRICS_ADDR", fileConfig.MetricsAddr, defaultMetricsAddr)
+	probeAddrDefault := resolveString("PROBE_ADDR", fileConfig.ProbeAddr, defaultProbeAddr)
+	pprofAddrDefault := resolveString("PPROF_ADDR", fileConfig.PprofAddr, "")
+	defaultTTLDefault := problems.resolveDuration("DEFAULT_TTL", fileConfig.DefaultTTL, defaultTTLValue)
+	maxTTLDefault := problems.resolveDuration("MAX_TTL", fileConfig.MaxTTL, defaultMaxTTLValue)
+	preProvisionCountDefault := problems.resolveInt("PRE_PROVISION_CLAIMS_COUNT", fileConfig.PreProvisionClaimsCount, defaultPreProvisionCount)
+	maxActiveClaimsDefault := problems.resolveInt("MAX_ACTIVE_CLAIMS", fileConfig.MaxActiveClaims, 0)
+	poolReserveDefault := problems.resolveInt("POOL_RESERVE", fileConfig.PoolReserve, 0)
+	poolMaxIdleAgeDefault := problems.resolveDuration("POOL_MAX_IDLE_AGE", fileConfig.PoolMaxIdleAge, 0)
+	provisioningModeDefault := resolveString("PROVISIONING_MODE", fileConfig.ProvisioningMode, string(api.ProvisioningModeHybrid))
+	poolReplenishEveryDefault := problems.resolveDuration("POOL_REPLENISH_INTERVAL", fileConfig.PoolReplenishInterval, 15*time.Second)
+	claimQueueTimeoutDefault := problems.resolveDuration("CLAIM_QUEUE_TIMEOUT", fileConfig.ClaimQueueTimeout, 0)
+	rerenderRateDefault := problems.resolveInt("RERENDER_RATE_PER_MINUTE", fileConfig.RerenderRatePerMinute, 30)
+	namespacePerClaimDefault := problems.resolveBool("NAMESPACE_PER_CLAIM", fileConfig.NamespacePerClaim, false)
+	idempotencyKeyTTLDefault := problems.resolveDuration("IDEMPOTENCY_KEY_TTL", fileConfig.IdempotencyKeyTTL, 24*time.Hour)
+	claimRatePerMinuteDefault := problems.resolveInt("CLAIM_RATE_LIMIT_PER_MINUTE", fileConfig.ClaimRateLimitPerMinute, 0)
+	claimRateBurstDefault := problems.resolveInt("CLAIM_RATE_LIMIT_BURST", fileConfig.ClaimRateLimitBurst, 5)
+	reconcileIntervalDefault := problems.resolveDuration("RECONCILE_INTERVAL", fileConfig.ReconcileInterval, defaultReconcileInterval)
+	configReloadEveryDefault := problems.resolveDuration("CONFIG_RELOAD_INTERVAL", fileConfig.ConfigReloadInterval, 30*time.Second)
+	releaseWaitTimeoutDefault := problems.resolveDuration("RELEASE_WAIT_TIMEOUT", fileConfig.ReleaseWaitTimeout, defaultReleaseWait)
+	readyTimeoutDefault := problems.resolveDuration("CLAIM_READY_TIMEOUT", fileConfig.ClaimReadyTimeout, defaultClaimReadyWait)
+	maxReadyTimeoutDefault := problems.resolveDuration("MAX_CLAIM_READY_TIMEOUT", fileConfig.MaxClaimReadyTimeout, 10*time.Minute)
+	releaseRetentionDefault := problems.resolveDuration("RELEASE_RETENTION", fileConfig.ReleaseRetention, time.Hour)
+	historyRetentionDefault := problems.resolveDuration("CLAIM_HISTORY_RETENTION", fileConfig.ClaimHistoryRetention, 24*time.Hour)
+	maxSuspendDurationDefault := problems.resolveDuration("MAX_SUSPEND_DURATION", fileConfig.MaxSuspendDuration, 24*time.Hour)
+	maxStartAtHorizonDefault := problems.resolveDuration("MAX_START_AT_HORIZON", fileConfig.MaxStartAtHorizon, 7*24*time.Hour)
+	webhookURLsDefault := resolveString("WEBHOOK_URLS", fileConfig.WebhookURLs, "")
+	webhookSecretDefault := resolveString("WEBHOOK_SECRET", fileConfig.WebhookSecret, "")
+	slackWebhookURLDefault := resolveString("SLACK_WEBHOOK_URL", fileConfig.SlackWebhookURL, "")
+	slackEventsDefault := resolveString("SLACK_EVENTS", fileConfig.SlackEvents, strings.Join(notify.DefaultSlackEvents, ","))
+	publicURLDefault := resolveString("PUBLIC_URL", fileConfig.PublicURL, "")
+	apiKeysDefault := resolveString("API_KEYS", fileConfig.APIKeys, "")
+	apiDocsDefault := problems.resolveBool("API_DOCS", fileConfig.APIDocs, false)
+	accessLogDefault := problems.resolveBool("ACCESS_LOG", fileConfig.AccessLog, true)
+	accessLogProbesDefault := problems.resolveBool("ACCESS_LOG_PROBES", fileConfig.AccessLogProbes, false)
+	auditLogDefault := problems.resolveBool("AUDIT_LOG", fileConfig.AuditLog, true)
+	auditWebhookURLDefault := resolveString("AUDIT_WEBHOOK_URL", fileConfig.AuditWebhookURL, "")
+	maxRequestBodyBytesDefault := problems.resolveInt("MAX_REQUEST_BODY_BYTES", fileConfig.MaxRequestBodyBytes, 1<<20)
+	drainTimeoutDefault := problems.resolveDuration("SHUTDOWN_DRAIN_TIMEOUT", fileConfig.ShutdownDrainTimeout, 20*time.Second)
+	renderCheckEveryDefault := problems.resolveDuration("RENDER_CHECK_INTERVAL", fileConfig.RenderCheckInterval, time.Minute)
+	corsAllowedOriginsDefault := resolveString("CORS_ALLOWED_ORIGINS", fileConfig.CORSAllowedOrigins, "")
+	corsAllowedMethodsDefault := resolveString("CORS_ALLOWED_METHODS", fileConfig.CORSAllowedMethods, "")
+	corsAllowedHeadersDefault := resolveString("CORS_ALLOWED_HEADERS", fileConfig.CORSAllowedHeaders, "")
+	authTokenReviewDefault := problems.resolveBool("AUTH_TOKEN_REVIEW", fileConfig.AuthTokenReview, false)
+	authAudiencesDefault := resolveString("AUTH_TOKEN_AUDIENCES", fileConfig.AuthTokenAudiences, "")
+	authRoutesDefault := resolveString("AUTH_ROUTES", fileConfig.AuthRoutes, "claim,release,renew,claims,admin")
+	adminSubjectsDefault := resolveString("ADMIN_SUBJECTS", fileConfig.AdminSubjects, "")
+	oidcIssuerDefault := resolveString("OIDC_ISSUER_URL", fileConfig.OIDCIssuerURL, "")
+	oidcAudienceDefault := resolveString("OIDC_AUDIENCE", fileConfig.OIDCAudience, "")
+	oidcUsernameClaimDefault := resolveString("OIDC_USERNAME_CLAIM", fileConfig.OIDCUsernameClaim, "sub")
+	expiryWarningDefault := problems.resolveDuration("EXPIRY_WARNING_WINDOW", fileConfig.ExpiryWarningWindow, 0)
+	expiryJitterDefault := problems.resolveDuration("EXPIRY_JITTER", fileConfig.ExpiryJitter, 0)
+	workingHoursDefault := resolveString("WORKING_HOURS", fileConfig.WorkingHours, "")
+	workingHoursTZDefault := resolveString("WORKING_HOURS_TIMEZONE", fileConfig.WorkingHoursTimezone, "UTC")
+	httpProbeTimeoutDefault := problems.resolveDuration("HTTP_PROBE_TIMEOUT", fileConfig.HTTPProbeTimeout, defaultHTTPProbeTimeout)
+	crashLoopRestartsDefault := problems.resolveInt("CRASHLOOP_RESTART_LIMIT", fileConfig.CrashLoopRestartLimit, 5)
+	failedRetentionDefault := problems.resolveDuration("FAILED_CLAIM_RETENTION", fileConfig.FailedClaimRetention, 0)
+	controllerLogLevelDefault := problems.resolveInt("ZAP_LOG_LEVEL", fileConfig.ZapLogLevel, 0)
 
-	bootstrap := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
-	bootstrap.StringVar(&configPath, "config", getEnv("CONFIG_PATH", ""), "path to YAML/JSON config file")
-	_ = bootstrap.Parse(os.Args[1:])
-
-	namespaceDefault := resolveString("NAMESPACE", os.Getenv("NAMESPACE"), defaultNamespace)
-	valuesPathDefault := resolveString("VALUES_PATH", os.Getenv("VALUES_PATH"), defaultValuesPath)
-	valuesConfigMapNameDefault := resolveString("VALUES_CONFIGMAP_NAME", os.Getenv("VALUES_CONFIGMAP_NAME"), "")
-	valuesConfigMapKeyDefault := resolveString("VALUES_CONFIGMAP_KEY", os.Getenv("VALUES_CONFIGMAP_KEY"), "")
-	templatePathDefault := resolveString("TEMPLATE_PATH", os.Getenv("TEMPLATE_PATH"), defaultTemplatePath)
-	apiAddrDefault := resolveString("API_ADDR", os.Getenv("API_ADDR"), defaultAPIAddr)
-	metricsAddrDefault := resolveString("METRICS_ADDR", os.Getenv("METRICS_ADDR"), defaultMetricsAddr)
-	probeAddrDefault := resolveString("PROBE_ADDR", os.Getenv("PROBE_ADDR"), defaultProbeAddr)
-	defaultTTLDefault := resolveDuration("DEFAULT_TTL", os.Getenv("DEFAULT_TTL"), defaultTTLValue)
-	maxTTLDefault := resolveDuration("MAX_TTL", os.Getenv("MAX_TTL"), defaultMaxTTLValue)
-	preProvisionCountDefault := resolveInt("PRE_PROVISION_CLAIMS_COUNT", os.Getenv("PRE_PROVISION_CLAIMS_COUNT"), defaultPreProvisionCount)
-	reconcileIntervalDefault := resolveDuration("RECONCILE_INTERVAL", os.Getenv("RECONCILE_INTERVAL"), defaultReconcileInterval)
+	flag.StringVar(&configPath, "config", configPath, "path to YAML/JSON config file")
 
 	flag.StringVar(&namespace, "namespace", namespaceDefault, "namespace watched and managed by the controller")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", watchNamespacesDefault, "comma-separated additional namespaces where claims can be created")
 	flag.StringVar(&valuesPath, "values-path", valuesPathDefault, "path to Helm values file")
 	flag.StringVar(&valuesConfigMapName, "values-configmap-name", valuesConfigMapNameDefault, "ConfigMap name containing values template")
 	flag.StringVar(&valuesConfigMapKey, "values-configmap-key", valuesConfigMapKeyDefault, "ConfigMap data key containing values template")
+	flag.BoolVar(&valuesMerge, "values-merge", valuesMergeDefault, "deep-merge ConfigMap values over file values instead of using only one source")
+	flag.StringVar(&valuesSchemaPath, "values-schema-path", valuesSchemaPathDefault, "path to a JSON schema validating values (defaults to the chart values.schema.json when available)")
+	flag.StringVar(&valuesAllowlist, "values-override-allowlist", valuesAllowlistDefault, "comma-separated dotted values paths clients may override in POST /claim")
 	flag.StringVar(&templatePath, "template-path", templatePathDefault, "path to Helm template file")
+	flag.StringVar(&templateRenderer, "template-renderer", templateRendererDefault, "template rendering backend (helm, kustomize or raw)")
+	flag.StringVar(&resourceBackend, "resource-backend", resourceBackendDefault, "how rendered claim resources are provisioned: apply (controller-managed objects) or helm-release (one Helm release per claim, helm renderer only)")
+	flag.StringVar(&templateRef, "template-ref", templateRefDefault, "OCI Helm chart reference (oci://registry/chart:tag) used instead of --template-path")
+	flag.StringVar(&templateCacheDir, "template-cache-dir", templateCacheDirDefault, "local directory caching charts pulled from OCI registries")
+	flag.StringVar(&templateRegSecret, "template-registry-secret", templateRegSecretDefault, "docker config Secret name used to authenticate against the chart registry")
+	flag.StringVar(&profilesPath, "profiles-path", profilesPathDefault, "path to a YAML file declaring additional named template profiles")
+	flag.StringVar(&quotasPath, "quotas-path", quotasPathDefault, "path to a YAML file declaring per-user and per-team claim quotas")
+	flag.StringVar(&tenantsPath, "tenants-path", tenantsPathDefault, "path to a YAML file mapping identities or the X-Tenant header to a namespace, profile and quota")
+	flag.StringVar(&credentialKeys, "credential-keys", credentialKeysDefault, "comma-separated keys of random credentials generated per claim into a claim-owned Secret")
 	flag.StringVar(&apiAddr, "api-addr", apiAddrDefault, "claim API listen address")
+	flag.StringVar(&grpcAddr, "grpc-addr", grpcAddrDefault, "gRPC claim service listen address (empty disables)")
+	flag.StringVar(&apiTLSCert, "api-tls-cert", apiTLSCertDefault, "PEM certificate served by the API and gRPC servers (empty serves plain text)")
+	flag.StringVar(&apiTLSKey, "api-tls-key", apiTLSKeyDefault, "PEM private key of --api-tls-cert")
+	flag.StringVar(&apiClientCA, "api-client-ca", apiClientCADefault, "PEM CA bundle; when set, API clients must present a certificate signed by it")
 	flag.StringVar(&metricsAddr, "metrics-addr", metricsAddrDefault, "metrics listen address")
 	flag.StringVar(&probeAddr, "health-probe-addr", probeAddrDefault, "probe listen address")
+	flag.StringVar(&pprofAddr, "pprof-addr", pprofAddrDefault, "pprof listen address (empty disables)")
 	flag.DurationVar(&defaultTTL, "default-ttl", defaultTTLDefault, "default claim lifetime")
 	flag.DurationVar(&maxTTL, "max-ttl", maxTTLDefault, "maximum claim lifetime")
 	flag.IntVar(&preProvisionCount, "pre-provision-claims-count", preProvisionCountDefault, "number of claims pre-provisioned in advance")
+	flag.IntVar(&maxActiveClaims, "max-active-claims", maxActiveClaimsDefault, "maximum number of active claims before POST /claim answers 429 (0 disables)")
+	flag.IntVar(&poolReserve, "pool-reserve", poolReserveDefault, "pre-provisioned claims kept for high-priority requests")
+	flag.DurationVar(&poolMaxIdleAge, "pool-max-idle-age", poolMaxIdleAgeDefault, "age after which unclaimed pre-provisioned claims are deleted and re-created with current values (0 disables)")
+	flag.StringVar(&provisioningMode, "provisioning-mode", provisioningModeDefault, "claim provisioning mode: hybrid (pool then on-demand), pool-only or on-demand (pre-provisioning disabled)")
+	flag.DurationVar(&poolReplenishEvery, "pool-replenish-interval", poolReplenishEveryDefault, "interval at which the pre-provisioned pool is converged to its target size")
+	flag.DurationVar(&claimQueueTimeout, "claim-queue-timeout", claimQueueTimeoutDefault, "maximum time a claim request waits in queue for capacity or a pool claim before being rejected (0 disables queuing)")
+	flag.IntVar(&rerenderRate, "rerender-rate-per-minute", rerenderRateDefault, "claims re-rendered per minute by POST /admin/rerender rollouts")
+	flag.BoolVar(&namespacePerClaim, "namespace-per-claim", namespacePerClaimDefault, "create a dedicated namespace for each claim and render its namespaced resources into it")
+	flag.DurationVar(&idempotencyKeyTTL, "idempotency-key-ttl", idempotencyKeyTTLDefault, "window during which a replayed Idempotency-Key returns the existing claim")
+	flag.IntVar(&claimRatePerMinute, "claim-rate-limit-per-minute", claimRatePerMinuteDefault, "POST /claim requests allowed per minute for each client IP or identity (0 disables)")
+	flag.IntVar(&claimRateBurst, "claim-rate-limit-burst", claimRateBurstDefault, "burst of POST /claim requests allowed above the per-minute rate")
 	flag.DurationVar(&reconcileInterval, "reconcile-interval", reconcileIntervalDefault, "controller periodic reconcile interval")
-	flag.IntVar(&controllerLogLevel, "zap-log-level", 0, "zap logger level")
+	flag.DurationVar(&configReloadEvery, "config-reload-interval", configReloadEveryDefault, "interval at which the config and quotas files are checked for changes and reloaded (0 disables; SIGHUP always reloads)")
+	flag.DurationVar(&releaseWaitTimeout, "release-wait-timeout", releaseWaitTimeoutDefault, "maximum time POST /release/{id}?wait=true waits for claim resources to be deleted")
+	flag.DurationVar(&readyTimeout, "claim-ready-timeout", readyTimeoutDefault, "default time POST /claim waits for claim resources to become ready before answering 504")
+	flag.DurationVar(&maxReadyTimeout, "max-claim-ready-timeout", maxReadyTimeoutDefault, "upper bound of the readyTimeout a POST /claim request may ask for")
+	flag.DurationVar(&maxSuspendDuration, "max-suspend-duration", maxSuspendDurationDefault, "safety cap after which a suspended claim expires again")
+	flag.DurationVar(&maxStartAtHorizon, "max-start-at-horizon", maxStartAtHorizonDefault, "how far in the future a POST /claim startAt may be")
+	flag.DurationVar(&releaseRetention, "release-retention", releaseRetentionDefault, "maximum time resources are kept after POST /release/{id}?retain=true")
+	flag.DurationVar(&historyRetention, "claim-history-retention", historyRetentionDefault, "time tombstones of released and expired claims are kept for GET /claims/history (0 disables)")
+	flag.StringVar(&webhookURLs, "webhook-urls", webhookURLsDefault, "comma-separated URLs receiving claim lifecycle events")
+	flag.StringVar(&webhookSecret, "webhook-secret", webhookSecretDefault, "HMAC-SHA256 key signing webhook payloads")
+	flag.StringVar(&slackWebhookURL, "slack-webhook-url", slackWebhookURLDefault, "Slack incoming webhook URL receiving claim notifications")
+	flag.StringVar(&slackEvents, "slack-events", slackEventsDefault, "comma-separated claim events sent to Slack")
+	flag.StringVar(&publicURL, "public-url", publicURLDefault, "externally reachable base URL of the claim API, used in notification links")
+	flag.StringVar(&apiKeys, "api-keys", apiKeysDefault, "comma-separated static API keys (name=key or key) accepted by the claim API")
+	flag.BoolVar(&apiDocs, "api-docs", apiDocsDefault, "serve a Swagger UI for /openapi.json on /docs")
+	flag.BoolVar(&accessLog, "access-log", accessLogDefault, "log every API request")
+	flag.BoolVar(&accessLogProbes, "access-log-probes", accessLogProbesDefault, "include /healthz and /readyz requests in the access log")
+	flag.BoolVar(&auditLog, "audit-log", auditLogDefault, "write an audit record as a JSON line on stdout for every API mutation")
+	flag.StringVar(&auditWebhookURL, "audit-webhook-url", auditWebhookURLDefault, "URL receiving every audit record as a JSON POST, signed with the webhook secret")
+	flag.IntVar(&maxRequestBodyBytes, "max-request-body-bytes", maxRequestBodyBytesDefault, "maximum size of API request bodies (0 disables the limit)")
+	flag.DurationVar(&drainTimeout, "shutdown-drain-timeout", drainTimeoutDefault, "how long in-flight claim requests may keep waiting for readiness on shutdown before being answered with 202")
+	flag.DurationVar(&renderCheckEvery, "render-check-interval", renderCheckEveryDefault, "interval of the background template render gating readiness (0 disables)")
+	flag.StringVar(&corsAllowedOrigins, "cors-allowed-origins", corsAllowedOriginsDefault, "comma-separated origins allowed to call the API from a browser, * for any (empty disables CORS)")
+	flag.StringVar(&corsAllowedMethods, "cors-allowed-methods", corsAllowedMethodsDefault, "comma-separated methods allowed in CORS requests (empty uses GET, POST, PATCH)")
+	flag.StringVar(&corsAllowedHeaders, "cors-allowed-headers", corsAllowedHeadersDefault, "comma-separated request headers allowed in CORS requests (empty uses the headers of the claim API)")
+	flag.BoolVar(&authTokenReview, "auth-token-review", authTokenReviewDefault, "validate bearer tokens with the Kubernetes TokenReview API")
+	flag.StringVar(&authAudiences, "auth-token-audiences", authAudiencesDefault, "comma-separated audiences required by TokenReview validation")
+	flag.StringVar(&authRoutes, "auth-routes", authRoutesDefault, "comma-separated API routes requiring authentication (claim, release, renew, claims, admin)")
+	flag.StringVar(&adminSubjects, "admin-subjects", adminSubjectsDefault, "comma-separated subjects (or group:<name>) allowed on /admin routes and on claims owned by others (empty leaves admin routes open)")
+	flag.StringVar(&oidcIssuer, "oidc-issuer-url", oidcIssuerDefault, "OIDC issuer whose JWTs are accepted by the claim API")
+	flag.StringVar(&oidcAudience, "oidc-audience", oidcAudienceDefault, "audience (client ID) required in OIDC tokens")
+	flag.StringVar(&oidcUsernameClaim, "oidc-username-claim", oidcUsernameClaimDefault, "OIDC token claim recorded as the claim owner")
+	flag.DurationVar(&expiryWarning, "expiry-warning-window", expiryWarningDefault, "time before expiry when claims are flagged as expiring soon (0 disables)")
+	flag.DurationVar(&expiryJitter, "expiry-jitter", expiryJitterDefault, "maximum delay added to each claim expiry, spread deterministically per claim to avoid synchronized deletes (0 disables)")
+	flag.StringVar(&workingHours, "working-hours", workingHoursDefault, "semicolon-separated windows (e.g. \"Mon-Fri 08:00-19:00\") outside which claims are force-expired")
+	flag.StringVar(&workingHoursTZ, "working-hours-timezone", workingHoursTZDefault, "timezone of the working hours windows")
+	flag.DurationVar(&httpProbeTimeout, "http-probe-timeout", httpProbeTimeoutDefault, "timeout of claim.controller/http-probe readiness requests")
+	flag.IntVar(&crashLoopRestarts, "crashloop-restart-limit", crashLoopRestartsDefault, "restarts of a CrashLoopBackOff container after which its claim is marked failed (0 disables)")
+	flag.DurationVar(&failedRetention, "failed-claim-retention", failedRetentionDefault, "time after which failed claims are cleaned up (0 keeps them until they expire)")
+	flag.IntVar(&controllerLogLevel, "zap-log-level", controllerLogLevelDefault, "zap logger level")
 	flag.Parse()
 
+	problems.checkPositiveDuration("default-ttl", defaultTTL)
+	problems.checkNonNegativeDuration("max-ttl", maxTTL)
+	problems.checkNonNegativeInt("pre-provision-claims-count", preProvisionCount)
+	problems.checkNonNegativeInt("max-active-claims", maxActiveClaims)
+	problems.checkNonNegativeInt("max-request-body-bytes", maxRequestBodyBytes)
+	problems.checkNonNegativeDuration("render-check-interval", renderCheckEvery)
+	problems.checkNonNegativeDuration("shutdown-drain-timeout", drainTimeout)
+	problems.checkNonNegativeInt("pool-reserve", poolReserve)
+	problems.checkNonNegativeDuration("pool-max-idle-age", poolMaxIdleAge)
+	problems.checkNonNegativeDuration("pool-replenish-interval", poolReplenishEvery)
+	problems.checkNonNegativeDuration("claim-queue-timeout", claimQueueTimeout)
+	problems.checkNonNegativeInt("rerender-rate-per-minute", rerenderRate)
+	problems.checkNonNegativeDuration("idempotency-key-ttl", idempotencyKeyTTL)
+	problems.checkNonNegativeInt("claim-rate-limit-per-minute", claimRatePerMinute)
+	problems.checkNonNegativeInt("claim-rate-limit-burst", claimRateBurst)
+	problems.checkNonNegativeDuration("reconcile-interval", reconcileInterval)
+	problems.checkNonNegativeDuration("config-reload-interval", configReloadEvery)
+	problems.checkNonNegativeDuration("http-probe-timeout", httpProbeTimeout)
+	problems.checkNonNegativeInt("crashloop-restart-limit", crashLoopRestarts)
+	problems.checkNonNegativeDuration("failed-claim-retention", failedRetention)
+	problems.checkNonNegativeDuration("release-wait-timeout", releaseWaitTimeout)
+	problems.checkNonNegativeDuration("claim-ready-timeout", readyTimeout)
+	problems.checkNonNegativeDuration("max-claim-ready-timeout", maxReadyTimeout)
+	problems.checkNonNegativeDuration("release-retention", releaseRetention)
+	problems.checkNonNegativeDuration("claim-history-retention", historyRetention)
+	problems.checkNonNegativeDuration("max-suspend-duration", maxSuspendDuration)
+	problems.checkNonNegativeDuration("max-start-at-horizon", maxStartAtHorizon)
+	problems.checkNonNegativeDuration("expiry-warning-window", expiryWarning)
+	problems.checkNonNegativeDuration("expiry-jitter", expiryJitter)
+	if templateRef == "" {
+		problems.checkFileExists("template-path", templatePath)
+	}
+	problems.checkFileExists("values-schema-path", valuesSchemaPath)
+	problems.checkFileExists("profiles-path", profilesPath)
+	problems.checkFileExists("api-tls-cert", apiTLSCert)
+	problems.checkFileExists("api-tls-key", apiTLSKey)
+	problems.checkFileExists("api-client-ca", apiClientCA)
+	if (apiTLSCert == "") != (apiTLSKey == "") {
+		problems.add("--api-tls-cert and --api-tls-key must be set together")
+	}
+	if apiClientCA != "" && apiTLSCert == "" {
+		problems.add("--api-client-ca requires --api-tls-cert and --api-tls-key")
+	}
+	problems.checkFileExists("quotas-path", quotasPath)
+	if quotasPath != "" && fileConfig.Quotas != nil {
+		problems.add("--quotas-path (or QUOTAS_PATH) and the config file quotas section cannot both be set")
+	}
+	if profilesPath != "" && len(fileConfig.Profiles) > 0 {
+		problems.add("--profiles-path (or PROFILES_PATH) and the config file profiles section cannot both be set")
+	}
+	problems.checkFileExists("tenants-path", tenantsPath)
+	if (valuesConfigMapName == "") != (valuesConfigMapKey == "") {
+		problems.add("--values-configmap-name and --values-configmap-key must be set together")
+	}
+	if _, err := template.ParseRendererKind(templateRenderer); err != nil {
+		problems.add("--template-renderer: %v", err)
+	}
+	if _, err := helmrelease.ParseBackend(resourceBackend); err != nil {
+		problems.add("--resource-backend: %v", err)
+	}
+	if _, err := api.ParseProvisioningMode(provisioningMode); err != nil {
+		problems.add("--provisioning-mode: %v", err)
+	}
+	if _, err := controller.ParseWorkingHours(workingHours, workingHoursTZ); err != nil {
+		problems.add("--working-hours: %v", err)
+	}
+	if err := problems.err(); err != nil {
+		panic(err)
+	}
+
 	if maxTTL < defaultTTL {
 		maxTTL = defaultTTL
 	}
@@ -104,6 +400,7 @@ func main() {
 	))
 
 	logger := ctrl.Log.WithName("main")
+	template.SetLogger(ctrl.Log.WithName("template"))
 
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
@@ -120,10 +417,18 @@ func main() {
 		panic(fmt.Errorf("create kube client: %w", err))
 	}
 
+	cacheNamespaces := map[string]cache.Config{namespace: {}}
+	for _, watchNamespace := range splitList(watchNamespaces) {
+		cacheNamespaces[watchNamespace] = cache.Config{}
+	}
+	if namespacePerClaim {
+		cacheNamespaces = nil
+	}
+
 	manager, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
 		Cache: cache.Options{
-			DefaultNamespaces:    map[string]cache.Config{namespace: {}},
+			DefaultNamespaces:    cacheNamespaces,
 			DefaultLabelSelector: managedSelector,
 			DefaultTransform:     cache.TransformStripManagedFields(),
 		},
@@ -131,89 +436,430 @@ func main() {
 			BindAddress: metricsAddr,
 		},
 		HealthProbeBindAddress: probeAddr,
+		PprofBindAddress:       pprofAddr,
 	})
 	if err != nil {
 		panic(fmt.Errorf("create manager: %w", err))
 	}
 
+	notifier := resolveNotifier(ctrl.Log.WithName("notify"), splitList(webhookURLs), webhookSecret, slackWebhookURL, publicURL, splitList(slackEvents))
+
+	workingHoursPolicy, err := controller.ParseWorkingHours(workingHours, workingHoursTZ)
+	if err != nil {
+		panic(err)
+	}
+
+	clusters := cluster.NewRegistry(manager.GetAPIReader(), namespace, manager.GetScheme())
+	helmReleases := helmrelease.New(restConfig)
+
 	reconciler := &controller.ClaimReconciler{
-		Client:            manager.GetClient(),
-		Scheme:            manager.GetScheme(),
-		Namespace:         namespace,
-		DefaultTTL:        defaultTTL,
-		ReconcileInterval: reconcileInterval,
-		Recorder:          manager.GetEventRecorderFor("claim-controller"),
+		Client:              manager.GetClient(),
+		APIReader:           manager.GetAPIReader(),
+		Scheme:              manager.GetScheme(),
+		Namespace:           namespace,
+		WatchNamespaces:     splitList(watchNamespaces),
+		Clusters:            clusters,
+		DefaultTTL:          defaultTTL,
+		ReconcileInterval:   reconcileInterval,
+		HTTPProbeTimeout:    httpProbeTimeout,
+		CrashLoopRestarts:   crashLoopRestarts,
+		FailedRetention:     failedRetention,
+		HistoryRetention:    historyRetention,
+		ExpiryWarningWindow: expiryWarning,
+		WorkingHours:        workingHoursPolicy,
+		ExpiryJitter:        expiryJitter,
+		HelmReleases:        helmReleases,
+		Notifier:            notifier,
+		Recorder:            manager.GetEventRecorderFor("claim-controller"),
 	}
 
 	if err := reconciler.SetupWithManager(manager); err != nil {
 		panic(fmt.Errorf("setup reconciler: %w", err))
 	}
+	if err := (&controller.ExpiryReconciler{ClaimReconciler: reconciler}).SetupWithManager(manager); err != nil {
+		panic(fmt.Errorf("setup expiry reconciler: %w", err))
+	}
+
+	renderer := resolveRenderer(kubeClient, namespace, templateRenderer, templatePath, templateRef, templateCacheDir, templateRegSecret)
+	backend, err := helmrelease.ParseBackend(resourceBackend)
+	if err != nil {
+		panic(err)
+	}
+	if _, isHelm := renderer.(*template.HelmRenderer); backend == helmrelease.BackendHelmRelease && !isHelm {
+		panic(fmt.Errorf("the helm-release resource backend requires the helm template renderer"))
+	}
 
+	profileConfigs := fileConfig.Profiles
+	if profilesPath != "" {
+		profileConfigs, err = config.LoadProfiles(profilesPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+	profiles := make([]api.Profile, 0, len(profileConfigs))
+	for _, profileConfig := range profileConfigs {
+		profileRenderer := resolveRenderer(kubeClient, namespace, profileConfig.Renderer, profileConfig.TemplatePath, profileConfig.TemplateRef, templateCacheDir, templateRegSecret)
+		if profileConfig.Namespace != "" && profileConfig.Namespace != namespace && !slices.Contains(splitList(watchNamespaces), profileConfig.Namespace) {
+			panic(fmt.Errorf("profile %q targets namespace %q which is not watched", profileConfig.Name, profileConfig.Namespace))
+		}
+		profileValuesProvider, err := resolveValuesProvider(logger, kubeClient, namespace, profileConfig.ValuesConfigMapName, profileConfig.ValuesConfigMapKey, profileConfig.ValuesPath, false)
+		if err != nil {
+			problems.add("profile %q: %v", profileConfig.Name, err)
+		}
+		minReady, err := controller.ParseMinReady(profileConfig.MinReady)
+		if err != nil {
+			problems.add("profile %q: %v", profileConfig.Name, err)
+		}
+		profiles = append(profiles, api.Profile{
+			Name:            profileConfig.Name,
+			Type:            profileConfig.Type,
+			Backend:         profileConfig.Backend,
+			Namespace:       profileConfig.Namespace,
+			Cluster:         profileConfig.Cluster,
+			DefaultTTL:      config.ParseDurationOrFallback(profileConfig.DefaultTTL, 0),
+			MaxTTL:          config.ParseDurationOrFallback(profileConfig.MaxTTL, 0),
+			Renderer:        profileRenderer,
+			ValuesProvider:  profileValuesProvider,
+			SchemaValidator: resolveSchemaValidator(profileRenderer, profileConfig.ValuesSchemaPath),
+			MinReady:        minReady,
+		})
+		logger.Info("loaded template profile", "profile", profileConfig.Name, "renderer", profileRenderer.Description())
+	}
+
+	valuesProvider, err := resolveValuesProvider(logger, kubeClient, namespace, valuesConfigMapName, valuesConfigMapKey, valuesPath, valuesMerge)
+	if err != nil {
+		problems.add("values: %v", err)
+	}
+	if err := problems.err(); err != nil {
+		panic(err)
+	}
+
+	mode, err := api.ParseProvisioningMode(provisioningMode)
+	if err != nil {
+		panic(err)
+	}
+
+	readinessChecks := map[string]healthz.Checker{
+		"cache":     cacheSyncedCheck(manager.GetCache()),
+		"apiserver": apiServerCheck(kubeClient),
+	}
+	auditSink := resolveAuditSink(ctrl.Log.WithName("audit"), auditLog, auditWebhookURL, webhookSecret)
 	apiServer := api.NewServer(api.Config{
-		Namespace:         namespace,
-		DefaultTTL:        defaultTTL,
-		MaxTTL:            maxTTL,
-		PreProvisionCount: preProvisionCount,
-		TemplatePath:      templatePath,
-		ValuesProvider:    resolveValuesProvider(logger, kubeClient, namespace, valuesConfigMapName, valuesConfigMapKey, valuesPath),
-		Client:            manager.GetClient(),
+		Namespace:             namespace,
+		DefaultTTL:            defaultTTL,
+		MaxTTL:                maxTTL,
+		PreProvisionCount:     preProvisionCount,
+		Renderer:              renderer,
+		SchemaValidator:       resolveSchemaValidator(renderer, valuesSchemaPath),
+		ValuesAllowlist:       values.NewAllowlist(splitList(valuesAllowlist)),
+		Profiles:              profiles,
+		CredentialKeys:        splitList(credentialKeys),
+		ReleaseWaitTimeout:    releaseWaitTimeout,
+		ReleaseRetention:      releaseRetention,
+		ClaimHistoryRetention: historyRetention,
+		ClaimReadyTimeout:     readyTimeout,
+		MaxClaimReadyTimeout:  maxReadyTimeout,
+		MaxSuspendDuration:    maxSuspendDuration,
+		MaxStartAtHorizon:     maxStartAtHorizon,
+		WorkingHours:          workingHoursPolicy,
+		Notifier:              notifier,
+		AuditSink:             auditSink,
+		Authenticators:        resolveAuthenticators(kubeClient, splitList(apiKeys), authTokenReview, splitList(authAudiences), oidcIssuer, oidcAudience, oidcUsernameClaim),
+		AuthRoutes:            splitList(authRoutes),
+		AdminSubjects:         splitList(adminSubjects),
+		Quotas:                resolveQuotas(quotasPath, fileConfig.Quotas),
+		Tenants:               resolveTenants(tenantsPath, append([]string{namespace}, splitList(watchNamespaces)...), profiles),
+		MaxActiveClaims:       maxActiveClaims,
+		PoolReserve:           poolReserve,
+		PoolMaxIdleAge:        poolMaxIdleAge,
+		ProvisioningMode:      mode,
+		PoolReplenishEvery:    poolReplenishEvery,
+		ClaimQueueTimeout:     claimQueueTimeout,
+		NamespacePerClaim:     namespacePerClaim,
+		RerenderRatePerMinute: rerenderRate,
+		Backend:               backend,
+		HelmReleases:          helmReleases,
+		APIDocs:               apiDocs,
+		AccessLog:             accessLog,
+		AccessLogProbes:       accessLogProbes,
+		MaxRequestBodyBytes:   int64(maxRequestBodyBytes),
+		RenderCheckEvery:      renderCheckEvery,
+		CORSAllowedOrigins:    splitList(corsAllowedOrigins),
+		CORSAllowedMethods:    splitList(corsAllowedMethods),
+		CORSAllowedHeaders:    splitList(corsAllowedHeaders),
+		Logger:                ctrl.Log.WithName("api"),
+		ReadinessChecks:       readinessChecks,
+		WatchNamespaces:       splitList(watchNamespaces),
+		IdempotencyKeyTTL:     idempotencyKeyTTL,
+		ClaimRatePerMinute:    claimRatePerMinute,
+		ClaimRateBurst:        claimRateBurst,
+		ValuesProvider:        valuesProvider,
+		Clusters:              clusters,
+		Client:                manager.GetClient(),
+		APIReader:             manager.GetAPIReader(),
 	})
+	reconciler.ReleaseChart = apiServer.ReleaseChart
+
+	if err := manager.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		panic(fmt.Errorf("add health check: %w", err))
+	}
+	for name, check := range apiServer.ReadinessChecks() {
+		if err := manager.AddReadyzCheck(name, check); err != nil {
+			panic(fmt.Errorf("add readiness check %s: %w", name, err))
+		}
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	if err := apiServer.Start(ctx); err != nil {
 		panic(fmt.Errorf("start api server dependencies: %w", err))
 	}
+	claimInformer, err := manager.GetCache().GetInformer(ctx, &corev1.ConfigMap{})
+	if err != nil {
+		panic(fmt.Errorf("get claim informer: %w", err))
+	}
+	if err := apiServer.WatchClaimChanges(claimInformer); err != nil {
+		panic(fmt.Errorf("watch claim changes: %w", err))
+	}
+
+	go watchRuntimeConfig(ctx, logger.WithName("reload"), configPath, configReloadEvery, runtimeConfig{
+		defaultTTL:        defaultTTL,
+		maxTTL:            maxTTL,
+		preProvisionCount: preProvisionCount,
+		reconcileInterval: reconcileInterval,
+		quotasPath:        quotasPath,
+		quotas:            fileConfig.Quotas,
+	}, func(cfg runtimeConfig) error {
+		return applyRuntimeConfig(cfg, apiServer, reconciler)
+	})
+
+	tlsConfig, tlsWatchers, err := apiTLSConfig(apiTLSCert, apiTLSKey, apiClientCA, logger.WithName("tls"))
+	if err != nil {
+		panic(fmt.Errorf("configure api tls: %w", err))
+	}
+	for _, watcher := range tlsWatchers {
+		if err := manager.Add(watcher); err != nil {
+			panic(fmt.Errorf("add tls watcher: %w", err))
+		}
+	}
 
 	httpServer := &http.Server{
 		Addr:              apiAddr,
 		Handler:           apiServer.Handler(),
+		TLSConfig:         tlsConfig,
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       15 * time.Second,
-		WriteTimeout:      30 * time.Second,
+		WriteTimeout:      30 * time.Second, // claim handlers extend their own deadline while waiting
 		IdleTimeout:       60 * time.Second,
 		MaxHeaderBytes:    1 << 20,
 	}
 
 	go func() {
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsConfig != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error(err, "api server stopped")
 		}
 	}()
 
+	drained := make(chan struct{})
+	if grpcAddr != "" {
+		var grpcOptions []grpc.ServerOption
+		if tlsConfig != nil {
+			grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		grpcServer := grpc.NewServer(grpcOptions...)
+		if err := apiServer.RegisterGRPC(grpcServer); err != nil {
+			panic(fmt.Errorf("register grpc claim service: %w", err))
+		}
+		reflection.Register(grpcServer)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			panic(fmt.Errorf("listen on grpc address: %w", err))
+		}
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error(err, "grpc server stopped")
+			}
+		}()
+		go func() {
+			<-drained
+			grpcServer.GracefulStop()
+		}()
+	}
+
+	// The manager keeps running while claim requests drain: their readiness
+	// waits read claims from its cache.
+	managerCtx, stopManager := context.WithCancel(context.Background())
+	defer stopManager()
 	go func() {
 		<-ctx.Done()
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+		apiServer.Drain(drainCtx)
+		cancelDrain()
+		close(drained)
+
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := httpServer.Shutdown(shutdownCtx); err != nil {
 			logger.Error(err, "failed to shutdown api server")
 		}
+		if auditSink != nil {
+			if err := auditSink.Close(shutdownCtx); err != nil {
+				logger.Error(err, "failed to flush audit records")
+			}
+		}
+		stopManager()
 	}()
 
-	logger.Info("starting manager", "namespace", namespace, "apiAddr", apiAddr, "metricsAddr", metricsAddr, "templatePath", templatePath, "valuesPath", valuesPath, "defaultTTL", defaultTTL.String(), "maxTTL", maxTTL.String(), "preProvisionClaimsCount", preProvisionCount)
-	if err := manager.Start(ctx); err != nil {
+	logger.Info("starting manager", "namespace", namespace, "apiAddr", apiAddr, "metricsAddr", metricsAddr, "templatePath", templatePath, "templateRenderer", templateRenderer, "templateRef", templateRef, "valuesPath", valuesPath, "defaultTTL", defaultTTL.String(), "maxTTL", maxTTL.String(), "preProvisionClaimsCount", preProvisionCount)
+	if err := manager.Start(managerCtx); err != nil {
 		panic(fmt.Errorf("run manager: %w", err))
 	}
 }
 
-func resolveValuesProvider(logger logr.Logger, kubeClient kubernetes.Interface, namespace, configMapName, configMapKey, valuesPath string) values.Provider {
+func resolveValuesProvider(logger logr.Logger, kubeClient kubernetes.Interface, namespace, configMapName, configMapKey, valuesPath string, merge bool) (values.Provider, error) {
+	if merge {
+		layers := make([]values.Provider, 0, 2)
+		if valuesPath != "" {
+			fileProvider, err := values.NewFileProvider(valuesPath)
+			if err != nil {
+				return nil, fmt.Errorf("file values layer: %w", err)
+			}
+			layers = append(layers, fileProvider)
+		}
+		if configMapKey != "" && configMapName != "" {
+			configMapProvider, err := values.NewConfigMapProvider(kubeClient, namespace, configMapName, configMapKey)
+			if err != nil {
+				return nil, fmt.Errorf("configmap values layer %s/%s: %w", configMapName, configMapKey, err)
+			}
+			layers = append(layers, configMapProvider)
+		}
+
+		mergeProvider, err := values.NewMergeProvider(layers...)
+		if err != nil {
+			return nil, fmt.Errorf("create merge values provider: %w", err)
+		}
+		logger.Info("using merge values provider", "source", mergeProvider.Description())
+		return mergeProvider, nil
+	}
+
 	if configMapKey != "" && configMapName != "" {
 		configMapProvider, err := values.NewConfigMapProvider(kubeClient, namespace, configMapName, configMapKey)
-		if err == nil {
-			logger.Info("using configmap values provider", "source", configMapProvider.Description())
-			return configMapProvider
+		if err != nil {
+			return nil, fmt.Errorf("configmap values %s/%s: %w", configMapName, configMapKey, err)
 		}
+		logger.Info("using configmap values provider", "source", configMapProvider.Description())
+		return configMapProvider, nil
 	}
 
 	if valuesPath != "" {
 		fileProvider, err := values.NewFileProvider(valuesPath)
 		if err != nil {
+			return nil, err
 		}
 		logger.Info("using file values provider", "source", fileProvider.Description())
-		return fileProvider
+		return fileProvider, nil
+	}
+
+	return nil, fmt.Errorf("no values source configured, please provide either configmap or file values")
+}
+
+func resolveRenderer(kubeClient kubernetes.Interface, namespace, rendererKind, templatePath, templateRef, cacheDir, registrySecret string) template.Renderer {
+	kind, err := template.ParseRendererKind(rendererKind)
+	if err != nil {
+		panic(err)
+	}
+
+	switch kind {
+	case template.RendererKustomize:
+		renderer, err := template.NewKustomizeRenderer(templatePath)
+		if err != nil {
+			panic(fmt.Errorf("create kustomize renderer: %w", err))
+		}
+		return renderer
+	case template.RendererRaw:
+		renderer, err := template.NewRawRenderer(templatePath)
+		if err != nil {
+			panic(fmt.Errorf("create raw renderer: %w", err))
+		}
+		return renderer
+	default:
+		return template.NewHelmRenderer(templatePath, resolveChartSource(kubeClient, namespace, templatePath, templateRef, cacheDir, registrySecret))
+	}
+}
+
+func resolveSchemaValidator(renderer template.Renderer, schemaPath string) *template.SchemaValidator {
+	var schemaJSON []byte
+	if schemaPath != "" {
+		data, err := os.ReadFile(schemaPath)
+		if err != nil {
+			panic(fmt.Errorf("read values schema: %w", err))
+		}
+		schemaJSON = data
+	} else if helmRenderer, ok := renderer.(*template.HelmRenderer); ok {
+		data, err := helmRenderer.Schema()
+		if err != nil {
+			panic(fmt.Errorf("load chart values schema: %w", err))
+		}
+		schemaJSON = data
+	}
+
+	if len(schemaJSON) == 0 {
+		return nil
+	}
+
+	validator, err := template.NewSchemaValidator(schemaJSON)
+	if err != nil {
+		panic(err)
+	}
+	return validator
+}
+
+func resolveChartSource(kubeClient kubernetes.Interface, namespace, templatePath, templateRef, cacheDir, registrySecret string) template.ChartSource {
+	if templateRef == "" && !template.IsChartDirectory(templatePath) {
+		return nil
+	}
+
+	var dockerConfigJSON []byte
+	if registrySecret != "" {
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.Background(), registrySecret, metav1.GetOptions{})
+		if err != nil {
+			panic(fmt.Errorf("get registry secret %s/%s: %w", namespace, registrySecret, err))
+		}
+		dockerConfigJSON = secret.Data[corev1.DockerConfigJsonKey]
+		if len(dockerConfigJSON) == 0 {
+			panic(fmt.Errorf("registry secret %s/%s has no %s key", namespace, registrySecret, corev1.DockerConfigJsonKey))
+		}
+	}
+
+	if templateRef == "" {
+		chartSource, err := template.NewDirectoryChartSource(templatePath, cacheDir, dockerConfigJSON)
+		if err != nil {
+			panic(fmt.Errorf("create chart source: %w", err))
+		}
+		return chartSource
+	}
+
+	chartSource, err := template.NewOCIChartSource(templateRef, cacheDir, dockerConfigJSON)
+	if err != nil {
+		panic(fmt.Errorf("create chart source: %w", err))
 	}
+	return chartSource
+}
 
-	panic(fmt.Errorf("no valid values provider found, please provide either configmap or file values"))
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
 }
 
 func firstNonEmpty(values ...string) string {
@@ -225,36 +871,176 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
-func resolveString(envName, fileValue, fallback string) string {
-	return firstNonEmpty(os.Getenv(envName), fileValue, fallback)
+func resolveAuthenticators(kubeClient kubernetes.Interface, apiKeys []string, tokenReview bool, audiences []string, oidcIssuer, oidcAudience, oidcUsernameClaim string) []api.Authenticator {
+	var authenticators []api.Authenticator
+	if len(apiKeys) > 0 {
+		staticKeys, err := api.NewStaticKeyAuthenticator(apiKeys)
+		if err != nil {
+			panic(fmt.Errorf("load api keys: %w", err))
+		}
+		authenticators = append(authenticators, staticKeys)
+	}
+	if tokenReview {
+		authenticators = append(authenticators, api.NewTokenReviewAuthenticator(kubeClient, audiences))
+	}
+	if oidcIssuer != "" {
+		authenticators = append(authenticators, api.NewOIDCAuthenticator(oidcIssuer, oidcAudience, oidcUsernameClaim))
+	}
+	return authenticators
 }
 
-func resolveDuration(envName, fileValue string, fallback time.Duration) time.Duration {
-	if envValue := os.Getenv(envName); envValue != "" {
-		if d, err := time.ParseDuration(envValue); err == nil {
-			return d
+func resolveQuotas(path string, inline *config.QuotasConfig) *api.QuotaPolicy {
+	policy, err := loadQuotaPolicy(path, inline)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+func loadQuotaPolicy(path string, inline *config.QuotasConfig) (*api.QuotaPolicy, error) {
+	quotasConfig := inline
+	if path != "" {
+		loaded, err := config.LoadQuotas(path)
+		if err != nil {
+			return nil, fmt.Errorf("load quotas: %w", err)
 		}
+		quotasConfig = loaded
+	}
+	if quotasConfig == nil {
+		return nil, nil
 	}
 
-	return config.ParseDurationOrFallback(fileValue, fallback)
+	policy := &api.QuotaPolicy{
+		Users: map[string]api.QuotaLimit{},
+		Teams: map[string]api.QuotaLimit{},
+	}
+	if quotasConfig.Default != nil {
+		policy.Default = &api.QuotaLimit{MaxActiveClaims: quotasConfig.Default.MaxActiveClaims, MaxTotalTTL: quotasConfig.Default.TotalTTL()}
+	}
+	for name, limit := range quotasConfig.Users {
+		policy.Users[name] = api.QuotaLimit{MaxActiveClaims: limit.MaxActiveClaims, MaxTotalTTL: limit.TotalTTL()}
+	}
+	for name, limit := range quotasConfig.Teams {
+		policy.Teams[name] = api.QuotaLimit{MaxActiveClaims: limit.MaxActiveClaims, MaxTotalTTL: limit.TotalTTL()}
+	}
+	return policy, nil
 }
 
-func resolveInt(envName, fileValue string, fallback int) int {
+func resolveTenants(path string, namespaces []string, profiles []api.Profile) []api.Tenant {
+	tenantConfigs, err := config.LoadTenants(path)
+	if err != nil {
+		panic(fmt.Errorf("load tenants: %w", err))
+	}
+
+	tenants := make([]api.Tenant, 0, len(tenantConfigs))
+	for _, tenantConfig := range tenantConfigs {
+		if tenantConfig.Namespace != "" && !slices.Contains(namespaces, tenantConfig.Namespace) {
+			panic(fmt.Errorf("tenant %q targets namespace %q which is not watched", tenantConfig.Name, tenantConfig.Namespace))
+		}
+		if tenantConfig.Profile != "" && tenantConfig.Profile != api.DefaultProfileName && !slices.ContainsFunc(profiles, func(profile api.Profile) bool { return profile.Name == tenantConfig.Profile }) {
+			panic(fmt.Errorf("tenant %q uses unknown profile %q", tenantConfig.Name, tenantConfig.Profile))
+		}
+
+		tenant := api.Tenant{
+			Name:       tenantConfig.Name,
+			Namespace:  tenantConfig.Namespace,
+			Profile:    tenantConfig.Profile,
+			DefaultTTL: config.ParseDurationOrFallback(tenantConfig.DefaultTTL, 0),
+			MaxTTL:     config.ParseDurationOrFallback(tenantConfig.MaxTTL, 0),
+			Subjects:   tenantConfig.Subjects,
+		}
+		if tenantConfig.Quota != nil {
+			tenant.Quota = &api.QuotaLimit{MaxActiveClaims: tenantConfig.Quota.MaxActiveClaims, MaxTotalTTL: tenantConfig.Quota.TotalTTL()}
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants
+}
+
+func resolveNotifier(logger logr.Logger, webhookURLs []string, webhookSecret, slackWebhookURL, publicURL string, slackEvents []string) notify.Notifier {
+	var notifiers notify.Multi
+	if len(webhookURLs) > 0 {
+		notifiers = append(notifiers, notify.NewWebhookNotifier(webhookURLs, webhookSecret, 0, logger.WithName("webhook")))
+	}
+	if slackWebhookURL != "" {
+		notifiers = append(notifiers, notify.NewSlackNotifier(slackWebhookURL, publicURL, slackEvents, logger.WithName("slack")))
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+	return notifiers
+}
+
+func resolveAuditSink(logger logr.Logger, stdout bool, webhookURL, webhookSecret string) audit.Sink {
+	var sinks audit.Multi
+	if stdout {
+		sinks = append(sinks, audit.NewStreamSink(os.Stdout, logger))
+	}
+	if webhookURL != "" {
+		sinks = append(sinks, audit.NewWebhookSink(webhookURL, webhookSecret, 0, logger))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return sinks
+}
+
+func resolveString(envName, fileValue, fallback string) string {
+	return firstNonEmpty(os.Getenv(envName), fileValue, fallback)
+}
+
+func (p *configProblems) resolveDuration(envName, fileValue string, fallback time.Duration) time.Duration {
+	return resolveParsed(p, envName, fileValue, fallback, "duration", time.ParseDuration)
+}
+
+func (p *configProblems) resolveInt(envName, fileValue string, fallback int) int {
+	return resolveParsed(p, envName, fileValue, fallback, "integer", strconv.Atoi)
+}
+
+func (p *configProblems) resolveBool(envName, fileValue string, fallback bool) bool {
+	return resolveParsed(p, envName, fileValue, fallback, "boolean", strconv.ParseBool)
+}
+
+func resolveParsed[T any](problems *configProblems, envName, fileValue string, fallback T, kind string, parse func(string) (T, error)) T {
 	if envValue := os.Getenv(envName); envValue != "" {
-		if parsed, err := strconv.Atoi(envValue); err == nil {
+		parsed, err := parse(envValue)
+		if err == nil {
 			return parsed
 		}
+		problems.add("%s=%q is not a valid %s", envName, envValue, kind)
 	}
 
 	if fileValue != "" {
-		if parsed, err := strconv.Atoi(fileValue); err == nil {
+		parsed, err := parse(fileValue)
+		if err == nil {
 			return parsed
 		}
+		problems.add("config file value %q for %s is not a valid %s", fileValue, envName, kind)
 	}
 
 	return fallback
 }
 
+func configPathFromArgs(args []string, fallback string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return fallback
+}
+
 func getEnv(name, fallback string) string {
 	v := os.Getenv(name)
 	if v == "" {
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+const testEnv = "CLAIM_CONTROLLER_TEST_VALUE"
+
+func TestResolvePrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		file      string
+		flag      string
+		want      string
+		wantError bool
+	}{
+		{name: "default", want: "default"},
+		{name: "file over default", file: "file", want: "file"},
+		{name: "env over file", env: "env", file: "file", want: "env"},
+		{name: "flag over env", env: "env", file: "file", flag: "flag", want: "flag"},
+		{name: "flag over default", flag: "flag", want: "flag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(testEnv, tt.env)
+
+			var got string
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.StringVar(&got, "value", resolveString(testEnv, tt.file, "default"), "")
+			var args []string
+			if tt.flag != "" {
+				args = []string{"--value=" + tt.flag}
+			}
+			if err := flags.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveParsed(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		resolve     func(*configProblems) any
+		want        any
+		wantProblem bool
+	}{
+		{
+			name:    "duration default",
+			resolve: func(p *configProblems) any { return p.resolveDuration(testEnv, "", time.Minute) },
+			want:    time.Minute,
+		},
+		{
+			name:    "duration from file",
+			resolve: func(p *configProblems) any { return p.resolveDuration(testEnv, "2h", time.Minute) },
+			want:    2 * time.Hour,
+		},
+		{
+			name:    "duration env over file",
+			env:     "30s",
+			resolve: func(p *configProblems) any { return p.resolveDuration(testEnv, "2h", time.Minute) },
+			want:    30 * time.Second,
+		},
+		{
+			name:        "invalid env falls back to file",
+			env:         "soon",
+			resolve:     func(p *configProblems) any { return p.resolveDuration(testEnv, "2h", time.Minute) },
+			want:        2 * time.Hour,
+			wantProblem: true,
+		},
+		{
+			name:        "invalid file falls back to default",
+			resolve:     func(p *configProblems) any { return p.resolveDuration(testEnv, "later", time.Minute) },
+			want:        time.Minute,
+			wantProblem: true,
+		},
+		{
+			name:    "int from file",
+			resolve: func(p *configProblems) any { return p.resolveInt(testEnv, "1000000", 5) },
+			want:    1000000,
+		},
+		{
+			name:    "int env over file",
+			env:     "7",
+			resolve: func(p *configProblems) any { return p.resolveInt(testEnv, "3", 5) },
+			want:    7,
+		},
+		{
+			name:        "invalid int",
+			env:         "1e+06",
+			resolve:     func(p *configProblems) any { return p.resolveInt(testEnv, "", 5) },
+			want:        5,
+			wantProblem: true,
+		},
+		{
+			name:    "bool default",
+			resolve: func(p *configProblems) any { return p.resolveBool(testEnv, "", true) },
+			want:    true,
+		},
+		{
+			name:    "bool env over file",
+			env:     "false",
+			resolve: func(p *configProblems) any { return p.resolveBool(testEnv, "true", true) },
+			want:    false,
+		},
+		{
+			name:        "invalid bool",
+			resolve:     func(p *configProblems) any { return p.resolveBool(testEnv, "maybe", false) },
+			want:        false,
+			wantProblem: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(testEnv, tt.env)
+
+			var problems configProblems
+			if got := tt.resolve(&problems); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if err := problems.err(); (err != nil) != tt.wantProblem {
+				t.Errorf("config problems = %v, want problem %v", err, tt.wantProblem)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/nonot/claim-controller/internal/api"
+	"github.com/nonot/claim-controller/internal/config"
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+type runtimeConfig struct {
+	defaultTTL        time.Duration
+	maxTTL            time.Duration
+	preProvisionCount int
+	reconcileInterval time.Duration
+	quotasPath        string
+	quotas            *config.QuotasConfig
+}
+
+func loadRuntimeConfig(configPath string, current runtimeConfig) (runtimeConfig, error) {
+	fileConfig, err := config.Load(configPath)
+	if err != nil {
+		return current, err
+	}
+
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var problems configProblems
+	next := current
+	next.quotas = fileConfig.Quotas
+	if !explicit["default-ttl"] {
+		next.defaultTTL = problems.resolveDuration("DEFAULT_TTL", fileConfig.DefaultTTL, defaultTTLValue)
+	}
+	if !explicit["max-ttl"] {
+		next.maxTTL = problems.resolveDuration("MAX_TTL", fileConfig.MaxTTL, defaultMaxTTLValue)
+	}
+	if !explicit["pre-provision-claims-count"] {
+		next.preProvisionCount = problems.resolveInt("PRE_PROVISION_CLAIMS_COUNT", fileConfig.PreProvisionClaimsCount, defaultPreProvisionCount)
+	}
+	if !explicit["reconcile-interval"] {
+		next.reconcileInterval = problems.resolveDuration("RECONCILE_INTERVAL", fileConfig.ReconcileInterval, defaultReconcileInterval)
+	}
+	if !explicit["quotas-path"] {
+		next.quotasPath = resolveString("QUOTAS_PATH", fileConfig.QuotasPath, "")
+	}
+	if next.quotasPath != "" && next.quotas != nil {
+		problems.add("--quotas-path (or QUOTAS_PATH) and the config file quotas section cannot both be set")
+	}
+	problems.checkPositiveDuration("default-ttl", next.defaultTTL)
+	problems.checkNonNegativeDuration("max-ttl", next.maxTTL)
+	problems.checkNonNegativeInt("pre-provision-claims-count", next.preProvisionCount)
+	problems.checkNonNegativeDuration("reconcile-interval", next.reconcileInterval)
+	if err := problems.err(); err != nil {
+		return current, err
+	}
+	return next, nil
+}
+
+func applyRuntimeConfig(cfg runtimeConfig, apiServer *api.Server, reconciler *controller.ClaimReconciler) error {
+	quotas, err := loadQuotaPolicy(cfg.quotasPath, cfg.quotas)
+	if err != nil {
+		return err
+	}
+
+	apiServer.Reload(api.Tunables{
+		DefaultTTL:        cfg.defaultTTL,
+		MaxTTL:            cfg.maxTTL,
+		PreProvisionCount: cfg.preProvisionCount,
+		Quotas:            quotas,
+	})
+	reconciler.Reload(controller.Tunables{
+		DefaultTTL:        cfg.defaultTTL,
+		ReconcileInterval: cfg.reconcileInterval,
+	})
+	return nil
+}
+
+func watchRuntimeConfig(ctx context.Context, logger logr.Logger, configPath string, pollInterval time.Duration, current runtimeConfig, apply func(runtimeConfig) error) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var poll <-chan time.Time
+	if pollInterval > 0 && configPath != "" {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	fingerprint := configFingerprint(configPath, current.quotasPath)
+	for {
+		reason := "SIGHUP"
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		case <-poll:
+			next := configFingerprint(configPath, current.quotasPath)
+			if bytes.Equal(next, fingerprint) {
+				continue
+			}
+			reason = "config file changed"
+		}
+
+		next, err := loadRuntimeConfig(configPath, current)
+		if err == nil {
+			err = apply(next)
+		}
+		fingerprint = configFingerprint(configPath, next.quotasPath)
+		if err != nil {
+			logger.Error(err, "failed to reload runtime configuration, keeping previous values", "reason", reason)
+			continue
+		}
+		current = next
+		logger.Info("reloaded runtime configuration", "reason", reason, "defaultTTL", current.defaultTTL.String(), "maxTTL", current.maxTTL.String(), "preProvisionClaimsCount", current.preProvisionCount, "reconcileInterval", current.reconcileInterval.String(), "quotasPath", current.quotasPath)
+	}
+}
+
+func configFingerprint(paths ...string) []byte {
+	hash := sha256.New()
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, _ := os.ReadFile(path)
+		hash.Write([]byte(path))
+		hash.Write(data)
+	}
+	return hash.Sum(nil)
+}
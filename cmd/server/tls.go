@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const tlsReloadInterval = 10 * time.Second
+
+// clientCAPool reloads the client CA bundle when the mounted file changes, so
+// rotated CAs are trusted without a restart (certwatcher only covers key pairs).
+type clientCAPool struct {
+	path        string
+	logger      logr.Logger
+	pool        atomic.Pointer[x509.CertPool]
+	fingerprint []byte
+}
+
+func newClientCAPool(path string, logger logr.Logger) (*clientCAPool, error) {
+	p := &clientCAPool{path: path, logger: logger}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *clientCAPool) load() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("client CA bundle %s contains no PEM certificates", p.path)
+	}
+	p.pool.Store(pool)
+	p.fingerprint = configFingerprint(p.path)
+	return nil
+}
+
+func (p *clientCAPool) Start(ctx context.Context) error {
+	ticker := time.NewTicker(tlsReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if bytes.Equal(configFingerprint(p.path), p.fingerprint) {
+			continue
+		}
+		if err := p.load(); err != nil {
+			p.logger.Error(err, "failed to reload client CA bundle, keeping previous one")
+			continue
+		}
+		p.logger.Info("reloaded client CA bundle", "path", p.path)
+	}
+}
+
+func (p *clientCAPool) verify(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("parse client certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("client certificate required")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         p.pool.Load(),
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verify client certificate: %w", err)
+	}
+	return nil
+}
+
+func (p *clientCAPool) NeedLeaderElection() bool {
+	return false
+}
+
+// apiTLSConfig returns nil when the API should serve plain HTTP. The returned
+// runnables keep the certificate and client CA up to date and must be started.
+func apiTLSConfig(certPath, keyPath, clientCAPath string, logger logr.Logger) (*tls.Config, []manager.Runnable, error) {
+	if certPath == "" {
+		return nil, nil, nil
+	}
+
+	watcher, err := certwatcher.New(certPath, keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load api tls certificate: %w", err)
+	}
+	watcher = watcher.WithWatchInterval(tlsReloadInterval)
+	runnables := []manager.Runnable{watcher}
+
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: watcher.GetCertificate,
+	}
+	if clientCAPath == "" {
+		return base, runnables, nil
+	}
+
+	clientCAs, err := newClientCAPool(clientCAPath, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	runnables = append(runnables, clientCAs)
+	// Verification is done by hand against the current pool: a static ClientCAs
+	// would not see reloads, and the config is shared with the gRPC server.
+	base.ClientAuth = tls.RequireAnyClientCert
+	base.VerifyPeerCertificate = clientCAs.verify
+	return base, runnables, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// configProblems collects configuration errors so they are reported together.
+// Startup and every reload use their own collector.
+type configProblems []string
+
+func (p *configProblems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p configProblems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration (%d problems):\n  - %s", len(p), strings.Join(p, "\n  - "))
+}
+
+func (p *configProblems) checkPositiveDuration(flagName string, value time.Duration) {
+	if value <= 0 {
+		p.add("--%s must be greater than 0, got %s", flagName, value)
+	}
+}
+
+func (p *configProblems) checkNonNegativeDuration(flagName string, value time.Duration) {
+	if value < 0 {
+		p.add("--%s must not be negative, got %s", flagName, value)
+	}
+}
+
+func (p *configProblems) checkNonNegativeInt(flagName string, value int) {
+	if value < 0 {
+		p.add("--%s must not be negative, got %d", flagName, value)
+	}
+}
+
+func (p *configProblems) checkFileExists(flagName, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		p.add("--%s: %v", flagName, err)
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true}
+
+// accessLogEntry is filled in by inner handlers that know more than the
+// middleware, e.g. the id of a claim created by the request.
+type accessLogEntry struct {
+	claimID   string
+	requester string
+	identity  *Identity
+}
+
+type accessLogContextKey struct{}
+
+func accessLogEntryFrom(ctx context.Context) *accessLogEntry {
+	entry, _ := ctx.Value(accessLogContextKey{}).(*accessLogEntry)
+	return entry
+}
+
+func setAccessLogClaimID(ctx context.Context, claimID string) {
+	if entry := accessLogEntryFrom(ctx); entry != nil {
+		entry.claimID = claimID
+	}
+}
+
+func setAccessLogIdentity(ctx context.Context, identity *Identity) {
+	if entry := accessLogEntryFrom(ctx); entry != nil {
+		entry.requester = identity.Subject
+		entry.identity = identity
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (s *Server) accessLogged(next http.Handler) http.Handler {
+	if !s.accessLog {
+		return next
+	}
+	logger := s.logger.WithName("access")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probePaths[r.URL.Path] && !s.accessLogProbes {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		entry := &accessLogEntry{}
+		recorder := &statusRecorder{ResponseWriter: w}
+		req := r.WithContext(context.WithValue(r.Context(), accessLogContextKey{}, entry))
+		next.ServeHTTP(recorder, req)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if entry.claimID == "" {
+			entry.claimID = req.PathValue("id")
+		}
+		if entry.requester == "" {
+			entry.requester = remoteHost(r)
+		}
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"durationSeconds", time.Since(start).Seconds(),
+			"claimID", entry.claimID,
+			"requester", entry.requester,
+			"requestID", RequestIDFromContext(r.Context()),
+		)
+	})
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
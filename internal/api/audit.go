@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/nonot/claim-controller/internal/audit"
+)
+
+const maxAuditedBodyBytes = 64 * 1024
+
+// auditBody keeps a copy of what the handler read from the request body.
+type auditBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	room := maxAuditedBodyBytes - b.buf.Len()
+	if n > room {
+		b.truncated = true
+	}
+	b.buf.Write(p[:min(n, room)])
+	return n, err
+}
+
+// audited records every mutation served by next in the audit trail. It wraps
+// authenticated so rejected credentials are recorded too; the subject is
+// picked up from the access log entry filled in by authenticated.
+func (s *Server) audited(action string, next http.HandlerFunc) http.HandlerFunc {
+	if s.auditSink == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		entry := accessLogEntryFrom(r.Context())
+		if entry == nil {
+			entry = &accessLogEntry{}
+			r = r.WithContext(context.WithValue(r.Context(), accessLogContextKey{}, entry))
+		}
+		var body *auditBody
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &auditBody{ReadCloser: r.Body}
+			r.Body = body
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		record := audit.Record{
+			Time:      time.Now().UTC(),
+			Action:    action,
+			Requester: remoteHost(r),
+			RequestID: RequestIDFromContext(r.Context()),
+			ClaimID:   entry.claimID,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    recorder.status,
+			Outcome:   audit.Outcome(recorder.status),
+			Params:    auditParams(r, body),
+		}
+		if entry.identity != nil {
+			if entry.identity.Subject != "" {
+				record.Requester = entry.identity.Subject
+			}
+			r = r.WithContext(context.WithValue(r.Context(), identityContextKey{}, entry.identity))
+		}
+		if record.ClaimID == "" {
+			record.ClaimID = r.PathValue("id")
+		}
+		if tenant, err := s.resolveTenant(r); err == nil && tenant != nil {
+			record.Tenant = tenant.Name
+		}
+		s.auditSink.Write(record)
+	}
+}
+
+// auditParams returns the query parameters and the top-level body fields of
+// the request. Values overrides are reduced to their keys since they may
+// carry secrets.
+func auditParams(r *http.Request, body *auditBody) map[string]any {
+	params := map[string]any{}
+	for key, values := range r.URL.Query() {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	if body != nil && body.buf.Len() > 0 {
+		var fields map[string]any
+		switch {
+		case body.truncated:
+			params["body"] = "truncated"
+		case json.Unmarshal(body.buf.Bytes(), &fields) == nil:
+			for key, value := range fields {
+				params[key] = value
+			}
+			if values, ok := fields["values"].(map[string]any); ok {
+				delete(params, "values")
+				keys := make([]string, 0, len(values))
+				for key := range values {
+					keys = append(keys, key)
+				}
+				slices.Sort(keys)
+				params["valuesKeys"] = keys
+			}
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
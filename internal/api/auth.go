@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+var authFailuresTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_auth_failures_total",
+	Help: "Total number of API requests rejected by authentication.",
+}, []string{"route", "reason"})
+
+var (
+	errMissingCredentials = errors.New("missing credentials")
+	errInvalidCredentials = errors.New("invalid credentials")
+)
+
+type Identity struct {
+	Subject string
+	Groups  []string
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *http.Request) (*Identity, error)
+}
+
+// Each authenticator prefixes its subjects and groups so identities from
+// different sources never collide.
+const (
+	apiKeySubjectPrefix      = "apikey:"
+	tokenReviewSubjectPrefix = "k8s:"
+	oidcSubjectPrefix        = "oidc:"
+)
+
+func newIdentity(prefix, subject string, groups []string) *Identity {
+	identity := &Identity{Subject: prefix + subject}
+	for _, group := range groups {
+		identity.Groups = append(identity.Groups, prefix+group)
+	}
+	return identity
+}
+
+type identityContextKey struct{}
+
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityContextKey{}).(*Identity)
+	return identity, ok
+}
+
+func (s *Server) authenticated(route string, next http.HandlerFunc) http.HandlerFunc {
+	if len(s.authenticators) == 0 || !s.authRoutes[route] {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var lastErr error = errMissingCredentials
+		for _, authenticator := range s.authenticators {
+			identity, err := authenticator.Authenticate(r.Context(), r)
+			if err == nil {
+				setAccessLogIdentity(r.Context(), identity)
+				next(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+				return
+			}
+			if !errors.Is(err, errMissingCredentials) {
+				lastErr = err
+			}
+		}
+
+		reason := "invalid"
+		if errors.Is(lastErr, errMissingCredentials) {
+			reason = "missing"
+		} else if !errors.Is(lastErr, errInvalidCredentials) {
+			reason = "error"
+		}
+		authFailuresTotal.WithLabelValues(route, reason).Inc()
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="claim-controller"`)
+		writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "unauthorized")
+	}
+}
+
+func credentialFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	authorization := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+type StaticKeyAuthenticator struct {
+	keys map[[sha256.Size]byte]string
+}
+
+func NewStaticKeyAuthenticator(entries []string) (*StaticKeyAuthenticator, error) {
+	keys := map[[sha256.Size]byte]string{}
+	for i, entry := range entries {
+		name, key, found := strings.Cut(entry, "=")
+		if !found {
+			name, key = fmt.Sprintf("api-key-%d", i+1), entry
+		}
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("api key %q is empty", name)
+		}
+		keys[sha256.Sum256([]byte(key))] = name
+	}
+	return &StaticKeyAuthenticator{keys: keys}, nil
+}
+
+func (a *StaticKeyAuthenticator) Authenticate(_ context.Context, r *http.Request) (*Identity, error) {
+	credential := credentialFromRequest(r)
+	if credential == "" {
+		return nil, errMissingCredentials
+	}
+
+	sum := sha256.Sum256([]byte(credential))
+	for hash, name := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			return newIdentity(apiKeySubjectPrefix, name, nil), nil
+		}
+	}
+	return nil, errInvalidCredentials
+}
+
+type TokenReviewAuthenticator struct {
+	kubeClient kubernetes.Interface
+	audiences  []string
+	cacheTTL   time.Duration
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]tokenReviewResult
+}
+
+type tokenReviewResult struct {
+	identity  *Identity
+	expiresAt time.Time
+}
+
+func NewTokenReviewAuthenticator(kubeClient kubernetes.Interface, audiences []string) *TokenReviewAuthenticator {
+	return &TokenReviewAuthenticator{
+		kubeClient: kubeClient,
+		audiences:  audiences,
+		cacheTTL:   time.Minute,
+		cache:      map[[sha256.Size]byte]tokenReviewResult{},
+	}
+}
+
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, r *http.Request) (*Identity, error) {
+	token := credentialFromRequest(r)
+	if token == "" {
+		return nil, errMissingCredentials
+	}
+
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	a.mu.Lock()
+	if cached, ok := a.cache[key]; ok && now.Before(cached.expiresAt) {
+		a.mu.Unlock()
+		return cached.identity, nil
+	}
+	a.mu.Unlock()
+
+	review, err := a.kubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: a.audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("token review: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, errInvalidCredentials
+	}
+
+	identity := newIdentity(tokenReviewSubjectPrefix, review.Status.User.Username, review.Status.User.Groups)
+	a.mu.Lock()
+	for cachedKey, cached := range a.cache {
+		if now.After(cached.expiresAt) {
+			delete(a.cache, cachedKey)
+		}
+	}
+	a.cache[key] = tokenReviewResult{identity: identity, expiresAt: now.Add(a.cacheTTL)}
+	a.mu.Unlock()
+
+	return identity, nil
+}
+
+var errNotClaimOwner = errors.New("claim is owned by another identity")
+
+// isAdmin reports whether the request identity is listed in the admin
+// subjects, either by subject or as group:<name>.
+func (s *Server) isAdmin(r *http.Request) bool {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok {
+		return false
+	}
+	if s.adminSubjects[identity.Subject] {
+		return true
+	}
+	for _, group := range identity.Groups {
+		if s.adminSubjects["group:"+group] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	if len(s.adminSubjects) == 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r) {
+			writeProblem(w, r, http.StatusForbidden, problemForbidden, "admin routes are restricted to the admin subjects")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// findOwnedClaims is findRequestClaims for mutations: an authenticated
+// caller may only change the claims it owns, unless it is an admin. Claims
+// without an owner are admin-only.
+func (s *Server) findOwnedClaims(ctx context.Context, r *http.Request, claimID string) ([]corev1.ConfigMap, error) {
+	claims, err := s.findRequestClaims(ctx, r, claimID)
+	if err != nil {
+		return nil, err
+	}
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok || identity.Subject == "" || s.isAdmin(r) {
+		return claims, nil
+	}
+	for _, claim := range claims {
+		if claim.Annotations[controller.OwnerAnnotationKey] != identity.Subject {
+			return nil, errNotClaimOwner
+		}
+	}
+	return claims, nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func TestStaticKeyAuthenticator(t *testing.T) {
+	authenticator, err := NewStaticKeyAuthenticator([]string{"ci=s3cr3t", "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{name: "api key header", header: "X-API-Key", value: "s3cr3t", want: "apikey:ci"},
+		{name: "bearer", header: "Authorization", value: "Bearer other", want: "apikey:api-key-2"},
+		{name: "missing", wantErr: errMissingCredentials},
+		{name: "invalid", header: "X-API-Key", value: "nope", wantErr: errInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/claims", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			identity, err := authenticator.Authenticate(context.Background(), req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && identity.Subject != tt.want {
+				t.Errorf("subject = %q, want %q", identity.Subject, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenReviewAuthenticatorPrefixesIdentity(t *testing.T) {
+	kubeClient := kubefake.NewClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, &authenticationv1.TokenReview{Status: authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			User:          authenticationv1.UserInfo{Username: "alice", Groups: []string{"devs"}},
+		}}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/claims", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	identity, err := NewTokenReviewAuthenticator(kubeClient, nil).Authenticate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Identity{Subject: "k8s:alice", Groups: []string{"k8s:devs"}}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("identity = %+v, want %+v", identity, want)
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	s := newTestServer(t, Config{AdminSubjects: []string{"k8s:alice", "group:k8s:admins"}})
+
+	tests := []struct {
+		name     string
+		identity *Identity
+		want     bool
+	}{
+		{name: "subject", identity: newIdentity(tokenReviewSubjectPrefix, "alice", nil), want: true},
+		{name: "group", identity: newIdentity(tokenReviewSubjectPrefix, "bob", []string{"admins"}), want: true},
+		{name: "same name from oidc", identity: newIdentity(oidcSubjectPrefix, "alice", nil)},
+		{name: "same group from oidc", identity: newIdentity(oidcSubjectPrefix, "bob", []string{"admins"})},
+		{name: "oidc subject shaped like a group entry", identity: newIdentity(oidcSubjectPrefix, "group:k8s:admins", nil)},
+		{name: "anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/pool", nil)
+			if tt.identity != nil {
+				req = req.WithContext(context.WithValue(req.Context(), identityContextKey{}, tt.identity))
+			}
+			if got := s.isAdmin(req); got != tt.want {
+				t.Errorf("isAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindOwnedClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   string
+		subject string
+		wantErr error
+	}{
+		{name: "owner", owner: "oidc:alice", subject: "oidc:alice"},
+		{name: "other subject", owner: "oidc:alice", subject: "oidc:bob", wantErr: errNotClaimOwner},
+		{name: "same name from another source", owner: "oidc:alice", subject: "k8s:alice", wantErr: errNotClaimOwner},
+		{name: "owner-less claim", subject: "oidc:bob", wantErr: errNotClaimOwner},
+		{name: "admin on owner-less claim", subject: "oidc:admin"},
+		{name: "anonymous without auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.owner != "" {
+				annotations[controller.OwnerAnnotationKey] = tt.owner
+			}
+			s := newTestServer(t, Config{AdminSubjects: []string{"oidc:admin"}}, testClaim("abc", annotations))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/renew/abc", nil)
+			if tt.subject != "" {
+				req = withIdentity(req, tt.subject)
+			}
+			_, err := s.findOwnedClaims(context.Background(), req, "abc")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const (
+	apiTimeoutRetryAfter   = 5 * time.Second
+	defaultReadyRetryAfter = 30 * time.Second
+)
+
+func (s *Server) checkCapacity(ctx context.Context) (bool, time.Duration, error) {
+	if s.maxActiveClaims <= 0 {
+		return true, 0, nil
+	}
+
+	claims := &corev1.ConfigMapList{}
+	if err := s.listClaims(ctx, claims, client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}); err != nil {
+		return false, 0, err
+	}
+
+	now := time.Now().UTC()
+	active := 0
+	var nextExpiry time.Duration
+	for _, claim := range claims.Items {
+		if claim.DeletionTimestamp != nil || claim.Annotations[controller.PreProvisionedAnnotationKey] == "true" {
+			continue
+		}
+		active++
+		if expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey]); err == nil {
+			if untilExpiry := expiresAt.Sub(now); nextExpiry == 0 || untilExpiry < nextExpiry {
+				nextExpiry = untilExpiry
+			}
+		}
+	}
+
+	if active < s.maxActiveClaims {
+		return true, 0, nil
+	}
+	return false, max(nextExpiry, time.Second), nil
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(max(d, time.Second).Seconds()))
+}
+
+// poolRetryAfter allows one replenishment pass per pool-sized batch queued.
+func (s *Server) poolRetryAfter() time.Duration {
+	passes := 1
+	if target := s.poolTarget(); target > 0 {
+		passes += s.claimQueue.pending() / target
+	}
+	return time.Duration(passes) * s.poolReplenishEvery
+}
+
+func (s *Server) observeReadyDuration(d time.Duration) {
+	for {
+		previous := s.readyDurationAverage.Load()
+		next := int64(d)
+		if previous > 0 {
+			next = previous + (int64(d)-previous)/5
+		}
+		if s.readyDurationAverage.CompareAndSwap(previous, next) {
+			return
+		}
+	}
+}
+
+func (s *Server) readyRetryAfter() time.Duration {
+	if average := time.Duration(s.readyDurationAverage.Load()); average > 0 {
+		return average
+	}
+	return defaultReadyRetryAfter
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+// claimChanges wakes Watch streams when a claim ConfigMap changes in the
+// manager cache, keyed by claim id.
+type claimChanges struct {
+	mu   sync.Mutex
+	wake map[string]chan struct{}
+}
+
+func newClaimChanges() *claimChanges {
+	return &claimChanges{wake: map[string]chan struct{}{}}
+}
+
+// changed returns a channel closed on the next change of the claim.
+func (c *claimChanges) changed(id string) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wake, ok := c.wake[id]
+	if !ok {
+		wake = make(chan struct{})
+		c.wake[id] = wake
+	}
+	return wake
+}
+
+func (c *claimChanges) signal(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if wake, ok := c.wake[id]; ok {
+		close(wake)
+		delete(c.wake, id)
+	}
+}
+
+func (c *claimChanges) signalObject(obj any) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if claim, ok := obj.(*corev1.ConfigMap); ok {
+		if id := claim.Labels[controller.ClaimLabelKeyId]; id != "" {
+			c.signal(id)
+		}
+	}
+}
+
+// WatchClaimChanges lets gRPC Watch streams react to claim updates instead of
+// polling. informer is the manager cache informer for ConfigMaps.
+func (s *Server) WatchClaimChanges(informer cache.Informer) error {
+	_, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    s.claimChanges.signalObject,
+		UpdateFunc: func(_, obj any) { s.claimChanges.signalObject(obj) },
+		DeleteFunc: s.claimChanges.signalObject,
+	})
+	return err
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+// cloneRequest lists the only fields a clone may set; everything else comes
+// from the source claim.
+type cloneRequest struct {
+	TTL          string         `json:"ttl,omitempty"`
+	Priority     string         `json:"priority,omitempty"`
+	ReadyTimeout string         `json:"readyTimeout,omitempty"`
+	MinReady     string         `json:"minReady,omitempty"`
+	Metadata     *ClaimMetadata `json:"metadata,omitempty"`
+}
+
+func (s *Server) handleClone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	claimID := strings.TrimSpace(r.PathValue("id"))
+	if claimID == "" {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "id is required")
+		return
+	}
+
+	var body cloneRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		writeDecodeProblem(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	claims, err := s.findRequestClaims(ctx, r, claimID)
+	if err != nil {
+		writeClaimLookupProblem(w, r, err)
+		return
+	}
+	source := claims[0]
+
+	profile, err := s.resolveProfile(source.Labels[controller.ProfileLabelKey])
+	if err != nil {
+		writeProblem(w, r, http.StatusConflict, problemConflict, fmt.Sprintf("cannot clone claim: %v", err))
+		return
+	}
+	current, err := s.currentRenderVersion(profile)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to compute template version")
+		return
+	}
+	templateHash := source.Annotations[controller.TemplateHashAnnotationKey]
+	valuesHash := source.Annotations[controller.ValuesHashAnnotationKey]
+	if (templateHash != current.templateHash || valuesHash != current.valuesHash) && !strings.EqualFold(r.URL.Query().Get("allowDrift"), "true") {
+		writeProblem(w, r, http.StatusConflict, problemTemplateDrift, fmt.Sprintf("claim was rendered from template %s / values %s but profile %q is now at template %s / values %s; retry with ?allowDrift=true to clone with the current version", templateHash, valuesHash, profile.Name, current.templateHash, current.valuesHash))
+		return
+	}
+
+	req := claimRequest{
+		TTL:          body.TTL,
+		Profile:      profile.Name,
+		Namespace:    source.Namespace,
+		Cluster:      source.Annotations[controller.ClusterAnnotationKey],
+		Team:         source.Labels[controller.TeamLabelKey],
+		Priority:     body.Priority,
+		Metadata:     body.Metadata,
+		ReadyTimeout: body.ReadyTimeout,
+		MinReady:     body.MinReady,
+	}
+	if req.MinReady == "" {
+		req.MinReady = source.Annotations[controller.MinReadyAnnotationKey]
+	}
+	if raw := strings.TrimSpace(source.Annotations[controller.ValuesOverridesAnnotationKey]); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &req.Values); err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to decode recorded values overrides")
+			return
+		}
+	}
+
+	s.serveClaim(w, r, req, source.Name)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/template"
+)
+
+func cloneSource() *corev1.ConfigMap {
+	source := testClaim("src", map[string]string{
+		controller.OwnerAnnotationKey:        "owner",
+		controller.TemplateHashAnnotationKey: "v1",
+		controller.ValuesHashAnnotationKey:   template.HashBytes([]byte("{}\n")),
+	})
+	source.Labels[controller.PriorityLabelKey] = PriorityHigh
+	return source
+}
+
+func TestHandleClone(t *testing.T) {
+	tests := []struct {
+		name       string
+		subject    string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "unsupported field", subject: "owner", body: `{"values":{"a":1}}`, wantStatus: http.StatusBadRequest, wantBody: "unknown field"},
+		{name: "expiresAt is not cloneable", subject: "owner", body: `{"expiresAt":"2030-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest, wantBody: "unknown field"},
+		{name: "high priority needs admin", subject: "owner", body: `{"priority":"high"}`, wantStatus: http.StatusForbidden, wantBody: "high priority"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{AdminSubjects: []string{"admin"}}, cloneSource())
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/claim/src/clone", strings.NewReader(tt.body))
+			req.SetPathValue("id", "src")
+			rec := httptest.NewRecorder()
+			s.handleClone(rec, withIdentity(req, tt.subject))
+
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("status = %d, body = %s; want %d containing %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandleCloneDoesNotInheritPriority(t *testing.T) {
+	source := cloneSource()
+	s := newTestServer(t, Config{ClaimReadyTimeout: 1}, source)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/claim/src/clone", strings.NewReader(`{}`))
+	req.SetPathValue("id", "src")
+	s.handleClone(httptest.NewRecorder(), withIdentity(req, "owner"))
+
+	claims := &corev1.ConfigMapList{}
+	if err := s.client.List(context.Background(), claims, client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}); err != nil {
+		t.Fatal(err)
+	}
+	cloned := 0
+	for _, claim := range claims.Items {
+		if claim.Annotations[controller.ClonedFromAnnotationKey] != source.Name {
+			continue
+		}
+		cloned++
+		if got := claim.Labels[controller.PriorityLabelKey]; got != PriorityNormal {
+			t.Errorf("clone priority = %q, want %q", got, PriorityNormal)
+		}
+	}
+	if cloned != 1 {
+		t.Fatalf("found %d clones, want 1", cloned)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const corsMaxAge = 10 * 60
+
+var (
+	defaultCORSAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPatch}
+	defaultCORSAllowedHeaders = []string{"Authorization", "Content-Type", "X-API-Key", IdempotencyKeyHeader, TenantHeader, RequestIDHeader, APIVersionHeader}
+	corsExposedHeaders        = []string{RequestIDHeader, APIVersionHeader, "Retry-After", "Deprecation", "Link", "Idempotent-Replayed"}
+)
+
+type corsPolicy struct {
+	origins []string
+	methods string
+	headers string
+}
+
+func newCORSPolicy(origins, methods, headers []string) *corsPolicy {
+	if len(origins) == 0 {
+		return nil
+	}
+	if len(methods) == 0 {
+		methods = defaultCORSAllowedMethods
+	}
+	if len(headers) == 0 {
+		headers = defaultCORSAllowedHeaders
+	}
+	return &corsPolicy{
+		origins: origins,
+		methods: strings.Join(methods, ", "),
+		headers: strings.Join(headers, ", "),
+	}
+}
+
+func (p *corsPolicy) allowedOrigin(origin string) string {
+	if slices.Contains(p.origins, "*") {
+		return "*"
+	}
+	if slices.ContainsFunc(p.origins, func(allowed string) bool { return strings.EqualFold(allowed, origin) }) {
+		return origin
+	}
+	return ""
+}
+
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	if s.cors == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := s.cors.allowedOrigin(origin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if allowed == "" {
+			if preflight {
+				writeProblem(w, r, http.StatusForbidden, problemForbidden, "origin not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", s.cors.methods)
+			w.Header().Set("Access-Control-Allow-Headers", s.cors.headers)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const credentialLength = 24
+
+func credentialsSecretName(claimName string) string {
+	return claimName + "-credentials"
+}
+
+func generateCredential(n int) (string, error) {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = letters[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+func (s *Server) createClaimCredentials(ctx context.Context, claim *corev1.ConfigMap) error {
+	if len(s.credentialKeys) == 0 {
+		return nil
+	}
+
+	data := make(map[string][]byte, len(s.credentialKeys))
+	for _, key := range s.credentialKeys {
+		value, err := generateCredential(credentialLength)
+		if err != nil {
+			return fmt.Errorf("generate credential %q: %w", key, err)
+		}
+		data[key] = []byte(value)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      credentialsSecretName(claim.Name),
+			Namespace: claim.Namespace,
+			Labels: map[string]string{
+				controller.ManagedByLabelKey: controller.ManagedByLabelValue,
+				controller.ClaimLabelKey:     claim.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         "v1",
+				Kind:               "ConfigMap",
+				Name:               claim.Name,
+				UID:                claim.UID,
+				Controller:         ptr.To(true),
+				BlockOwnerDeletion: ptr.To(true),
+			}},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	if err := s.client.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create credentials secret: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) readClaimCredentials(ctx context.Context, claim *corev1.ConfigMap) (map[string]string, error) {
+	if len(s.credentialKeys) == 0 {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: credentialsSecretName(claim.Name)}, secret); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		out[key] = string(value)
+	}
+	return out, nil
+}
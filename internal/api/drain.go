@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const drainRetryAfter = 5 * time.Second
+
+var errDrained = errors.New("server is draining")
+
+// beginClaimRequest registers an in-flight claim request, unless the server is
+// draining and should not take new claims.
+func (s *Server) beginClaimRequest() bool {
+	s.drainMu.RLock()
+	defer s.drainMu.RUnlock()
+	if s.draining {
+		return false
+	}
+	s.claimRequests.Add(1)
+	return true
+}
+
+func (s *Server) endClaimRequest() {
+	s.claimRequests.Done()
+}
+
+// Drain stops accepting new claims and waits for in-flight claim requests to
+// complete. Requests still waiting for readiness when ctx ends are answered
+// with 202 and the claim status path, so clients can poll another replica.
+func (s *Server) Drain(ctx context.Context) {
+	s.drainMu.Lock()
+	alreadyDraining := s.draining
+	s.draining = true
+	s.drainMu.Unlock()
+	if alreadyDraining {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.claimRequests.Wait()
+		close(done)
+	}()
+
+	s.logger.Info("draining in-flight claim requests")
+	select {
+	case <-done:
+		s.logger.Info("claim requests drained")
+		return
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("drain deadline reached, answering pending claim requests asynchronously")
+	s.stopDrain()
+	<-done
+}
+
+func (s *Server) checkDraining(_ *http.Request) error {
+	s.drainMu.RLock()
+	defer s.drainMu.RUnlock()
+	if s.draining {
+		return errDrained
+	}
+	return nil
+}
+
+// drainAware returns a context cancelled with errDrained once the drain deadline is reached.
+func (s *Server) drainAware(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(s.drainCtx, func() { cancel(errDrained) })
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+func (s *Server) waitForClaimReadyOrDrain(ctx context.Context, claim *corev1.ConfigMap, timeout time.Duration) (*corev1.ConfigMap, bool, error) {
+	waitCtx, cancel := s.drainAware(ctx)
+	defer cancel()
+	readyClaim, err := s.waitForClaimReady(waitCtx, claim.Namespace, claim.Name, timeout)
+	if err != nil && errors.Is(context.Cause(waitCtx), errDrained) {
+		return nil, true, nil
+	}
+	return readyClaim, false, err
+}
+
+func writeDrainingProblem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(drainRetryAfter)))
+	writeProblem(w, r, http.StatusServiceUnavailable, problemShuttingDown, "server is shutting down, retry on another instance")
+}
+
+// writeClaimTicket answers a claim request whose readiness wait was cut short
+// by a drain; the claim itself keeps being provisioned.
+func writeClaimTicket(w http.ResponseWriter, r *http.Request, claimID string, expiresAt time.Time, profileName string) {
+	setAccessLogClaimID(r.Context(), claimID)
+	writeJSON(w, http.StatusAccepted, scheduledClaimResponse{
+		Status:        "pending",
+		ID:            claimID,
+		ExpiresAt:     expiresAt.Format(time.RFC3339),
+		Profile:       profileName,
+		StatusPath:    apiV1.path("/claims/%s", claimID),
+		ReleasePath:   apiV1.path("/release/%s", claimID),
+		ReleaseMethod: http.MethodPost,
+	})
+}
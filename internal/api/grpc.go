@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcWatchResync re-reads the claim of a Watch stream when no change was
+// signalled, in case an informer event was missed.
+const grpcWatchResync = 30 * time.Second
+
+var grpcForwardedMetadata = []string{"authorization", "x-api-key", strings.ToLower(IdempotencyKeyHeader), strings.ToLower(TenantHeader), strings.ToLower(RequestIDHeader)}
+
+type grpcRequestBuilder func(ctx context.Context, req *dynamicpb.Message) (*http.Request, error)
+
+type grpcResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *grpcResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *grpcResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (s *Server) RegisterGRPC(registrar grpc.ServiceRegistrar) error {
+	service, err := loadClaimServiceDescriptor()
+	if err != nil {
+		return err
+	}
+	methods := service.Methods()
+
+	registrar.RegisterService(&grpc.ServiceDesc{
+		ServiceName: grpcServiceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Claim", Handler: s.grpcUnary(methods.ByName("Claim"), grpcClaimRequest)},
+			{MethodName: "Renew", Handler: s.grpcUnary(methods.ByName("Renew"), grpcRenewRequest)},
+			{MethodName: "Release", Handler: s.grpcUnary(methods.ByName("Release"), grpcReleaseRequest)},
+		},
+		Streams: []grpc.StreamDesc{
+			{StreamName: "Watch", Handler: s.grpcWatch(methods.ByName("Watch")), ServerStreams: true},
+		},
+		Metadata: "claimcontroller/v1/claim.proto",
+	}, s)
+	return nil
+}
+
+func (s *Server) grpcUnary(method protoreflect.MethodDescriptor, build grpcRequestBuilder) grpc.MethodHandler {
+	return func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := dynamicpb.NewMessage(method.Input())
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, in any) (any, error) {
+			httpReq, err := build(ctx, in.(*dynamicpb.Message))
+			if err != nil {
+				return nil, err
+			}
+			return s.serveGRPC(httpReq, method.Output())
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: s, FullMethod: fmt.Sprintf("/%s/%s", grpcServiceName, method.Name())}, handler)
+	}
+}
+
+func (s *Server) grpcWatch(method protoreflect.MethodDescriptor) grpc.StreamHandler {
+	return func(_ any, stream grpc.ServerStream) error {
+		req := dynamicpb.NewMessage(method.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		id := grpcString(req, "id")
+		if id == "" {
+			return status.Error(codes.InvalidArgument, "id is required")
+		}
+
+		ctx := stream.Context()
+		resync := time.NewTimer(grpcWatchResync)
+		defer resync.Stop()
+
+		var last []byte
+		for {
+			// Subscribe before reading so a change in between is not missed.
+			changed := s.claimChanges.changed(id)
+			httpReq, err := newGRPCHTTPRequest(ctx, http.MethodGet, apiV1.path("/claims/%s", url.PathEscape(id)), nil)
+			if err != nil {
+				return err
+			}
+			recorder := s.recordGRPC(httpReq)
+			switch {
+			case recorder.status == http.StatusNotFound && last != nil:
+				return nil
+			case recorder.status != http.StatusOK:
+				return grpcStatusFromHTTP(recorder)
+			case !bytes.Equal(recorder.body.Bytes(), last):
+				update := dynamicpb.NewMessage(method.Output())
+				if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(recorder.body.Bytes(), update); err != nil {
+					return status.Errorf(codes.Internal, "decode claim status: %v", err)
+				}
+				if err := stream.SendMsg(update); err != nil {
+					return err
+				}
+				last = bytes.Clone(recorder.body.Bytes())
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-changed:
+			case <-resync.C:
+			}
+			resync.Reset(grpcWatchResync)
+		}
+	}
+}
+
+func grpcClaimRequest(ctx context.Context, req *dynamicpb.Message) (*http.Request, error) {
+	body, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "encode claim request: %v", err)
+	}
+	return newGRPCHTTPRequest(ctx, http.MethodPost, apiV1.path("/claim"), body)
+}
+
+func grpcRenewRequest(ctx context.Context, req *dynamicpb.Message) (*http.Request, error) {
+	if grpcString(req, "id") == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	// The id travels in the path; the renew body only carries the TTL fields.
+	payload := proto.Clone(req).(*dynamicpb.Message)
+	payload.Clear(payload.Descriptor().Fields().ByName("id"))
+	body, err := protojson.Marshal(payload)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "encode renew request: %v", err)
+	}
+	return newGRPCHTTPRequest(ctx, http.MethodPost, apiV1.path("/renew/%s", url.PathEscape(grpcString(req, "id"))), body)
+}
+
+func grpcReleaseRequest(ctx context.Context, req *dynamicpb.Message) (*http.Request, error) {
+	if grpcString(req, "id") == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	query := url.Values{}
+	query.Set("wait", strconv.FormatBool(grpcBool(req, "wait")))
+	query.Set("retain", strconv.FormatBool(grpcBool(req, "retain")))
+	return newGRPCHTTPRequest(ctx, http.MethodPost, apiV1.path("/release/%s?%s", url.PathEscape(grpcString(req, "id")), query.Encode()), nil)
+}
+
+func newGRPCHTTPRequest(ctx context.Context, method, target string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range grpcForwardedMetadata {
+			if values := md.Get(key); len(values) > 0 {
+				req.Header.Set(key, values[0])
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+	return req, nil
+}
+
+func (s *Server) recordGRPC(req *http.Request) *grpcResponseWriter {
+	recorder := &grpcResponseWriter{header: http.Header{}}
+	s.Handler().ServeHTTP(recorder, req)
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	return recorder
+}
+
+func (s *Server) serveGRPC(req *http.Request, output protoreflect.MessageDescriptor) (*dynamicpb.Message, error) {
+	recorder := s.recordGRPC(req)
+	if recorder.status >= http.StatusBadRequest {
+		return nil, grpcStatusFromHTTP(recorder)
+	}
+
+	resp := dynamicpb.NewMessage(output)
+	if recorder.status == http.StatusNoContent || recorder.body.Len() == 0 {
+		if field := output.Fields().ByName("status"); field != nil {
+			resp.Set(field, protoreflect.ValueOfString("released"))
+		}
+		return resp, nil
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(recorder.body.Bytes(), resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "decode response: %v", err)
+	}
+	return resp, nil
+}
+
+func grpcStatusFromHTTP(recorder *grpcResponseWriter) error {
+	message := strings.TrimSpace(recorder.body.String())
+	var problem Problem
+	if json.Unmarshal(recorder.body.Bytes(), &problem) == nil && problem.Detail != "" {
+		message = problem.Detail
+	}
+	code := codes.Internal
+	switch recorder.status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, message)
+}
+
+func grpcString(msg *dynamicpb.Message, name protoreflect.Name) string {
+	return msg.Get(msg.Descriptor().Fields().ByName(name)).String()
+}
+
+func grpcBool(msg *dynamicpb.Message, name protoreflect.Name) bool {
+	return msg.Get(msg.Descriptor().Fields().ByName(name)).Bool()
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	grpcPackage     = "claimcontroller.v1"
+	grpcServiceName = grpcPackage + ".ClaimService"
+)
+
+type protoFieldSpec struct {
+	name     string
+	kind     descriptorpb.FieldDescriptorProto_Type
+	typeName string
+	repeated bool
+	mapEntry bool
+}
+
+func stringField(name string) protoFieldSpec {
+	return protoFieldSpec{name: name, kind: descriptorpb.FieldDescriptorProto_TYPE_STRING}
+}
+
+func boolField(name string) protoFieldSpec {
+	return protoFieldSpec{name: name, kind: descriptorpb.FieldDescriptorProto_TYPE_BOOL}
+}
+
+func messageField(name, typeName string, repeated bool) protoFieldSpec {
+	return protoFieldSpec{name: name, kind: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName: typeName, repeated: repeated}
+}
+
+func stringMapField(name string) protoFieldSpec {
+	return protoFieldSpec{name: name, kind: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated: true, mapEntry: true}
+}
+
+func protoMessage(name string, fields ...protoFieldSpec) *descriptorpb.DescriptorProto {
+	message := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for i, spec := range fields {
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(spec.name),
+			Number: proto.Int32(int32(i + 1)),
+			Type:   spec.kind.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if spec.repeated {
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}
+		if spec.typeName != "" {
+			field.TypeName = proto.String(spec.typeName)
+		}
+		if spec.mapEntry {
+			entryName := mapEntryName(spec.name)
+			field.TypeName = proto.String(fmt.Sprintf(".%s.%s.%s", grpcPackage, name, entryName))
+			message.NestedType = append(message.NestedType, &descriptorpb.DescriptorProto{
+				Name:    proto.String(entryName),
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("key"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), JsonName: proto.String("key")},
+					{Name: proto.String("value"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), JsonName: proto.String("value")},
+				},
+			})
+		}
+		message.Field = append(message.Field, field)
+	}
+	return message
+}
+
+func mapEntryName(fieldName string) string {
+	camel := ""
+	upper := true
+	for _, r := range fieldName {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upper = false
+		camel += string(r)
+	}
+	return camel + "Entry"
+}
+
+func protoMethod(name, input, output string, serverStreaming bool) *descriptorpb.MethodDescriptorProto {
+	method := &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String("." + grpcPackage + "." + input),
+		OutputType: proto.String("." + grpcPackage + "." + output),
+	}
+	if serverStreaming {
+		method.ServerStreaming = proto.Bool(true)
+	}
+	return method
+}
+
+// Mirrors proto/claimcontroller/v1/claim.proto; field order defines field numbers.
+func claimServiceFileDescriptor() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("claimcontroller/v1/claim.proto"),
+		Package:    proto.String(grpcPackage),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/struct.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			protoMessage("ClaimRequest",
+				stringField("ttl"), stringField("expires_at"), stringField("profile"), stringField("namespace"), stringField("cluster"),
+				stringField("team"), stringField("priority"), stringField("idempotency_key"), messageField("values", ".google.protobuf.Struct", false),
+				stringField("start_at"), stringField("ready_timeout"), stringField("min_ready"), messageField("metadata", "."+grpcPackage+".ClaimMetadata", false),
+				boolField("working_hours_exempt")),
+			protoMessage("ClaimMetadata", stringField("purpose"), stringField("repo"), stringField("pipeline_url"), stringMapField("labels")),
+			protoMessage("ClaimResponse",
+				stringField("id"), stringField("status"), stringField("expires_at"), stringField("profile"), boolField("pre_provisioned"),
+				stringMapField("data"), stringField("namespace"), stringField("cluster"), stringField("kubeconfig"), stringMapField("credentials"),
+				stringField("credentials_secret"), stringField("start_at"), stringField("cloned_from"), messageField("metadata", "."+grpcPackage+".ClaimMetadata", false)),
+			protoMessage("RenewRequest", stringField("id"), stringField("ttl"), stringField("expires_at")),
+			protoMessage("RenewResponse", stringField("id"), stringField("status"), stringField("expires_at")),
+			protoMessage("ReleaseRequest", stringField("id"), boolField("wait"), boolField("retain")),
+			protoMessage("ReleaseResponse", stringField("status"), stringField("message"), stringField("retained_until")),
+			protoMessage("WatchRequest", stringField("id")),
+			protoMessage("ResourceStatus", stringField("kind"), stringField("name"), stringField("namespace"), boolField("ready"), stringField("message"), boolField("failed")),
+			protoMessage("Condition", stringField("type"), stringField("status"), stringField("reason"), stringField("message"), stringField("last_transition_time")),
+			protoMessage("ClaimStatus",
+				stringField("id"), stringField("name"), stringField("status"), stringField("message"), stringField("profile"),
+				stringField("expires_at"), boolField("suspended"), messageField("resources", "."+grpcPackage+".ResourceStatus", true),
+				messageField("conditions", "."+grpcPackage+".Condition", true)),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ClaimService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				protoMethod("Claim", "ClaimRequest", "ClaimResponse", false),
+				protoMethod("Renew", "RenewRequest", "RenewResponse", false),
+				protoMethod("Release", "ReleaseRequest", "ReleaseResponse", false),
+				protoMethod("Watch", "WatchRequest", "ClaimStatus", true),
+			},
+		}},
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("github.com/nonot/claim-controller/proto/claimcontroller/v1;claimcontrollerv1")},
+	}
+}
+
+func loadClaimServiceDescriptor() (protoreflect.ServiceDescriptor, error) {
+	file, err := protoregistry.GlobalFiles.FindFileByPath("claimcontroller/v1/claim.proto")
+	if err != nil {
+		file, err = protodesc.NewFile(claimServiceFileDescriptor(), protoregistry.GlobalFiles)
+		if err != nil {
+			return nil, fmt.Errorf("build claim service descriptor: %w", err)
+		}
+		if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+			return nil, fmt.Errorf("register claim service descriptor: %w", err)
+		}
+	}
+	return file.Services().ByName("ClaimService"), nil
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// ReadinessChecks returns the checks behind /readyz, including the ones given
+// in Config, so the manager probe endpoint can report the same components.
+func (s *Server) ReadinessChecks() map[string]healthz.Checker {
+	return s.readinessChecks
+}
+
+// probe serves the aggregated checks on path and each check on path/<name>,
+// e.g. /readyz/values.
+func (s *Server) probe(path string, checks map[string]healthz.Checker) {
+	handler := http.StripPrefix(path, &healthz.Handler{Checks: checks})
+	s.mux.Handle(path, handler)
+	s.mux.Handle(path+"/", handler)
+}
+
+func (s *Server) checkValues(_ *http.Request) error {
+	for name, profile := range s.profiles {
+		if profile.ValuesProvider == nil {
+			continue
+		}
+		if _, err := profile.ValuesProvider.GetValues(); err != nil {
+			return fmt.Errorf("profile %q values provider %s: %w", name, profile.ValuesProvider.Description(), err)
+		}
+	}
+	return nil
+}
+
+type renderCheckResult struct {
+	err error
+}
+
+// runRenderCheck periodically renders every profile with a synthetic claim id,
+// so an instance whose template or values broke after startup stops being ready.
+func (s *Server) runRenderCheck(ctx context.Context) {
+	ticker := time.NewTicker(s.renderCheckEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.renderProfiles()
+		previous := s.renderCheck.Swap(&renderCheckResult{err: err})
+		switch {
+		case err != nil && (previous == nil || previous.err == nil):
+			s.logger.Error(err, "background template render failed, reporting not ready")
+		case err == nil && previous != nil && previous.err != nil:
+			s.logger.Info("background template render recovered")
+		}
+	}
+}
+
+func (s *Server) renderProfiles() error {
+	for _, name := range s.profileNames() {
+		if result := s.validateProfile(s.profiles[name]); !result.Valid {
+			return fmt.Errorf("profile %q does not render: %s", name, result.Error)
+		}
+	}
+	return nil
+}
+
+func (s *Server) checkRender(_ *http.Request) error {
+	result := s.renderCheck.Load()
+	if result == nil {
+		return errors.New("template has not been rendered yet")
+	}
+	return result.err
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/template"
+)
+
+// releaseResourceTemplate renders the claim release without touching the
+// cluster and returns the values the controller installs it with.
+func (s *Server) releaseResourceTemplate(ctx context.Context, claimID string, opts claimOptions) (template.ResourceTemplate, renderVersion, []byte, error) {
+	profile := opts.profile
+	if profile == nil {
+		profile = s.defaultProfile
+	}
+	if s.helmReleases == nil {
+		return template.ResourceTemplate{}, renderVersion{}, nil, fmt.Errorf("helm release backend is not configured")
+	}
+	renderer, ok := profile.Renderer.(*template.HelmRenderer)
+	if !ok {
+		return template.ResourceTemplate{}, renderVersion{}, nil, fmt.Errorf("profile %q uses the helm-release backend without the helm renderer", profile.Name)
+	}
+
+	valuesData, version, err := s.claimValues(profile, opts)
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, nil, err
+	}
+	chartObj, err := renderer.ReleaseChart(claimID)
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, nil, err
+	}
+	releaseValues, err := chartutil.ReadValues(valuesData)
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, nil, fmt.Errorf("decode values file: %w", err)
+	}
+
+	namespace := s.resourceNamespace(claimID, opts)
+	manifest, err := s.helmReleases.Render(ctx, namespace, fmt.Sprintf("claim-%s", claimID), chartObj, releaseValues)
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, nil, err
+	}
+
+	resourceTemplate, err := template.DecodeManifests(manifest)
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, nil, err
+	}
+	return resourceTemplate, version, valuesData, nil
+}
+
+// ReleaseChart returns the chart the controller installs a helm-release claim
+// from, taken from the claim profile.
+func (s *Server) ReleaseChart(claim *corev1.ConfigMap) (*chart.Chart, error) {
+	profile, ok := s.profiles[claim.Labels[controller.ProfileLabelKey]]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", claim.Labels[controller.ProfileLabelKey])
+	}
+	renderer, ok := profile.Renderer.(*template.HelmRenderer)
+	if !ok {
+		return nil, fmt.Errorf("profile %q uses the helm-release backend without the helm renderer", profile.Name)
+	}
+	return renderer.ReleaseChart(claim.Labels[controller.ClaimLabelKeyId])
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+type claimHistoryResponse struct {
+	Items    []controller.Tombstone `json:"items"`
+	Continue string                 `json:"continue,omitempty"`
+}
+
+func (s *Server) handleClaimHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	tenant, err := s.resolveTenant(r)
+	if err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultHistoryLimit
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit))
+			return
+		}
+	}
+	token, err := decodeHistoryContinue(query.Get("continue"))
+	if err != nil || token.Namespace >= len(s.namespaces) {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "invalid continue token")
+		return
+	}
+
+	// Tombstones are outside the cache label selector, so they are read
+	// uncached, one API server page at a time.
+	var reader client.Reader = s.client
+	if s.apiReader != nil {
+		reader = s.apiReader
+	}
+	tombstones := &corev1.ConfigMapList{}
+	next := ""
+	for remaining := limit; ; {
+		page := &corev1.ConfigMapList{}
+		if err := reader.List(r.Context(), page, client.InNamespace(s.namespaces[token.Namespace]), client.MatchingLabels{controller.TombstoneLabelKey: "true"}, client.Limit(remaining), client.Continue(token.Continue)); err != nil {
+			if apierrors.IsResourceExpired(err) {
+				writeProblem(w, r, http.StatusGone, problemInvalidRequest, "continue token expired, restart the listing")
+				return
+			}
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to list claim history")
+			return
+		}
+		tombstones.Items = append(tombstones.Items, page.Items...)
+		remaining -= len(page.Items)
+
+		if page.Continue != "" {
+			token.Continue = page.Continue
+		} else if token.Namespace+1 < len(s.namespaces) {
+			token = historyContinue{Namespace: token.Namespace + 1}
+		} else {
+			break
+		}
+		if remaining <= 0 {
+			next = token.encode()
+			break
+		}
+	}
+
+	filters := map[string]func(controller.Tombstone) string{
+		"id":      func(t controller.Tombstone) string { return t.ID },
+		"profile": func(t controller.Tombstone) string { return t.Profile },
+		"owner":   func(t controller.Tombstone) string { return t.Owner },
+		"reason":  func(t controller.Tombstone) string { return t.Reason },
+	}
+	items := make([]controller.Tombstone, 0, len(tombstones.Items))
+	for i := range tombstones.Items {
+		tombstone, ok := controller.TombstoneFrom(&tombstones.Items[i])
+		if !ok || (tenant != nil && tombstone.Tenant != tenant.Name) {
+			continue
+		}
+		matched := true
+		for param, field := range filters {
+			if want := strings.TrimSpace(query.Get(param)); want != "" && field(tombstone) != want {
+				matched = false
+			}
+		}
+		if matched {
+			items = append(items, tombstone)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].EndedAt != items[j].EndedAt {
+			return items[i].EndedAt > items[j].EndedAt
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	writeJSON(w, http.StatusOK, claimHistoryResponse{Items: items, Continue: next})
+}
+
+// historyContinue resumes a history listing: the index of the watched
+// namespace being read and the API server continue token within it.
+type historyContinue struct {
+	Namespace int    `json:"n,omitempty"`
+	Continue  string `json:"c,omitempty"`
+}
+
+func (t historyContinue) encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeHistoryContinue(raw string) (historyContinue, error) {
+	var token historyContinue
+	if raw = strings.TrimSpace(raw); raw == "" {
+		return token, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return token, err
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return token, err
+	}
+	if token.Namespace < 0 {
+		return token, fmt.Errorf("invalid namespace index %d", token.Namespace)
+	}
+	return token, nil
+}
+
+func (s *Server) recordTombstone(ctx context.Context, claim *corev1.ConfigMap, reason string) {
+	endedBy := ""
+	if identity, ok := IdentityFromContext(ctx); ok {
+		endedBy = identity.Subject
+	}
+	if err := controller.RecordTombstone(ctx, s.client, claim, reason, endedBy, s.historyRetention); err != nil {
+		s.requestLogger(ctx).Error(err, "failed to record claim tombstone", "claim", claim.Name)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func testTombstone(namespace, id, reason string) *corev1.ConfigMap {
+	data, _ := json.Marshal(controller.Tombstone{ID: id, Reason: reason, EndedAt: "2026-01-01T00:00:00Z"})
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claim-" + id + "-tombstone",
+			Namespace: namespace,
+			Labels:    map[string]string{controller.TombstoneLabelKey: "true"},
+		},
+		Data: map[string]string{controller.TombstoneDataKey: string(data)},
+	}
+}
+
+// pagingReader applies Limit and Continue like the API server, which the fake
+// client ignores.
+type pagingReader struct {
+	client.Reader
+	lists int
+}
+
+func (r *pagingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.lists++
+	if err := r.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	configMaps := list.(*corev1.ConfigMapList)
+	offset, _ := strconv.Atoi(listOpts.Continue)
+	configMaps.Items = configMaps.Items[min(offset, len(configMaps.Items)):]
+	if listOpts.Limit > 0 && int64(len(configMaps.Items)) > listOpts.Limit {
+		configMaps.Items = configMaps.Items[:listOpts.Limit]
+		configMaps.Continue = strconv.Itoa(offset + int(listOpts.Limit))
+	}
+	return nil
+}
+
+func TestHandleClaimHistoryPages(t *testing.T) {
+	var objects []client.Object
+	for i := range 5 {
+		objects = append(objects, testTombstone(testNamespace, fmt.Sprintf("a%d", i), controller.EndReasonReleased))
+	}
+	for i := range 2 {
+		objects = append(objects, testTombstone("other", fmt.Sprintf("b%d", i), controller.EndReasonExpired))
+	}
+	s := newTestServer(t, Config{WatchNamespaces: []string{"other"}}, objects...)
+	reader := &pagingReader{Reader: s.apiReader}
+	s.apiReader = reader
+
+	tests := []struct {
+		name      string
+		query     url.Values
+		want      int
+		wantLists int
+	}{
+		{name: "all pages", query: url.Values{"limit": {"3"}}, want: 7, wantLists: 4},
+		{name: "filtered pages", query: url.Values{"limit": {"2"}, "reason": {controller.EndReasonExpired}}, want: 2, wantLists: 5},
+		{name: "single page", query: url.Values{}, want: 7, wantLists: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader.lists = 0
+			seen := map[string]bool{}
+			for pages := 0; ; pages++ {
+				if pages > 10 {
+					t.Fatal("history does not terminate")
+				}
+				rec := httptest.NewRecorder()
+				s.handleClaimHistory(rec, httptest.NewRequest(http.MethodGet, "/claims/history?"+tt.query.Encode(), nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+				}
+				var page claimHistoryResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+					t.Fatal(err)
+				}
+				for _, item := range page.Items {
+					if seen[item.ID] {
+						t.Errorf("tombstone %s listed twice", item.ID)
+					}
+					seen[item.ID] = true
+				}
+				if page.Continue == "" {
+					break
+				}
+				tt.query.Set("continue", page.Continue)
+			}
+			if len(seen) != tt.want {
+				t.Errorf("listed %d tombstones, want %d", len(seen), tt.want)
+			}
+			if reader.lists != tt.wantLists {
+				t.Errorf("made %d list calls, want %d", reader.lists, tt.wantLists)
+			}
+		})
+	}
+}
+
+func TestHandleClaimHistoryRejectsBadTokens(t *testing.T) {
+	s := newTestServer(t, Config{})
+	for _, query := range []string{"limit=0", "limit=1001", "continue=%25%25", "continue=" + historyContinue{Namespace: 3}.encode()} {
+		rec := httptest.NewRecorder()
+		s.handleClaimHistory(rec, httptest.NewRequest(http.MethodGet, "/claims/history?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
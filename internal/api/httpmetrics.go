@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// instrumented records request metrics per mux pattern rather than per path,
+// so claim ids do not end up in label values.
+func (s *Server) instrumented(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := s.routeLabel(r)
+		method := methodLabel(r.Method)
+		inFlight := httpRequestsInFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		httpRequestDurationSeconds.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+		httpRequestsTotal.WithLabelValues(route, method, strconv.Itoa(recorder.status)).Inc()
+	})
+}
+
+func (s *Server) routeLabel(r *http.Request) string {
+	if _, pattern := s.mux.Handler(r); pattern != "" {
+		return pattern
+	}
+	return "unmatched"
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	defaultIdempotencyKeyTTL  = 24 * time.Hour
+	idempotencyKeyLabelLength = 63
+	idempotentClaimIDLength   = 16
+)
+
+var errIdempotencyKeyInUse = errors.New("idempotency key is held by a claim being deleted")
+
+func idempotencyKeyFromRequest(r *http.Request, req claimRequest) string {
+	key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+	if key == "" {
+		key = strings.TrimSpace(req.IdempotencyKey)
+	}
+	if key == "" {
+		return ""
+	}
+
+	subject := ""
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		subject = identity.Subject
+	}
+	sum := sha256.Sum256([]byte(subject + "\x00" + key))
+	return hex.EncodeToString(sum[:])[:idempotencyKeyLabelLength]
+}
+
+// idempotentClaimID derives the claim id from the hashed key, so concurrent
+// requests with the same key race on one claim name and only one is created.
+func idempotentClaimID(idempotencyKey string) string {
+	return idempotencyKey[:idempotentClaimIDLength]
+}
+
+// idempotentClaim returns the claim created for the key by a concurrent
+// request. It is read uncached since it may not have reached the cache yet.
+func (s *Server) idempotentClaim(ctx context.Context, namespace, idempotencyKey string) (*corev1.ConfigMap, error) {
+	var reader client.Reader = s.client
+	if s.apiReader != nil {
+		reader = s.apiReader
+	}
+	claim := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: namespace, Name: "claim-" + idempotentClaimID(idempotencyKey)}
+	if err := reader.Get(ctx, key, claim); err != nil {
+		return nil, err
+	}
+	if claim.Labels[controller.IdempotencyKeyLabelKey] != idempotencyKey || claim.DeletionTimestamp != nil {
+		return nil, errIdempotencyKeyInUse
+	}
+	return claim, nil
+}
+
+func (s *Server) findIdempotentClaim(ctx context.Context, idempotencyKey string) (*corev1.ConfigMap, error) {
+	claims := &corev1.ConfigMapList{}
+	if err := s.listClaims(ctx, claims, client.MatchingLabels{
+		controller.ManagedByLabelKey:      controller.ManagedByLabelValue,
+		controller.IdempotencyKeyLabelKey: idempotencyKey,
+	}); err != nil {
+		return nil, err
+	}
+
+	ttl := s.idempotencyKeyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+
+	var newest *corev1.ConfigMap
+	var newestClaimedAt time.Time
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if claim.DeletionTimestamp != nil {
+			continue
+		}
+		claimedAt, ok := claimClaimedAtTime(*claim)
+		if !ok {
+			claimedAt = claim.CreationTimestamp.Time
+		}
+		if time.Since(claimedAt) > ttl {
+			continue
+		}
+		if newest == nil || claimedAt.After(newestClaimedAt) {
+			newest, newestClaimedAt = claim, claimedAt
+		}
+	}
+	return newest, nil
+}
+
+func (s *Server) replayIdempotentClaim(w http.ResponseWriter, r *http.Request, claim *corev1.ConfigMap, readyTimeout time.Duration) {
+	claimID := strings.TrimSpace(claim.Labels[controller.ClaimLabelKeyId])
+	expiresAt, _ := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey])
+
+	extendWriteDeadline(w, readyTimeout)
+	readyClaim, drained, err := s.waitForClaimReadyOrDrain(r.Context(), claim, readyTimeout)
+	if drained {
+		writeClaimTicket(w, r, claimID, expiresAt, claim.Labels[controller.ProfileLabelKey])
+		return
+	}
+	if errors.Is(err, errClaimFailed) {
+		writeProblem(w, r, http.StatusBadGateway, problemClaimFailed, err.Error())
+		return
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(s.readyRetryAfter())))
+			writeProblem(w, r, http.StatusGatewayTimeout, problemTimeout, "timed out waiting for claim resources to become ready")
+			return
+		}
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed while waiting for claim readiness")
+		return
+	}
+
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	s.writeClaimResponse(w, r, http.StatusOK, readyClaim, claimID, expiresAt, false, claim.Labels[controller.ProfileLabelKey])
+}
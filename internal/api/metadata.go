@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+type ClaimMetadata struct {
+	Purpose     string            `json:"purpose,omitempty"`
+	Repo        string            `json:"repo,omitempty"`
+	PipelineURL string            `json:"pipelineUrl,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+func (m *ClaimMetadata) validate() error {
+	if m == nil {
+		return nil
+	}
+	if len(m.Purpose) > 1024 {
+		return fmt.Errorf("metadata.purpose must be at most 1024 characters")
+	}
+	if m.PipelineURL != "" {
+		if parsed, err := url.Parse(m.PipelineURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("metadata.pipelineUrl must be an absolute URL")
+		}
+	}
+	for key, value := range m.Labels {
+		if errs := validation.IsQualifiedName(controller.MetadataLabelPrefix + key); len(errs) > 0 || strings.Contains(key, "/") {
+			return fmt.Errorf("invalid metadata label key %q", key)
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid metadata label value for %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func (m *ClaimMetadata) apply(claim *corev1.ConfigMap) {
+	if m == nil {
+		return
+	}
+	if claim.Annotations == nil {
+		claim.Annotations = map[string]string{}
+	}
+	if claim.Labels == nil {
+		claim.Labels = map[string]string{}
+	}
+	if m.Purpose != "" {
+		claim.Annotations[controller.PurposeAnnotationKey] = m.Purpose
+	}
+	if m.Repo != "" {
+		claim.Annotations[controller.RepoAnnotationKey] = m.Repo
+	}
+	if m.PipelineURL != "" {
+		claim.Annotations[controller.PipelineURLAnnotationKey] = m.PipelineURL
+	}
+	for key, value := range m.Labels {
+		claim.Labels[controller.MetadataLabelPrefix+key] = value
+	}
+}
+
+func claimMetadataFrom(claim *corev1.ConfigMap) *ClaimMetadata {
+	metadata := &ClaimMetadata{
+		Purpose:     claim.Annotations[controller.PurposeAnnotationKey],
+		Repo:        claim.Annotations[controller.RepoAnnotationKey],
+		PipelineURL: claim.Annotations[controller.PipelineURLAnnotationKey],
+	}
+	for key, value := range claim.Labels {
+		if name, ok := strings.CutPrefix(key, controller.MetadataLabelPrefix); ok {
+			if metadata.Labels == nil {
+				metadata.Labels = map[string]string{}
+			}
+			metadata.Labels[name] = value
+		}
+	}
+	if metadata.Purpose == "" && metadata.Repo == "" && metadata.PipelineURL == "" && len(metadata.Labels) == 0 {
+		return nil
+	}
+	return metadata
+}
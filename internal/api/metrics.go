@@ -11,60 +11,133 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
-var claimsCreatedTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+var claimsCreatedTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
 	Name: "claim_controller_claims_created_total",
 	Help: "Total number of claims successfully created.",
-})
+}, []string{"profile"})
 
-var claimsCreatedOnDemandTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+var claimsCreatedOnDemandTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
 	Name: "claim_controller_claims_created_ondemand_total",
 	Help: "Total number of claims created on demand (not pre-provisioned).",
-})
+}, []string{"profile"})
 
-var claimsReusedPreProvisionedTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+var claimsReusedPreProvisionedTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
 	Name: "claim_controller_claims_reused_preprovisioned_total",
 	Help: "Total number of pre-provisioned claims reused by claim requests.",
-})
+}, []string{"profile"})
 
 var claimsPreProvisionedCreatedTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
 	Name: "claim_controller_claims_preprovisioned_created_total",
 	Help: "Total number of claims created in advance for the pre-provisioned pool.",
 })
 
-var claimReadyDurationSeconds = promauto.With(metrics.Registry).NewHistogram(prometheus.HistogramOpts{
+var claimsPreProvisionedRecycledTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "claim_controller_claims_preprovisioned_recycled_total",
+	Help: "Total number of pre-provisioned claims recycled after exceeding the pool max idle age.",
+})
+
+var poolReplenishDurationSeconds = promauto.With(metrics.Registry).NewHistogram(prometheus.HistogramOpts{
+	Name:    "claim_controller_pool_replenish_duration_seconds",
+	Help:    "Duration of pre-provisioned pool replenishment passes.",
+	Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+})
+
+var poolReplenishFailuresTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "claim_controller_pool_replenish_failures_total",
+	Help: "Total number of pre-provisioned pool replenishment passes that failed.",
+})
+
+var poolAvailable = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+	Name: "claim_controller_pool_available",
+	Help: "Number of pre-provisioned claims currently waiting in the pool.",
+})
+
+var poolTargetGauge = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+	Name: "claim_controller_pool_target",
+	Help: "Target size of the pre-provisioned pool.",
+})
+
+var poolRequestsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_pool_requests_total",
+	Help: "Total number of pool-eligible claim requests, by result (hit when served from the pool, miss otherwise).",
+}, []string{"result"})
+
+var claimQueueDepth = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+	Name: "claim_controller_claim_queue_depth",
+	Help: "Number of claim requests currently waiting in the queue.",
+})
+
+var claimQueueWaitSeconds = promauto.With(metrics.Registry).NewHistogram(prometheus.HistogramOpts{
+	Name:    "claim_controller_claim_queue_wait_seconds",
+	Help:    "Time claim requests spent waiting in the queue.",
+	Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+})
+
+var claimReadyDurationSeconds = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
 	Name:    "claim_controller_claim_ready_duration_seconds",
 	Help:    "Time in seconds from claim creation to healthy state.",
 	Buckets: prometheus.ExponentialBuckets(1, 2, 8),
-})
+}, []string{"profile"})
 
 var claimsReleasedTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
 	Name: "claim_controller_claims_released_total",
 	Help: "Total number of claims successfully released.",
 })
 
+var claimsRejectedTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_claims_rejected_total",
+	Help: "Total number of claim requests rejected before creation, by reason.",
+}, []string{"reason"})
+
+var claimsPreemptedTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "claim_controller_claims_preempted_total",
+	Help: "Total number of expired claims released early to make room for high-priority claims.",
+})
+
 var timedOutClaimsTotal = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
 	Name: "claim_controller_timedout_claims_total",
 	Help: "Total number of claims that timed out waiting for readiness.",
 })
 
-var claimLifetimeExpectedRatio = promauto.With(metrics.Registry).NewHistogram(prometheus.HistogramOpts{
+var apiPanicsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_api_panics_total",
+	Help: "Total number of panics recovered in API handlers.",
+}, []string{"route"})
+
+var httpRequestsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_http_requests_total",
+	Help: "Total number of API requests, by route, method and status code.",
+}, []string{"route", "method", "code"})
+
+var httpRequestDurationSeconds = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "claim_controller_http_request_duration_seconds",
+	Help:    "Duration of API requests, by route and method.",
+	Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+}, []string{"route", "method"})
+
+var httpRequestsInFlight = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "claim_controller_http_requests_in_flight",
+	Help: "Number of API requests currently being served, by route.",
+}, []string{"route"})
+
+var claimLifetimeExpectedRatio = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
 	Name:    "claim_controller_claim_lifetime_expected_ratio",
 	Help:    "Ratio between actual claim lifetime and expected lifetime at deletion.",
 	Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.1, 2, 3},
-})
+}, []string{"profile"})
 
-var claimUsageExpectedRatio = promauto.With(metrics.Registry).NewHistogram(prometheus.HistogramOpts{
+var claimUsageExpectedRatio = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
 	Name:    "claim_controller_claim_usage_expected_ratio",
 	Help:    "Ratio between actual claim usage duration and expected usage duration at deletion.",
 	Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.1, 2, 3},
-})
+}, []string{"profile"})
 
-func newClaimLifetimeDurationHistogram(defaultTTL time.Duration) prometheus.Observer {
-	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+func newClaimLifetimeDurationHistogram(defaultTTL time.Duration) prometheus.ObserverVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "claim_controller_claim_lifetime_duration_seconds",
 		Help:    "Claim lifetime in seconds from creation to release.",
 		Buckets: claimLifetimeDurationBuckets(defaultTTL),
-	})
+	}, []string{"profile"})
 
 	err := metrics.Registry.Register(histogram)
 	if err == nil {
@@ -73,7 +146,7 @@ func newClaimLifetimeDurationHistogram(defaultTTL time.Duration) prometheus.Obse
 
 	var alreadyRegistered prometheus.AlreadyRegisteredError
 	if errors.As(err, &alreadyRegistered) {
-		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.Observer)
+		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.ObserverVec)
 		if ok {
 			return existingHistogram
 		}
@@ -109,12 +182,12 @@ func claimLifetimeDurationBuckets(defaultTTL time.Duration) []float64 {
 	return uniqueBuckets
 }
 
-func newClaimTotalDurationHistogram(maxTTL time.Duration) prometheus.Observer {
-	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+func newClaimTotalDurationHistogram(maxTTL time.Duration) prometheus.ObserverVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "claim_controller_claim_total_duration_seconds",
 		Help:    "Configured total claim duration in seconds from creation to expiration.",
 		Buckets: claimTotalDurationBuckets(maxTTL),
-	})
+	}, []string{"profile"})
 
 	err := metrics.Registry.Register(histogram)
 	if err == nil {
@@ -123,7 +196,7 @@ func newClaimTotalDurationHistogram(maxTTL time.Duration) prometheus.Observer {
 
 	var alreadyRegistered prometheus.AlreadyRegisteredError
 	if errors.As(err, &alreadyRegistered) {
-		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.Observer)
+		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.ObserverVec)
 		if ok {
 			return existingHistogram
 		}
@@ -152,12 +225,12 @@ func claimTotalDurationBuckets(maxTTL time.Duration) []float64 {
 	return buckets
 }
 
-func newClaimIdleDurationHistogram(maxTTL time.Duration) prometheus.Observer {
-	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+func newClaimIdleDurationHistogram(maxTTL time.Duration) prometheus.ObserverVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "claim_controller_claim_idle_duration_seconds",
 		Help:    "Claim idle duration in seconds between resource creation and effective claim usage.",
 		Buckets: claimTotalDurationBuckets(maxTTL),
-	})
+	}, []string{"profile"})
 
 	err := metrics.Registry.Register(histogram)
 	if err == nil {
@@ -166,7 +239,7 @@ func newClaimIdleDurationHistogram(maxTTL time.Duration) prometheus.Observer {
 
 	var alreadyRegistered prometheus.AlreadyRegisteredError
 	if errors.As(err, &alreadyRegistered) {
-		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.Observer)
+		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.ObserverVec)
 		if ok {
 			return existingHistogram
 		}
@@ -175,12 +248,12 @@ func newClaimIdleDurationHistogram(maxTTL time.Duration) prometheus.Observer {
 	panic(fmt.Errorf("register claim idle duration histogram: %w", err))
 }
 
-func newClaimUsageDurationHistogram(maxTTL time.Duration) prometheus.Observer {
-	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+func newClaimUsageDurationHistogram(maxTTL time.Duration) prometheus.ObserverVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "claim_controller_claim_usage_duration_seconds",
 		Help:    "Claim actual usage duration in seconds between effective claim usage and release.",
 		Buckets: claimTotalDurationBuckets(maxTTL),
-	})
+	}, []string{"profile"})
 
 	err := metrics.Registry.Register(histogram)
 	if err == nil {
@@ -189,7 +262,7 @@ func newClaimUsageDurationHistogram(maxTTL time.Duration) prometheus.Observer {
 
 	var alreadyRegistered prometheus.AlreadyRegisteredError
 	if errors.As(err, &alreadyRegistered) {
-		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.Observer)
+		existingHistogram, ok := alreadyRegistered.ExistingCollector.(prometheus.ObserverVec)
 		if ok {
 			return existingHistogram
 		}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/cluster"
+)
+
+func (s *Server) resolveClaimNamespace(requested string, profile *Profile) (string, error) {
+	namespace := strings.TrimSpace(requested)
+	if namespace == "" && profile != nil {
+		namespace = profile.Namespace
+	}
+	if namespace == "" {
+		return s.namespace, nil
+	}
+	if !slices.Contains(s.namespaces, namespace) {
+		return "", fmt.Errorf("namespace %q is not watched, available namespaces: %s", namespace, strings.Join(s.namespaces, ", "))
+	}
+	return namespace, nil
+}
+
+func (s *Server) listClaims(ctx context.Context, list *corev1.ConfigMapList, opts ...client.ListOption) error {
+	items := []corev1.ConfigMap{}
+	for _, namespace := range s.namespaces {
+		page := &corev1.ConfigMapList{}
+		if err := s.client.List(ctx, page, append([]client.ListOption{client.InNamespace(namespace)}, opts...)...); err != nil {
+			return err
+		}
+		items = append(items, page.Items...)
+	}
+	list.Items = items
+	return nil
+}
+
+func (s *Server) resolveClaimCluster(ctx context.Context, requested string, profile *Profile) (string, error) {
+	name := strings.TrimSpace(requested)
+	if name == "" && profile != nil {
+		name = profile.Cluster
+	}
+	if name == "" {
+		return "", nil
+	}
+	if s.clusters == nil {
+		return "", fmt.Errorf("%w %q: cross-cluster provisioning is not enabled", cluster.ErrUnknownCluster, name)
+	}
+	if err := s.clusters.Has(ctx, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+type OIDCAuthenticator struct {
+	issuer        string
+	audience      string
+	usernameClaim string
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCAuthenticator(issuer, audience, usernameClaim string) *OIDCAuthenticator {
+	if usernameClaim == "" {
+		usernameClaim = "sub"
+	}
+	return &OIDCAuthenticator{
+		issuer:        issuer,
+		audience:      audience,
+		usernameClaim: usernameClaim,
+	}
+}
+
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context, r *http.Request) (*Identity, error) {
+	token := credentialFromRequest(r)
+	if token == "" {
+		return nil, errMissingCredentials
+	}
+
+	verifier, err := a.idTokenVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: decode token claims: %v", errInvalidCredentials, err)
+	}
+
+	subject, _ := claims[a.usernameClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no %q claim", errInvalidCredentials, a.usernameClaim)
+	}
+
+	var groups []string
+	if rawGroups, ok := claims["groups"].([]any); ok {
+		for _, group := range rawGroups {
+			if name, ok := group.(string); ok {
+				groups = append(groups, name)
+			}
+		}
+	}
+	return newIdentity(oidcSubjectPrefix, subject, groups), nil
+}
+
+func (a *OIDCAuthenticator) idTokenVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.verifier != nil {
+		return a.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), a.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc issuer %s: %w", a.issuer, err)
+	}
+
+	a.verifier = provider.Verifier(&oidc.Config{
+		ClientID:          a.audience,
+		SkipClientIDCheck: a.audience == "",
+	})
+	return a.verifier, nil
+}
@@ -0,0 +1,379 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type claimResponse struct {
+	Status            string            `json:"status"`
+	ID                string            `json:"id"`
+	ExpiresAt         string            `json:"expiresAt"`
+	Profile           string            `json:"profile"`
+	PreProvisioned    bool              `json:"preProvisioned"`
+	Data              map[string]string `json:"data"`
+	Namespace         string            `json:"namespace,omitempty"`
+	Cluster           string            `json:"cluster,omitempty"`
+	ClonedFrom        string            `json:"clonedFrom,omitempty"`
+	Metadata          *ClaimMetadata    `json:"metadata,omitempty"`
+	Kubeconfig        string            `json:"kubeconfig,omitempty"`
+	Credentials       map[string]string `json:"credentials,omitempty"`
+	CredentialsSecret string            `json:"credentialsSecret,omitempty"`
+	ReleasePath       string            `json:"releasePath"`
+	ReleaseMethod     string            `json:"releaseMethod"`
+	RenewPath         string            `json:"renewPath"`
+	RenewMethod       string            `json:"renewMethod"`
+}
+
+type scheduledClaimResponse struct {
+	Status        string `json:"status"`
+	ID            string `json:"id"`
+	StartAt       string `json:"startAt,omitempty"`
+	ExpiresAt     string `json:"expiresAt"`
+	Profile       string `json:"profile"`
+	StatusPath    string `json:"statusPath"`
+	ReleasePath   string `json:"releasePath"`
+	ReleaseMethod string `json:"releaseMethod"`
+}
+
+type renewResponse struct {
+	Status      string `json:"status"`
+	ID          string `json:"id"`
+	ExpiresAt   string `json:"expiresAt"`
+	RenewPath   string `json:"renewPath"`
+	RenewMethod string `json:"renewMethod"`
+}
+
+type releaseResponse struct {
+	Status        string `json:"status"`
+	Message       string `json:"message,omitempty"`
+	RetainedUntil string `json:"retainedUntil,omitempty"`
+}
+
+type claimListResponse struct {
+	Items []claimStatus `json:"items"`
+}
+
+type poolResponse struct {
+	Size      int `json:"size"`
+	Available int `json:"available"`
+}
+
+type rerenderResponse struct {
+	Claims        []staleClaim `json:"claims"`
+	RatePerMinute int          `json:"ratePerMinute,omitempty"`
+}
+
+type validateResponse struct {
+	Valid    bool                `json:"valid"`
+	Profiles []profileValidation `json:"profiles"`
+}
+
+type openAPIParam struct {
+	name        string
+	in          string
+	description string
+}
+
+type openAPIOperation struct {
+	method      string
+	path        string
+	route       string
+	summary     string
+	params      []openAPIParam
+	request     any
+	responses   map[int]any
+	description map[int]string
+}
+
+var idParam = openAPIParam{name: "id", in: "path", description: "claim id"}
+
+var openAPIOperations = []openAPIOperation{
+	{
+		method: http.MethodPost, path: "/claim", route: "claim", summary: "Create a claim",
+		params:  []openAPIParam{{name: IdempotencyKeyHeader, in: "header", description: "replays return the existing claim"}, {name: TenantHeader, in: "header", description: "tenant the claim is created for"}},
+		request: claimRequest{},
+		responses: map[int]any{
+			http.StatusCreated: claimResponse{}, http.StatusAccepted: scheduledClaimResponse{}, http.StatusBadRequest: nil, http.StatusForbidden: nil,
+			http.StatusUnprocessableEntity: Problem{}, http.StatusTooManyRequests: nil, http.StatusServiceUnavailable: nil, http.StatusGatewayTimeout: nil,
+		},
+	},
+	{
+		method: http.MethodPatch, path: "/claim/{id}", route: "claim", summary: "Update the values of a claim",
+		params: []openAPIParam{idParam}, request: claimRequest{},
+		responses: map[int]any{http.StatusOK: claimStatus{}, http.StatusBadRequest: nil, http.StatusNotFound: nil, http.StatusConflict: nil, http.StatusUnprocessableEntity: Problem{}},
+	},
+	{
+		method: http.MethodPost, path: "/claim/{id}/clone", route: "claim", summary: "Create a claim from the template version and values of another one",
+		params:    []openAPIParam{idParam, {name: "allowDrift", in: "query", description: "clone with the current template version when it changed"}},
+		request:   cloneRequest{},
+		responses: map[int]any{http.StatusCreated: claimResponse{}, http.StatusBadRequest: nil, http.StatusForbidden: nil, http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	{
+		method: http.MethodPost, path: "/claim/{id}/suspend", route: "renew", summary: "Pause the expiry of a claim",
+		params:    []openAPIParam{idParam},
+		responses: map[int]any{http.StatusOK: claimStatus{}, http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	{
+		method: http.MethodPost, path: "/claim/{id}/resume", route: "renew", summary: "Resume the expiry of a suspended claim",
+		params:    []openAPIParam{idParam},
+		responses: map[int]any{http.StatusOK: claimStatus{}, http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	{
+		method: http.MethodPost, path: "/release/{id}", route: "release", summary: "Release a claim",
+		params:      []openAPIParam{idParam, {name: "wait", in: "query", description: "wait until the claim resources are deleted"}, {name: "retain", in: "query", description: "keep the resources for the release retention window"}},
+		responses:   map[int]any{http.StatusNoContent: nil, http.StatusAccepted: releaseResponse{}, http.StatusNotFound: nil, http.StatusForbidden: nil},
+		description: map[int]string{http.StatusNoContent: "claim released"},
+	},
+	{
+		method: http.MethodPost, path: "/renew/{id}", route: "renew", summary: "Extend the lifetime of a claim",
+		params: []openAPIParam{idParam}, request: claimRequest{},
+		responses: map[int]any{http.StatusOK: renewResponse{}, http.StatusBadRequest: nil, http.StatusNotFound: nil, http.StatusConflict: nil},
+	},
+	{
+		method: http.MethodGet, path: "/claims", route: "claims", summary: "List claims",
+		params:    []openAPIParam{{name: "all", in: "query", description: "include pre-provisioned claims"}},
+		responses: map[int]any{http.StatusOK: claimListResponse{}},
+	},
+	{
+		method: http.MethodGet, path: "/claims/history", route: "claims", summary: "List tombstones of released and expired claims",
+		params: []openAPIParam{
+			{name: "id", in: "query", description: "only the claim with this id"}, {name: "profile", in: "query", description: "only claims of this profile"},
+			{name: "owner", in: "query", description: "only claims of this owner"}, {name: "reason", in: "query", description: "released, expired, outside_working_hours, preempted or failed"},
+			{name: "limit", in: "query", description: "page size (default 100, max 1000)"}, {name: "continue", in: "query", description: "token returned by the previous page"},
+		},
+		responses: map[int]any{http.StatusOK: claimHistoryResponse{}, http.StatusBadRequest: nil, http.StatusGone: nil},
+	},
+	{
+		method: http.MethodGet, path: "/claims/{id}", route: "claims", summary: "Get a claim and the readiness of its resources",
+		params:    []openAPIParam{idParam},
+		responses: map[int]any{http.StatusOK: claimStatus{}, http.StatusNotFound: nil},
+	},
+	{
+		method: http.MethodGet, path: "/admin/pool", route: "admin", summary: "Get the pre-provisioned pool size",
+		responses: map[int]any{http.StatusOK: poolResponse{}},
+	},
+	{
+		method: http.MethodPost, path: "/admin/pool", route: "admin", summary: "Resize the pre-provisioned pool",
+		request:   poolResizeRequest{},
+		responses: map[int]any{http.StatusOK: poolResponse{}, http.StatusBadRequest: nil, http.StatusConflict: nil},
+	},
+	{
+		method: http.MethodPost, path: "/admin/rerender", route: "admin", summary: "Re-render claims whose template or values changed",
+		request:   rerenderRequest{},
+		responses: map[int]any{http.StatusOK: rerenderResponse{}, http.StatusAccepted: rerenderResponse{}, http.StatusConflict: nil},
+	},
+	{
+		method: http.MethodPost, path: "/admin/validate", route: "admin", summary: "Render profiles with their current values",
+		request:   validateRequest{},
+		responses: map[int]any{http.StatusOK: validateResponse{}, http.StatusNotFound: nil, http.StatusUnprocessableEntity: validateResponse{}},
+	},
+}
+
+func (s *Server) openAPIHandler() http.HandlerFunc {
+	spec, err := json.Marshal(s.openAPISpec())
+	if err != nil {
+		panic(fmt.Errorf("encode openapi spec: %w", err))
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	}
+}
+
+func (s *Server) openAPISpec() map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+	for _, op := range openAPIOperations {
+		operation := map[string]any{
+			"summary":     op.summary,
+			"operationId": operationID(op.method, op.path),
+			"responses":   openAPIResponses(op, schemas),
+		}
+		if len(op.params) > 0 {
+			params := make([]any, 0, len(op.params))
+			for _, param := range op.params {
+				params = append(params, map[string]any{
+					"name":        param.name,
+					"in":          param.in,
+					"required":    param.in == "path",
+					"description": param.description,
+					"schema":      map[string]any{"type": "string"},
+				})
+			}
+			operation["parameters"] = params
+		}
+		if op.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": false,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemaRef(reflect.TypeOf(op.request), schemas)}},
+			}
+		}
+		if s.authRoutes[op.route] {
+			operation["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"apiKey": []string{}}}
+		}
+
+		path := apiV1.prefix() + op.path
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "claim-controller API",
+			"version": APIVersionV1,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"apiKey":     map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+func openAPIResponses(op openAPIOperation, schemas map[string]any) map[string]any {
+	codes := make([]int, 0, len(op.responses))
+	for code := range op.responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	responses := map[string]any{}
+	for _, code := range codes {
+		description := op.description[code]
+		if description == "" {
+			description = http.StatusText(code)
+		}
+		response := map[string]any{"description": description}
+		body := op.responses[code]
+		if body == nil && code >= 400 {
+			body = Problem{}
+		}
+		if body != nil {
+			contentType := "application/json"
+			if _, ok := body.(Problem); ok {
+				contentType = ProblemContentType
+			}
+			response["content"] = map[string]any{contentType: map[string]any{"schema": schemaRef(reflect.TypeOf(body), schemas)}}
+		}
+		responses[strconv.Itoa(code)] = response
+	}
+	return responses
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.Trim(segment, "{}")
+		if segment == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
+
+func schemaRef(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Name() == "" {
+		return schemaFor(t, schemas)
+	}
+
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, ok := schemas[name]; !ok {
+		schemas[name] = map[string]any{}
+		schemas[name] = structSchema(t, schemas)
+	}
+	return map[string]any{"$ref": fmt.Sprintf("#/components/schemas/%s", name)}
+}
+
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaRef(t.Elem(), schemas)
+	case reflect.Struct:
+		return schemaRef(t, schemas)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// swaggerUIPage loads an exact swagger-ui-dist release so the docs page never
+// picks up a new major or a republished tag.
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>claim-controller API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css" crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js" crossorigin="anonymous"></script>
+  <script>window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUIPage))
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+type ProvisioningMode string
+
+const (
+	ProvisioningModeHybrid   ProvisioningMode = "hybrid"
+	ProvisioningModePoolOnly ProvisioningMode = "pool-only"
+	ProvisioningModeOnDemand ProvisioningMode = "on-demand"
+)
+
+const defaultPoolReplenishInterval = 15 * time.Second
+
+var (
+	errPoolExhausted = errors.New("no pre-provisioned claim available")
+	errNotPoolable   = errors.New("pool-only mode cannot serve this claim from the pre-provisioned pool")
+)
+
+func ParseProvisioningMode(raw string) (ProvisioningMode, error) {
+	switch mode := ProvisioningMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return ProvisioningModeHybrid, nil
+	case ProvisioningModeHybrid, ProvisioningModePoolOnly, ProvisioningModeOnDemand:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown provisioning mode %q (expected %s, %s or %s)", raw, ProvisioningModeHybrid, ProvisioningModePoolOnly, ProvisioningModeOnDemand)
+	}
+}
+
+type poolResizeRequest struct {
+	Size *int `json:"size"`
+}
+
+func (s *Server) poolTarget() int {
+	return int(s.preProvisionCount.Load())
+}
+
+func (s *Server) setPoolTarget(size int) {
+	s.preProvisionCount.Store(int64(size))
+	poolTargetGauge.Set(float64(size))
+	s.triggerPoolReconcile()
+}
+
+func (s *Server) triggerPoolReconcile() {
+	select {
+	case s.poolTrigger <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) runPoolReplenisher(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.poolTrigger:
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		err := s.ensurePreProvisionedClaims(ctx)
+		poolReplenishDurationSeconds.Observe(time.Since(start).Seconds())
+		if err != nil && ctx.Err() == nil {
+			poolReplenishFailuresTotal.Inc()
+			s.logger.Error(err, "failed to replenish pre-provisioned claims")
+		}
+		s.claimQueue.signal()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.poolReplenishEvery)
+	}
+}
+
+func (s *Server) listPoolClaims(ctx context.Context) ([]corev1.ConfigMap, error) {
+	claimList := &corev1.ConfigMapList{}
+	if err := s.client.List(ctx, claimList, client.InNamespace(s.namespace), client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}); err != nil {
+		return nil, err
+	}
+
+	pool := make([]corev1.ConfigMap, 0, len(claimList.Items))
+	for _, claim := range claimList.Items {
+		if claim.DeletionTimestamp != nil {
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(claim.Annotations[controller.PreProvisionedAnnotationKey]), "true") {
+			continue
+		}
+		pool = append(pool, claim)
+	}
+	sort.Slice(pool, func(i, j int) bool {
+		return pool[i].CreationTimestamp.Before(&pool[j].CreationTimestamp)
+	})
+	poolAvailable.Set(float64(len(pool)))
+	return pool, nil
+}
+
+// scaleDownPool deletes up to excess pool claims and returns how many are gone.
+func (s *Server) scaleDownPool(ctx context.Context, pool []corev1.ConfigMap, excess int) (int, error) {
+	deleted := 0
+	for i := 0; i < excess && i < len(pool); i++ {
+		claim := pool[i]
+		err := s.client.Delete(ctx, &claim, client.Preconditions{ResourceVersion: &claim.ResourceVersion}, foregroundPropagation())
+		if client.IgnoreNotFound(err) != nil {
+			return deleted, fmt.Errorf("delete pre-provisioned claim %s: %w", claim.Name, err)
+		}
+		deleted++
+		s.logger.Info("released pre-provisioned claim during pool scale-down", "claim", claim.Name)
+	}
+	return deleted, nil
+}
+
+func (s *Server) recycleStalePoolClaims(ctx context.Context, pool []corev1.ConfigMap) ([]corev1.ConfigMap, error) {
+	if s.poolMaxIdleAge <= 0 {
+		return pool, nil
+	}
+
+	cutoff := time.Now().Add(-s.poolMaxIdleAge)
+	fresh := make([]corev1.ConfigMap, 0, len(pool))
+	for _, claim := range pool {
+		if !claim.CreationTimestamp.Time.Before(cutoff) {
+			fresh = append(fresh, claim)
+			continue
+		}
+		err := s.client.Delete(ctx, &claim, client.Preconditions{ResourceVersion: &claim.ResourceVersion}, foregroundPropagation())
+		if apierrors.IsConflict(err) {
+			continue
+		}
+		if client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("recycle pre-provisioned claim %s: %w", claim.Name, err)
+		}
+		claimsPreProvisionedRecycledTotal.Inc()
+		s.logger.Info("recycled stale pre-provisioned claim", "claim", claim.Name, "age", time.Since(claim.CreationTimestamp.Time).Round(time.Second).String())
+	}
+	return fresh, nil
+}
+
+func (s *Server) handleAdminPool(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if s.provisioningMode == ProvisioningModeOnDemand {
+			writeJSON(w, http.StatusOK, poolResponse{})
+			return
+		}
+	case http.MethodPost:
+		if s.provisioningMode == ProvisioningModeOnDemand {
+			writeProblem(w, r, http.StatusConflict, problemConflict, "pre-provisioning is disabled in on-demand provisioning mode")
+			return
+		}
+		var req poolResizeRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			writeDecodeProblem(w, r, err)
+			return
+		}
+		if req.Size == nil || *req.Size < 0 {
+			writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "size must be a non-negative integer")
+			return
+		}
+		previous := s.poolTarget()
+		s.setPoolTarget(*req.Size)
+		s.requestLogger(r.Context()).Info("pre-provisioned pool resized", "from", previous, "to", *req.Size)
+	default:
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	pool, err := s.listPoolClaims(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to list pre-provisioned claims")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poolResponse{Size: s.poolTarget(), Available: len(pool)})
+}
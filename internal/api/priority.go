@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+func parsePriority(raw string) (string, error) {
+	switch priority := strings.ToLower(strings.TrimSpace(raw)); priority {
+	case "":
+		return PriorityNormal, nil
+	case PriorityLow, PriorityNormal, PriorityHigh:
+		return priority, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q (expected low, normal or high)", raw)
+	}
+}
+
+func (s *Server) preemptExpiredClaim(ctx context.Context) (bool, error) {
+	claims := &corev1.ConfigMapList{}
+	if err := s.listClaims(ctx, claims, client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	var victim *corev1.ConfigMap
+	var victimExpiresAt time.Time
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if claim.DeletionTimestamp != nil || claim.Annotations[controller.PreProvisionedAnnotationKey] == "true" {
+			continue
+		}
+		if claim.Labels[controller.PriorityLabelKey] != PriorityLow {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey])
+		if err != nil || expiresAt.After(now) {
+			continue
+		}
+		if victim == nil || expiresAt.Before(victimExpiresAt) {
+			victim, victimExpiresAt = claim, expiresAt
+		}
+	}
+	if victim == nil {
+		return false, nil
+	}
+
+	if err := s.client.Delete(ctx, victim, foregroundPropagation()); client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+	s.recordTombstone(ctx, victim, controller.EndReasonPreempted)
+	claimsPreemptedTotal.Inc()
+	s.requestLogger(ctx).Info("preempted expired claim", "claim", victim.Name, "expiredAt", victimExpiresAt.Format(time.RFC3339))
+	return true, nil
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "", want: PriorityNormal},
+		{raw: " High ", want: PriorityHigh},
+		{raw: "low", want: PriorityLow},
+		{raw: "urgent", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePriority(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parsePriority(%q) = %q, %v; want %q, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func priorityClaim(id, priority string, expiresAt time.Time) *corev1.ConfigMap {
+	claim := testClaim(id, map[string]string{controller.ExpiresAtAnnotationKey: expiresAt.Format(time.RFC3339)})
+	claim.Labels[controller.PriorityLabelKey] = priority
+	return claim
+}
+
+func TestPreemptExpiredClaim(t *testing.T) {
+	expired := time.Now().UTC().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		claims      []client.Object
+		wantVictim  string
+		wantPreempt bool
+	}{
+		{
+			name: "only low claims are preempted",
+			claims: []client.Object{
+				priorityClaim("normal", PriorityNormal, expired.Add(-time.Hour)),
+				priorityClaim("high", PriorityHigh, expired.Add(-time.Hour)),
+				priorityClaim("low", PriorityLow, expired),
+			},
+			wantVictim:  "claim-low",
+			wantPreempt: true,
+		},
+		{
+			name: "expired normal claims are kept",
+			claims: []client.Object{
+				priorityClaim("normal", PriorityNormal, expired),
+			},
+		},
+		{
+			name: "unexpired low claims are kept",
+			claims: []client.Object{
+				priorityClaim("low", PriorityLow, time.Now().Add(time.Hour)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{}, tt.claims...)
+
+			preempted, err := s.preemptExpiredClaim(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if preempted != tt.wantPreempt {
+				t.Fatalf("preempted = %v, want %v", preempted, tt.wantPreempt)
+			}
+			for _, object := range tt.claims {
+				err := s.client.Get(context.Background(), client.ObjectKeyFromObject(object), &corev1.ConfigMap{})
+				if deleted := apierrors.IsNotFound(err); deleted != (object.GetName() == tt.wantVictim) {
+					t.Errorf("claim %s deleted = %v", object.GetName(), deleted)
+				}
+			}
+		})
+	}
+}
+
+func TestHighPriorityRequiresAdmin(t *testing.T) {
+	s := newTestServer(t, Config{AdminSubjects: []string{"admin"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/claim", strings.NewReader(`{"priority":"high"}`))
+	req = withIdentity(req, "someone")
+	rec := httptest.NewRecorder()
+	s.handleClaim(rec, req)
+
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "high priority") {
+		t.Errorf("status = %d, body = %s; want 403 for high priority", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/nonot/claim-controller/internal/template"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	problemTypeBase    = "https://claim-controller.io/problems/"
+)
+
+const (
+	problemInvalidRequest        = "invalid_request"
+	problemRequestTooLarge       = "request_too_large"
+	problemTTLInvalid            = "ttl_invalid"
+	problemMaxTTLReached         = "max_ttl_reached"
+	problemValuesNotAllowed      = "values_not_allowed"
+	problemValuesInvalid         = "values_invalid"
+	problemUnknownProfile        = "unknown_profile"
+	problemClaimNotFound         = "claim_not_found"
+	problemClaimNotManaged       = "claim_not_managed"
+	problemClaimReleased         = "claim_released"
+	problemClaimDeleting         = "claim_deleting"
+	problemClaimFailed           = "claim_failed"
+	problemPoolExhausted         = "pool_exhausted"
+	problemNotPoolable           = "not_poolable"
+	problemCapacityReached       = "capacity_reached"
+	problemQuotaExceeded         = "quota_exceeded"
+	problemRateLimited           = "rate_limited"
+	problemOutsideWorkingHours   = "outside_working_hours"
+	problemTemplateDrift         = "template_drift"
+	problemConflict              = "conflict"
+	problemUnauthorized          = "unauthorized"
+	problemForbidden             = "forbidden"
+	problemMethodNotAllowed      = "method_not_allowed"
+	problemUnsupportedAPIVersion = "unsupported_api_version"
+	problemTimeout               = "timeout"
+	problemShuttingDown          = "shutting_down"
+	problemInternal              = "internal_error"
+)
+
+// Problem is an RFC 7807 error body; Code repeats the last segment of Type.
+type Problem struct {
+	Type       string                     `json:"type"`
+	Title      string                     `json:"title"`
+	Status     int                        `json:"status"`
+	Detail     string                     `json:"detail,omitempty"`
+	Instance   string                     `json:"instance,omitempty"`
+	Code       string                     `json:"code"`
+	RequestID  string                     `json:"requestId,omitempty"`
+	Violations []template.SchemaViolation `json:"violations,omitempty"`
+}
+
+type problemError struct {
+	code string
+	err  error
+}
+
+func (e *problemError) Error() string {
+	return e.err.Error()
+}
+
+func (e *problemError) Unwrap() error {
+	return e.err
+}
+
+func withProblemCode(code string, err error) error {
+	return &problemError{code: code, err: err}
+}
+
+func problemCode(err error, fallback string) string {
+	var coded *problemError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.Is(err, errClaimNotFound):
+		return problemClaimNotFound
+	case errors.Is(err, errClaimNotManaged):
+		return problemClaimNotManaged
+	case errors.Is(err, errMaxTTLReached):
+		return problemMaxTTLReached
+	case errors.Is(err, errClaimReleased):
+		return problemClaimReleased
+	case errors.Is(err, errPoolExhausted):
+		return problemPoolExhausted
+	case errors.Is(err, errNotPoolable):
+		return problemNotPoolable
+	case errors.Is(err, errTenantForbidden):
+		return problemForbidden
+	}
+	return fallback
+}
+
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+	problem := Problem{
+		Type:   problemTypeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+	if r != nil {
+		problem.Instance = r.URL.RequestURI()
+		problem.RequestID = RequestIDFromContext(r.Context())
+	}
+	return problem
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblemBody(w, newProblem(r, status, code, detail))
+}
+
+func writeErrorProblem(w http.ResponseWriter, r *http.Request, status int, err error, fallback string) {
+	writeProblem(w, r, status, problemCode(err, fallback), err.Error())
+}
+
+func writeProblemBody(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+func writeSchemaProblem(w http.ResponseWriter, r *http.Request, err *template.SchemaValidationError) {
+	problem := newProblem(r, http.StatusUnprocessableEntity, problemValuesInvalid, "values do not match schema")
+	problem.Violations = err.Violations
+	writeProblemBody(w, problem)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/template"
+	"github.com/nonot/claim-controller/internal/values"
+)
+
+const DefaultProfileName = "default"
+
+type Profile struct {
+	Name            string
+	Type            string
+	Backend         string
+	Namespace       string
+	Cluster         string
+	DefaultTTL      time.Duration
+	MaxTTL          time.Duration
+	Renderer        template.Renderer
+	ValuesProvider  values.Provider
+	SchemaValidator *template.SchemaValidator
+	MinReady        *controller.MinReady
+}
+
+type claimOptions struct {
+	namespace          string
+	cluster            string
+	tenant             string
+	profile            *Profile
+	overrides          map[string]any
+	team               string
+	idempotencyKey     string
+	metadata           *ClaimMetadata
+	priority           string
+	clonedFrom         string
+	startAt            time.Time
+	expiresAt          time.Time
+	requester          string
+	minReady           *controller.MinReady
+	workingHoursExempt bool
+}
+
+func (p *Profile) start(ctx context.Context) error {
+	if p.ValuesProvider == nil {
+		return nil
+	}
+	if err := p.ValuesProvider.Start(ctx); err != nil {
+		return fmt.Errorf("start values provider for profile %q: %w", p.Name, err)
+	}
+	if p.SchemaValidator == nil {
+		return nil
+	}
+
+	valuesData, err := p.ValuesProvider.GetValues()
+	if err != nil {
+		return fmt.Errorf("load values for profile %q: %w", p.Name, err)
+	}
+	if err := p.SchemaValidator.Validate(valuesData); err != nil {
+		return fmt.Errorf("validate values for profile %q: %w", p.Name, err)
+	}
+	return nil
+}
+
+func (s *Server) resolveProfile(name string) (*Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return s.defaultProfile, nil
+	}
+
+	profile, ok := s.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, available profiles: %s", name, strings.Join(s.profileNames(), ", "))
+	}
+	return profile, nil
+}
+
+func (s *Server) profileNames() []string {
+	names := make([]string, 0, len(s.profiles))
+	for name := range s.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileMetricLabel keeps metric cardinality bounded to the configured
+// profiles, whatever the claim label says.
+func (s *Server) profileMetricLabel(claim *corev1.ConfigMap) string {
+	name := claim.Labels[controller.ProfileLabelKey]
+	if name == "" {
+		return DefaultProfileName
+	}
+	if _, ok := s.profiles[name]; !ok {
+		return "unknown"
+	}
+	return name
+}
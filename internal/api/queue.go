@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const claimQueuePollInterval = 2 * time.Second
+
+var errClaimQueueTimeout = errors.New("timed out waiting in the claim queue")
+
+type queueTicket struct{}
+
+type claimQueue struct {
+	timeout time.Duration
+	mu      sync.Mutex
+	waiters []*queueTicket
+	wake    chan struct{}
+}
+
+func newClaimQueue(timeout time.Duration) *claimQueue {
+	if timeout <= 0 {
+		return nil
+	}
+	return &claimQueue{timeout: timeout, wake: make(chan struct{})}
+}
+
+func (q *claimQueue) pending() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
+
+func (q *claimQueue) signal() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// wait blocks in FIFO order until attempt reports done while at the head of
+// the queue, so two waiters never race for the same freed slot.
+func (q *claimQueue) wait(ctx context.Context, attempt func() (bool, error)) error {
+	ticket := &queueTicket{}
+	q.mu.Lock()
+	q.waiters = append(q.waiters, ticket)
+	q.mu.Unlock()
+	claimQueueDepth.Inc()
+
+	start := time.Now()
+	defer func() {
+		q.remove(ticket)
+		claimQueueDepth.Dec()
+		claimQueueWaitSeconds.Observe(time.Since(start).Seconds())
+		q.signal()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	poll := time.NewTicker(claimQueuePollInterval)
+	defer poll.Stop()
+	for {
+		q.mu.Lock()
+		head := q.waiters[0] == ticket
+		wake := q.wake
+		q.mu.Unlock()
+
+		if head {
+			done, err := attempt()
+			if done {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return errClaimQueueTimeout
+		case <-wake:
+		case <-poll.C:
+		}
+	}
+}
+
+func (q *claimQueue) remove(ticket *queueTicket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, waiter := range q.waiters {
+		if waiter == ticket {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+var quotaUsage = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "claim_controller_quota_usage",
+	Help: "Current quota usage per quota key (active_claims or total_ttl_seconds).",
+}, []string{"key", "resource"})
+
+type QuotaLimit struct {
+	MaxActiveClaims int
+	MaxTotalTTL     time.Duration
+}
+
+type QuotaPolicy struct {
+	Default *QuotaLimit
+	Users   map[string]QuotaLimit
+	Teams   map[string]QuotaLimit
+}
+
+type quotaExceededError struct {
+	key        string
+	reason     string
+	retryAfter time.Duration
+	// unsatisfiable is set when the request can never fit the limit, so
+	// retrying later is pointless.
+	unsatisfiable bool
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s: %s", e.key, e.reason)
+}
+
+// limitFor returns the quota key and limit for a request. Teams are chosen by
+// the caller, so only configured teams get a shared key: the default limit is
+// always applied per user, otherwise a new team name would be a fresh budget.
+func (p *QuotaPolicy) limitFor(subject, team string) (string, *QuotaLimit) {
+	if p == nil {
+		return "", nil
+	}
+	if team != "" {
+		if limit, ok := p.Teams[team]; ok {
+			return "team:" + team, &limit
+		}
+	}
+	if subject != "" {
+		if limit, ok := p.Users[subject]; ok {
+			return "user:" + subject, &limit
+		}
+	}
+	if p.Default == nil {
+		return "", nil
+	}
+	if subject != "" {
+		return "user:" + subject, p.Default
+	}
+	return "", nil
+}
+
+func (s *Server) checkQuota(ctx context.Context, team string, tenant *Tenant, ttl time.Duration) error {
+	subject := ""
+	if identity, ok := IdentityFromContext(ctx); ok {
+		subject = identity.Subject
+	}
+
+	key, limit := s.currentTunables().Quotas.limitFor(subject, team)
+	if limit == nil && (tenant == nil || tenant.Quota == nil) {
+		return nil
+	}
+
+	claims := &corev1.ConfigMapList{}
+	if err := s.listClaims(ctx, claims, client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}); err != nil {
+		return err
+	}
+
+	if tenant != nil && tenant.Quota != nil {
+		err := enforceQuota("tenant:"+tenant.Name, tenant.Quota, claims.Items, ttl, func(claim *corev1.ConfigMap) bool {
+			return claim.Labels[controller.TenantLabelKey] == tenant.Name
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if limit == nil {
+		return nil
+	}
+	return enforceQuota(key, limit, claims.Items, ttl, func(claim *corev1.ConfigMap) bool {
+		if team != "" && key == "team:"+team {
+			return claim.Labels[controller.TeamLabelKey] == team
+		}
+		return claim.Annotations[controller.OwnerAnnotationKey] == subject
+	})
+}
+
+func enforceQuota(key string, limit *QuotaLimit, claims []corev1.ConfigMap, ttl time.Duration, matches func(*corev1.ConfigMap) bool) error {
+	now := time.Now().UTC()
+	activeClaims := 0
+	var totalTTL, nextExpiry time.Duration
+	for i := range claims {
+		claim := &claims[i]
+		if claim.DeletionTimestamp != nil || claim.Annotations[controller.PreProvisionedAnnotationKey] == "true" || !matches(claim) {
+			continue
+		}
+
+		activeClaims++
+		if expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey]); err == nil && expiresAt.After(now) {
+			untilExpiry := expiresAt.Sub(now)
+			totalTTL += untilExpiry
+			if nextExpiry == 0 || untilExpiry < nextExpiry {
+				nextExpiry = untilExpiry
+			}
+		}
+	}
+
+	quotaUsage.WithLabelValues(key, "active_claims").Set(float64(activeClaims))
+	quotaUsage.WithLabelValues(key, "total_ttl_seconds").Set(totalTTL.Seconds())
+
+	if limit.MaxActiveClaims > 0 && activeClaims+1 > limit.MaxActiveClaims {
+		return &quotaExceededError{key: key, reason: fmt.Sprintf("%d/%d active claims", activeClaims, limit.MaxActiveClaims), retryAfter: nextExpiry}
+	}
+	if limit.MaxTotalTTL > 0 && ttl > limit.MaxTotalTTL {
+		return &quotaExceededError{key: key, reason: fmt.Sprintf("ttl %s exceeds total ttl limit %s", ttl, limit.MaxTotalTTL), unsatisfiable: true}
+	}
+	if limit.MaxTotalTTL > 0 && totalTTL+ttl > limit.MaxTotalTTL {
+		// Every active claim burns one second of the budget per second.
+		excess := totalTTL + ttl - limit.MaxTotalTTL
+		return &quotaExceededError{key: key, reason: fmt.Sprintf("total ttl %s would exceed %s", (totalTTL + ttl).Round(time.Second), limit.MaxTotalTTL), retryAfter: excess / time.Duration(max(activeClaims, 1))}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func TestQuotaPolicyLimitFor(t *testing.T) {
+	policy := &QuotaPolicy{
+		Default: &QuotaLimit{MaxActiveClaims: 1},
+		Users:   map[string]QuotaLimit{"apikey:ci": {MaxActiveClaims: 20}},
+		Teams:   map[string]QuotaLimit{"platform": {MaxActiveClaims: 10}},
+	}
+
+	tests := []struct {
+		name    string
+		policy  *QuotaPolicy
+		subject string
+		team    string
+		wantKey string
+		wantMax int
+	}{
+		{name: "no policy", subject: "apikey:ci"},
+		{name: "configured team", policy: policy, subject: "apikey:ci", team: "platform", wantKey: "team:platform", wantMax: 10},
+		{name: "unknown team falls back to the user", policy: policy, subject: "apikey:ci", team: "new", wantKey: "user:apikey:ci", wantMax: 20},
+		{name: "default per user", policy: policy, subject: "oidc:alice", wantKey: "user:oidc:alice", wantMax: 1},
+		{name: "anonymous has no default", policy: policy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, limit := tt.policy.limitFor(tt.subject, tt.team)
+			if key != tt.wantKey {
+				t.Errorf("key = %q, want %q", key, tt.wantKey)
+			}
+			got := 0
+			if limit != nil {
+				got = limit.MaxActiveClaims
+			}
+			if got != tt.wantMax {
+				t.Errorf("max active claims = %d, want %d", got, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestEnforceQuota(t *testing.T) {
+	now := time.Now().UTC()
+	active := func(id string, expiresIn time.Duration) corev1.ConfigMap {
+		return *testClaim(id, map[string]string{controller.ExpiresAtAnnotationKey: now.Add(expiresIn).Format(time.RFC3339)})
+	}
+	pooled := active("pool", time.Hour)
+	pooled.Annotations[controller.PreProvisionedAnnotationKey] = "true"
+	claims := []corev1.ConfigMap{active("a", 30*time.Minute), active("b", time.Hour), pooled}
+
+	tests := []struct {
+		name              string
+		limit             QuotaLimit
+		ttl               time.Duration
+		wantErr           bool
+		wantUnsatisfiable bool
+		wantRetryAfter    time.Duration
+	}{
+		{name: "within limits", limit: QuotaLimit{MaxActiveClaims: 3, MaxTotalTTL: 4 * time.Hour}, ttl: time.Hour},
+		{name: "active claims reached", limit: QuotaLimit{MaxActiveClaims: 2}, ttl: time.Hour, wantErr: true, wantRetryAfter: 30 * time.Minute},
+		{name: "ttl beyond the total budget", limit: QuotaLimit{MaxTotalTTL: time.Hour}, ttl: 2 * time.Hour, wantErr: true, wantUnsatisfiable: true},
+		{name: "total budget used", limit: QuotaLimit{MaxTotalTTL: 2 * time.Hour}, ttl: time.Hour, wantErr: true, wantRetryAfter: 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := enforceQuota("test", &tt.limit, claims, tt.ttl, func(*corev1.ConfigMap) bool { return true })
+			var quotaErr *quotaExceededError
+			if !errors.As(err, &quotaErr) {
+				if tt.wantErr {
+					t.Fatalf("error = %v, want quota exceeded", err)
+				}
+				return
+			}
+			if !tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if quotaErr.unsatisfiable != tt.wantUnsatisfiable {
+				t.Errorf("unsatisfiable = %v, want %v", quotaErr.unsatisfiable, tt.wantUnsatisfiable)
+			}
+			if diff := quotaErr.retryAfter - tt.wantRetryAfter; diff < -2*time.Second || diff > 2*time.Second {
+				t.Errorf("retryAfter = %s, want %s", quotaErr.retryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	now := time.Now().UTC()
+	claim := func(id string, expiresIn time.Duration) client.Object {
+		return testClaim(id, map[string]string{controller.ExpiresAtAnnotationKey: now.Add(expiresIn).Format(time.RFC3339)})
+	}
+	pooled := claim("pool", time.Hour).(*corev1.ConfigMap)
+	pooled.Annotations[controller.PreProvisionedAnnotationKey] = "true"
+
+	tests := []struct {
+		name           string
+		maxActive      int
+		wantOK         bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "unlimited", wantOK: true},
+		{name: "room left", maxActive: 3, wantOK: true},
+		{name: "full", maxActive: 2, wantRetryAfter: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{MaxActiveClaims: tt.maxActive}, claim("a", 10*time.Minute), claim("b", time.Hour), pooled)
+
+			ok, retryAfter, err := s.checkCapacity(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if diff := retryAfter - tt.wantRetryAfter; diff < -2*time.Second || diff > 2*time.Second {
+				t.Errorf("retryAfter = %s, want %s", retryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	for d, want := range map[time.Duration]int{0: 1, 200 * time.Millisecond: 1, 1500 * time.Millisecond: 2, time.Minute: 60} {
+		if got := retryAfterSeconds(d); got != want {
+			t.Errorf("retryAfterSeconds(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
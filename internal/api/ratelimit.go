@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const rateLimiterIdleTTL = 10 * time.Minute
+
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	clients   map[string]*rateLimiterEntry
+	lastSweep time.Time
+}
+
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &rateLimiter{
+		limit:   rate.Limit(float64(perMinute) / 60),
+		burst:   burst,
+		clients: map[string]*rateLimiterEntry{},
+	}
+}
+
+func (l *rateLimiter) reserve(key string) time.Duration {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for clientKey, entry := range l.clients {
+			if now.Sub(entry.lastSeen) > rateLimiterIdleTTL {
+				delete(l.clients, clientKey)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.clients[key]
+	if !ok {
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = entry
+	}
+	entry.lastSeen = now
+
+	if entry.limiter.AllowN(now, 1) {
+		return 0
+	}
+	// The reservation only measures the wait; cancel it so rejected retries
+	// do not book future tokens.
+	reservation := entry.limiter.ReserveN(now, 1)
+	defer reservation.CancelAt(now)
+	return reservation.DelayFrom(now)
+}
+
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	if s.claimRateLimiter == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+
+		if delay := s.claimRateLimiter.reserve(rateLimitKey(r)); delay > 0 {
+			claimsRejectedTotal.WithLabelValues("rate_limit").Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(delay)))
+			writeProblem(w, r, http.StatusTooManyRequests, problemRateLimited, "rate limit exceeded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func rateLimitKey(r *http.Request) string {
+	if identity, ok := IdentityFromContext(r.Context()); ok && identity.Subject != "" {
+		return "identity:" + identity.Subject
+	}
+	return "ip:" + remoteHost(r)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *headerTracker) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerTracker) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *headerTracker) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (s *Server) recovered(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracker := &headerTracker{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// net/http uses this sentinel to abort a response on purpose.
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			apiPanicsTotal.WithLabelValues(route).Inc()
+			s.requestLogger(r.Context()).Error(fmt.Errorf("%v", recovered), "recovered from panic in API handler",
+				"method", r.Method, "path", r.URL.Path, "route", route, "stack", string(debug.Stack()))
+			if !tracker.wroteHeader {
+				writeProblem(w, r, http.StatusInternalServerError, problemInternal, "internal server error")
+			}
+		}()
+		next.ServeHTTP(tracker, r)
+	})
+}
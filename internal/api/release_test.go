@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func TestHandleRelease(t *testing.T) {
+	tests := []struct {
+		name          string
+		id            string
+		subject       string
+		wantStatus    int
+		wantDeleted   bool
+		wantTombstone bool
+	}{
+		{name: "owner releases", id: "abc", subject: "oidc:alice", wantStatus: http.StatusNoContent, wantDeleted: true, wantTombstone: true},
+		{name: "other subject", id: "abc", subject: "oidc:bob", wantStatus: http.StatusForbidden},
+		{name: "unknown claim", id: "nope", subject: "oidc:alice", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := testClaim("abc", map[string]string{controller.OwnerAnnotationKey: "oidc:alice"})
+			s := newTestServer(t, Config{ClaimHistoryRetention: time.Hour}, claim)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/release/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			rec := httptest.NewRecorder()
+			s.handleRelease(rec, withIdentity(req, tt.subject))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s; want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			err := s.client.Get(context.Background(), client.ObjectKeyFromObject(claim), &corev1.ConfigMap{})
+			if deleted := apierrors.IsNotFound(err); deleted != tt.wantDeleted {
+				t.Errorf("claim deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			tombstone := &corev1.ConfigMap{}
+			err = s.client.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: claim.Name + "-tombstone"}, tombstone)
+			if recorded := err == nil; recorded != tt.wantTombstone {
+				t.Fatalf("tombstone recorded = %v, want %v", recorded, tt.wantTombstone)
+			}
+			if tt.wantTombstone {
+				record, ok := controller.TombstoneFrom(tombstone)
+				if !ok || record.Reason != controller.EndReasonReleased || record.EndedBy != tt.subject {
+					t.Errorf("tombstone = %+v", record)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleRenew(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name       string
+		claimedAt  time.Time
+		body       string
+		wantStatus int
+	}{
+		{name: "renews", claimedAt: now, body: `{"ttl":"30m"}`, wantStatus: http.StatusOK},
+		{name: "max ttl reached", claimedAt: now.Add(-5 * time.Hour), body: `{"ttl":"30m"}`, wantStatus: http.StatusConflict},
+		{name: "invalid ttl", claimedAt: now, body: `{"ttl":"soon"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := testClaim("abc", map[string]string{
+				controller.OwnerAnnotationKey:     "oidc:alice",
+				controller.ClaimedAtAnnotationKey: tt.claimedAt.Format(time.RFC3339),
+				controller.ExpiresAtAnnotationKey: now.Add(time.Minute).Format(time.RFC3339),
+			})
+			s := newTestServer(t, Config{}, claim)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/renew/abc", strings.NewReader(tt.body))
+			req.SetPathValue("id", "abc")
+			rec := httptest.NewRecorder()
+			s.handleRenew(rec, withIdentity(req, "oidc:alice"))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, body = %s; want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"time"
+)
+
+type Tunables struct {
+	DefaultTTL        time.Duration
+	MaxTTL            time.Duration
+	PreProvisionCount int
+	Quotas            *QuotaPolicy
+}
+
+func (t Tunables) normalized() Tunables {
+	if t.MaxTTL < t.DefaultTTL {
+		t.MaxTTL = t.DefaultTTL
+	}
+	t.PreProvisionCount = max(0, t.PreProvisionCount)
+	return t
+}
+
+func (s *Server) currentTunables() *Tunables {
+	return s.tunables.Load()
+}
+
+func (s *Server) Reload(tunables Tunables) {
+	tunables = tunables.normalized()
+	previous := s.tunables.Swap(&tunables)
+	if s.provisioningMode != ProvisioningModeOnDemand && previous.PreProvisionCount != tunables.PreProvisionCount {
+		s.setPoolTarget(tunables.PreProvisionCount)
+	}
+	s.logger.Info("runtime configuration reloaded", "defaultTTL", tunables.DefaultTTL.String(), "maxTTL", tunables.MaxTTL.String(), "preProvisionCount", tunables.PreProvisionCount, "previousDefaultTTL", previous.DefaultTTL.String(), "previousMaxTTL", previous.MaxTTL.String())
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func (s *Server) storeRenderedChunks(ctx context.Context, claim *corev1.ConfigMap, chunks [][]byte) error {
+	for i, chunk := range chunks {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.RenderedChunkSecretName(claim.Name, i),
+			Namespace: claim.Namespace,
+		}}
+		_, err := controllerutil.CreateOrUpdate(ctx, s.client, secret, func() error {
+			if secret.Labels == nil {
+				secret.Labels = map[string]string{}
+			}
+			secret.Labels[controller.ManagedByLabelKey] = controller.ManagedByLabelValue
+			secret.Labels[controller.ClaimLabelKey] = claim.Name
+			secret.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Name:       claim.Name,
+				UID:        claim.UID,
+			}}
+			secret.Type = corev1.SecretTypeOpaque
+			secret.Data = map[string][]byte{controller.RenderedChunkDataKey: chunk}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("store rendered resources chunk %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) pruneRenderedChunks(ctx context.Context, claim *corev1.ConfigMap, keep int) error {
+	for i := keep; ; i++ {
+		stale := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.RenderedChunkSecretName(claim.Name, i),
+			Namespace: claim.Namespace,
+		}}
+		err := s.client.Delete(ctx, stale)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("delete stale rendered resources chunk %d: %w", i, err)
+		}
+	}
+}
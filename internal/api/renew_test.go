@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+func TestRenewClaimCountsFromStartAt(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	startAt := now.Add(2 * time.Hour)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		ttl         time.Duration
+		want        time.Time
+	}{
+		{
+			name:        "active claim renews from now",
+			annotations: map[string]string{controller.ClaimedAtAnnotationKey: now.Format(time.RFC3339)},
+			ttl:         30 * time.Minute,
+			want:        now.Add(30 * time.Minute),
+		},
+		{
+			name: "scheduled claim renews from its start",
+			annotations: map[string]string{
+				controller.ClaimedAtAnnotationKey: now.Format(time.RFC3339),
+				controller.StartAtAnnotationKey:   startAt.Format(time.RFC3339),
+			},
+			ttl:  30 * time.Minute,
+			want: startAt.Add(30 * time.Minute),
+		},
+		{
+			name: "scheduled claim is capped from its start",
+			annotations: map[string]string{
+				controller.ClaimedAtAnnotationKey: now.Format(time.RFC3339),
+				controller.StartAtAnnotationKey:   startAt.Format(time.RFC3339),
+			},
+			ttl:  10 * time.Hour,
+			want: startAt.Add(4 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := testClaim("abc", tt.annotations)
+			s := newTestServer(t, Config{}, claim)
+
+			updated, err := s.renewClaim(context.Background(), *claim, tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := time.Parse(time.RFC3339, updated.Annotations[controller.ExpiresAtAnnotationKey])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := got.Sub(tt.want); diff < -time.Second || diff > time.Second {
+				t.Errorf("expiresAt = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
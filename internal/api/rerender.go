@@ -0,0 +1,390 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/helmrelease"
+	"github.com/nonot/claim-controller/internal/template"
+)
+
+const (
+	defaultRerenderRatePerMinute = 30
+	rerenderFieldOwner           = "claim-controller-rerender"
+)
+
+type rerenderRequest struct {
+	Profile string `json:"profile,omitempty"`
+	DryRun  bool   `json:"dryRun,omitempty"`
+}
+
+type staleClaim struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Profile        string `json:"profile"`
+	PreProvisioned bool   `json:"preProvisioned"`
+	TemplateHash   string `json:"templateHash"`
+	ValuesHash     string `json:"valuesHash"`
+}
+
+func (s *Server) handleAdminRerender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req rerenderRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeDecodeProblem(w, r, err)
+		return
+	}
+	if req.Profile != "" {
+		if _, ok := s.profiles[req.Profile]; !ok {
+			writeProblem(w, r, http.StatusBadRequest, problemUnknownProfile, fmt.Sprintf("unknown profile %q", req.Profile))
+			return
+		}
+	}
+
+	stale, err := s.findStaleClaims(r.Context(), req.Profile)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to detect stale claims")
+		return
+	}
+	summaries := make([]staleClaim, 0, len(stale))
+	for _, claim := range stale {
+		summaries = append(summaries, staleClaim{
+			ID:             claim.Labels[controller.ClaimLabelKeyId],
+			Name:           claim.Name,
+			Profile:        claim.Labels[controller.ProfileLabelKey],
+			PreProvisioned: claim.Annotations[controller.PreProvisionedAnnotationKey] == "true",
+			TemplateHash:   claim.Annotations[controller.TemplateHashAnnotationKey],
+			ValuesHash:     claim.Annotations[controller.ValuesHashAnnotationKey],
+		})
+	}
+
+	if req.DryRun || len(stale) == 0 {
+		writeJSON(w, http.StatusOK, rerenderResponse{Claims: summaries})
+		return
+	}
+	if !s.rerenderRunning.CompareAndSwap(false, true) {
+		writeProblem(w, r, http.StatusConflict, problemConflict, "a re-render rollout is already running")
+		return
+	}
+
+	go s.rolloutRerender(s.runContext(), stale)
+	writeJSON(w, http.StatusAccepted, rerenderResponse{Claims: summaries, RatePerMinute: s.rerenderRatePerMinute})
+}
+
+func (s *Server) findStaleClaims(ctx context.Context, profileName string) ([]corev1.ConfigMap, error) {
+	selector := client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}
+	if profileName != "" {
+		selector[controller.ProfileLabelKey] = profileName
+	}
+	claims := &corev1.ConfigMapList{}
+	if err := s.listClaims(ctx, claims, selector); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	versions := map[string]renderVersion{}
+	stale := make([]corev1.ConfigMap, 0)
+	for _, claim := range claims.Items {
+		if claim.DeletionTimestamp != nil {
+			continue
+		}
+		if expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey]); err == nil && !expiresAt.After(now) {
+			continue
+		}
+
+		name := claim.Labels[controller.ProfileLabelKey]
+		profile, ok := s.profiles[name]
+		if !ok {
+			continue
+		}
+		current, ok := versions[name]
+		if !ok {
+			var err error
+			if current, err = s.currentRenderVersion(profile); err != nil {
+				return nil, err
+			}
+			versions[name] = current
+		}
+		if claim.Annotations[controller.TemplateHashAnnotationKey] == current.templateHash && claim.Annotations[controller.ValuesHashAnnotationKey] == current.valuesHash {
+			continue
+		}
+		stale = append(stale, claim)
+	}
+	return stale, nil
+}
+
+func (s *Server) currentRenderVersion(profile *Profile) (renderVersion, error) {
+	if profile.Renderer == nil || profile.ValuesProvider == nil {
+		return renderVersion{}, fmt.Errorf("profile %q is not fully configured", profile.Name)
+	}
+	templateHash, err := profile.Renderer.Version()
+	if err != nil {
+		return renderVersion{}, fmt.Errorf("compute template version for profile %q: %w", profile.Name, err)
+	}
+	valuesData, err := profile.ValuesProvider.GetValues()
+	if err != nil {
+		return renderVersion{}, fmt.Errorf("load values for profile %q: %w", profile.Name, err)
+	}
+	return renderVersion{templateHash: templateHash, valuesHash: template.HashBytes(valuesData)}, nil
+}
+
+func (s *Server) rolloutRerender(ctx context.Context, claims []corev1.ConfigMap) {
+	defer s.rerenderRunning.Store(false)
+
+	interval := time.Minute / time.Duration(s.rerenderRatePerMinute)
+	s.logger.Info("starting re-render rollout", "claims", len(claims), "interval", interval.String())
+	rerendered, failed := 0, 0
+	for i := range claims {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("re-render rollout interrupted", "rerendered", rerendered, "failed", failed)
+				return
+			case <-time.After(interval):
+			}
+		}
+
+		if err := s.rerenderClaim(ctx, &claims[i]); err != nil {
+			failed++
+			s.logger.Error(err, "failed to re-render claim", "claim", claims[i].Name)
+			continue
+		}
+		rerendered++
+	}
+	s.logger.Info("re-render rollout finished", "rerendered", rerendered, "failed", failed)
+}
+
+func (s *Server) rerenderClaim(ctx context.Context, claim *corev1.ConfigMap) error {
+	if claim.Annotations[controller.PreProvisionedAnnotationKey] == "true" {
+		err := s.client.Delete(ctx, claim, client.Preconditions{ResourceVersion: &claim.ResourceVersion}, foregroundPropagation())
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		s.logger.Info("recycled stale pre-provisioned claim", "claim", claim.Name)
+		s.triggerPoolReconcile()
+		return nil
+	}
+
+	overrides, err := recordedOverrides(claim)
+	if err != nil {
+		return err
+	}
+	_, err = s.applyClaimRender(ctx, claim, overrides)
+	return err
+}
+
+func recordedOverrides(claim *corev1.ConfigMap) (map[string]any, error) {
+	raw := strings.TrimSpace(claim.Annotations[controller.ValuesOverridesAnnotationKey])
+	if raw == "" {
+		return nil, nil
+	}
+	var overrides map[string]any
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("decode recorded values overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+func (s *Server) applyClaimRender(ctx context.Context, claim *corev1.ConfigMap, overrides map[string]any) (*corev1.ConfigMap, error) {
+	profile, ok := s.profiles[claim.Labels[controller.ProfileLabelKey]]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", claim.Labels[controller.ProfileLabelKey])
+	}
+	opts := claimOptions{
+		namespace: claim.Namespace,
+		cluster:   claim.Annotations[controller.ClusterAnnotationKey],
+		profile:   profile,
+		overrides: overrides,
+		requester: claim.Annotations[controller.OwnerAnnotationKey],
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey]); err == nil {
+		opts.expiresAt = expiresAt
+	}
+	overridesBytes, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	claimID := claim.Labels[controller.ClaimLabelKeyId]
+	helmRelease := claim.Annotations[controller.BackendAnnotationKey] == helmrelease.BackendHelmRelease
+	var resourceTemplate template.ResourceTemplate
+	var version renderVersion
+	var releaseValues []byte
+	if helmRelease {
+		resourceTemplate, version, releaseValues, err = s.releaseResourceTemplate(ctx, claimID, opts)
+	} else {
+		resourceTemplate, version, err = s.loadResourceTemplate(claimID, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	renderedResourcesBytes, err := json.Marshal(resourceTemplate.RenderedObjects)
+	if err != nil {
+		return nil, err
+	}
+	renderedResources, renderedChunks, err := controller.PackRenderedResources(renderedResourcesBytes)
+	if err != nil {
+		return nil, err
+	}
+	returnValuesBytes, err := json.Marshal(resourceTemplate.ReturnValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if !helmRelease {
+		if err := s.applyRenderedObjects(ctx, claimID, opts, resourceTemplate.RenderedObjects); err != nil {
+			return nil, err
+		}
+		if err := s.pruneDroppedObjects(ctx, claim, claimID, opts, resourceTemplate.RenderedObjects); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.storeRenderedChunks(ctx, claim, renderedChunks); err != nil {
+		return nil, err
+	}
+
+	updated := claim.DeepCopy()
+	if updated.Data == nil {
+		updated.Data = map[string]string{}
+	}
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Data[controller.RenderedResourcesDataKey] = renderedResources
+	updated.Data[controller.ReturnValuesDataKey] = string(returnValuesBytes)
+	if helmRelease {
+		updated.Data[controller.HelmReleaseValuesDataKey] = string(releaseValues)
+	}
+	version.apply(updated)
+	if len(overrides) > 0 {
+		updated.Annotations[controller.ValuesOverridesAnnotationKey] = string(overridesBytes)
+	} else {
+		delete(updated.Annotations, controller.ValuesOverridesAnnotationKey)
+	}
+	if err := s.client.Patch(ctx, updated, client.MergeFrom(claim), client.FieldOwner(apiFieldOwner)); err != nil {
+		return nil, err
+	}
+	if err := s.pruneRenderedChunks(ctx, updated, len(renderedChunks)); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// applyRenderedObjects applies every object, even after a failure, and
+// reports all failures. The claim keeps its previous render until everything
+// applied, so the claim stays stale and a retry re-applies the whole render.
+func (s *Server) applyRenderedObjects(ctx context.Context, claimID string, opts claimOptions, objects []json.RawMessage) error {
+	target, err := s.targetClient(ctx, opts.cluster)
+	if err != nil {
+		return err
+	}
+	namespace := s.resourceNamespace(claimID, opts)
+	var errs []error
+	for _, raw := range objects {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("decode rendered resource: %w", err)
+		}
+		mapping, err := target.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve resource scope for %s %s: %w", obj.GetKind(), obj.GetName(), err))
+			continue
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj.SetNamespace(namespace)
+		}
+		obj.SetManagedFields(nil)
+		obj.SetResourceVersion("")
+		if err := target.Patch(ctx, obj, client.Apply, client.FieldOwner(rerenderFieldOwner), client.ForceOwnership); err != nil {
+			errs = append(errs, fmt.Errorf("apply %s %s: %w", obj.GetKind(), obj.GetName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pruneDroppedObjects deletes the objects of the recorded render that the new
+// render no longer contains, before the new render replaces the record.
+func (s *Server) pruneDroppedObjects(ctx context.Context, claim *corev1.ConfigMap, claimID string, opts claimOptions, objects []json.RawMessage) error {
+	previousJSON, err := controller.RenderedResourcesJSON(ctx, s.client, claim)
+	if err != nil {
+		return err
+	}
+	var previous []map[string]any
+	if len(previousJSON) > 0 {
+		if err := json.Unmarshal(previousJSON, &previous); err != nil {
+			return fmt.Errorf("decode previous rendered resources: %w", err)
+		}
+	}
+
+	kept := map[string]bool{}
+	for _, raw := range objects {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("decode rendered resource: %w", err)
+		}
+		kept[renderedObjectKey(obj)] = true
+	}
+
+	target, err := s.targetClient(ctx, opts.cluster)
+	if err != nil {
+		return err
+	}
+	namespace := s.resourceNamespace(claimID, opts)
+	for _, raw := range previous {
+		obj := &unstructured.Unstructured{Object: raw}
+		if kept[renderedObjectKey(obj)] {
+			continue
+		}
+		mapping, err := target.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("resolve resource scope for %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj.SetNamespace(namespace)
+		}
+		if err := target.Delete(ctx, obj, foregroundPropagation()); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("prune %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func renderedObjectKey(obj *unstructured.Unstructured) string {
+	return obj.GroupVersionKind().Group + "/" + obj.GetKind() + "/" + obj.GetName()
+}
+
+func (s *Server) targetClient(ctx context.Context, clusterName string) (client.Client, error) {
+	if clusterName == "" {
+		return s.client, nil
+	}
+	if s.clusters == nil {
+		return nil, fmt.Errorf("claim targets cluster %q but no cluster registry is configured", clusterName)
+	}
+	return s.clusters.Client(ctx, clusterName)
+}
+
+func (s *Server) runContext() context.Context {
+	if ctx := s.ctx.Load(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const defaultReleaseRetention = time.Hour
+
+var errClaimReleased = errors.New("claim was released and cannot be renewed")
+
+func isReleasedClaim(claim *corev1.ConfigMap) bool {
+	return claim.Annotations[controller.ReleasedAtAnnotationKey] != ""
+}
+
+func (s *Server) softReleaseClaim(ctx context.Context, claim corev1.ConfigMap) (time.Time, error) {
+	now := time.Now().UTC()
+	retainUntil := now.Add(s.releaseRetention)
+
+	updated := claim.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, updated.Annotations[controller.ExpiresAtAnnotationKey]); err == nil && expiresAt.Before(retainUntil) {
+		retainUntil = expiresAt.UTC()
+	}
+	if !isReleasedClaim(updated) {
+		updated.Annotations[controller.ReleasedAtAnnotationKey] = now.Format(time.RFC3339)
+		if identity, ok := IdentityFromContext(ctx); ok && identity.Subject != "" {
+			updated.Annotations[controller.ReleasedByAnnotationKey] = identity.Subject
+		}
+	}
+	updated.Annotations[controller.ExpiresAtAnnotationKey] = retainUntil.Format(time.RFC3339)
+	if err := s.client.Patch(ctx, updated, client.MergeFrom(&claim), client.FieldOwner(apiFieldOwner)); err != nil {
+		return time.Time{}, fmt.Errorf("soft release claim %s: %w", claim.Name, err)
+	}
+	return retainUntil, nil
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTTLUntil(t *testing.T) {
+	policy := ttlPolicy{defaultTTL: time.Hour, maxTTL: 4 * time.Hour}
+	now := time.Now().UTC().Truncate(time.Second)
+	startAt := now.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		startAt   string
+		want      time.Duration
+		wantErr   string
+	}{
+		{name: "from now", expiresAt: now.Add(2 * time.Hour), want: 2 * time.Hour},
+		{name: "from startAt", expiresAt: startAt.Add(3 * time.Hour), startAt: startAt.Format(time.RFC3339), want: 3 * time.Hour},
+		{name: "past startAt is ignored", expiresAt: now.Add(time.Hour), startAt: now.Add(-time.Hour).Format(time.RFC3339), want: time.Hour},
+		{name: "before startAt", expiresAt: now.Add(time.Hour), startAt: startAt.Format(time.RFC3339), wantErr: "future"},
+		{name: "beyond max ttl", expiresAt: startAt.Add(5 * time.Hour), startAt: startAt.Format(time.RFC3339), wantErr: "maximum TTL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ttlUntil(tt.expiresAt.Format(time.RFC3339), tt.startAt, policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := got - tt.want; diff < -time.Second || diff > time.Second {
+				t.Errorf("ttl = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartAtHorizon(t *testing.T) {
+	s := newTestServer(t, Config{MaxStartAtHorizon: 48 * time.Hour})
+
+	tests := []struct {
+		name    string
+		startAt time.Time
+		want    int
+	}{
+		{name: "beyond the horizon", startAt: time.Now().Add(72 * time.Hour), want: http.StatusBadRequest},
+		{name: "within the horizon", startAt: time.Now().Add(24 * time.Hour), want: http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"ttl":"1h","startAt":"` + tt.startAt.UTC().Format(time.RFC3339) + `"}`
+			rec := httptest.NewRecorder()
+			s.handleClaim(rec, httptest.NewRequest(http.MethodPost, "/v1/claim", strings.NewReader(body)))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, body = %s; want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -5,312 +5,869 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
+	"github.com/nonot/claim-controller/internal/audit"
+	"github.com/nonot/claim-controller/internal/cluster"
 	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/helmrelease"
+	"github.com/nonot/claim-controller/internal/notify"
+	"github.com/nonot/claim-controller/internal/template"
 	"github.com/nonot/claim-controller/internal/values"
+	"github.com/nonot/claim-controller/internal/vcluster"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Config struct {
-	Namespace         string
-	DefaultTTL        time.Duration
-	MaxTTL            time.Duration
-	PreProvisionCount int
-	TemplatePath      string
-	ValuesProvider    values.Provider
-	Client            client.Client
+	Namespace             string
+	DefaultTTL            time.Duration
+	MaxTTL                time.Duration
+	PreProvisionCount     int
+	Renderer              template.Renderer
+	SchemaValidator       *template.SchemaValidator
+	ValuesAllowlist       values.Allowlist
+	ValuesProvider        values.Provider
+	Profiles              []Profile
+	CredentialKeys        []string
+	ReleaseWaitTimeout    time.Duration
+	ReleaseRetention      time.Duration
+	ClaimHistoryRetention time.Duration
+	ClaimReadyTimeout     time.Duration
+	MaxClaimReadyTimeout  time.Duration
+	MaxSuspendDuration    time.Duration
+	MaxStartAtHorizon     time.Duration
+	WorkingHours          *controller.WorkingHours
+	Notifier              notify.Notifier
+	AuditSink             audit.Sink
+	Authenticators        []Authenticator
+	AuthRoutes            []string
+	AdminSubjects         []string
+	Quotas                *QuotaPolicy
+	IdempotencyKeyTTL     time.Duration
+	MaxActiveClaims       int
+	PoolReserve           int
+	PoolMaxIdleAge        time.Duration
+	ProvisioningMode      ProvisioningMode
+	PoolReplenishEvery    time.Duration
+	ClaimQueueTimeout     time.Duration
+	NamespacePerClaim     bool
+	WatchNamespaces       []string
+	Tenants               []Tenant
+	Clusters              *cluster.Registry
+	ClaimRatePerMinute    int
+	ClaimRateBurst        int
+	RerenderRatePerMinute int
+	Backend               string
+	HelmReleases          *helmrelease.Client
+	APIDocs               bool
+	AccessLog             bool
+	AccessLogProbes       bool
+	MaxRequestBodyBytes   int64
+	RenderCheckEvery      time.Duration
+	CORSAllowedOrigins    []string
+	CORSAllowedMethods    []string
+	CORSAllowedHeaders    []string
+	Logger                logr.Logger
+	ReadinessChecks       map[string]healthz.Checker
+	Client                client.Client
+	APIReader             client.Reader
 }
 
 type Server struct {
-	namespace          string
-	defaultTTL         time.Duration
-	maxTTL             time.Duration
-	templatePath       string
-	valuesProvider     values.Provider
-	client             client.Client
-	claimLifetime      prometheus.Observer
-	claimTotalTTL      prometheus.Observer
-	claimIdleDuration  prometheus.Observer
-	claimUsageDuration prometheus.Observer
-	preProvisionCount  int
-	mux                *http.ServeMux
+	namespace             string
+	tunables              atomic.Pointer[Tunables]
+	defaultProfile        *Profile
+	profiles              map[string]*Profile
+	valuesAllowlist       values.Allowlist
+	credentialKeys        []string
+	releaseWaitTimeout    time.Duration
+	releaseRetention      time.Duration
+	historyRetention      time.Duration
+	claimReadyTimeout     time.Duration
+	maxClaimReadyTimeout  time.Duration
+	maxSuspendDuration    time.Duration
+	maxStartAtHorizon     time.Duration
+	workingHours          *controller.WorkingHours
+	notifier              notify.Notifier
+	auditSink             audit.Sink
+	authenticators        []Authenticator
+	authRoutes            map[string]bool
+	adminSubjects         map[string]bool
+	idempotencyKeyTTL     time.Duration
+	maxActiveClaims       int
+	poolReserve           int
+	poolMaxIdleAge        time.Duration
+	provisioningMode      ProvisioningMode
+	poolReplenishEvery    time.Duration
+	claimQueue            *claimQueue
+	claimChanges          *claimChanges
+	namespacePerClaim     bool
+	namespaces            []string
+	tenants               map[string]*Tenant
+	clusters              *cluster.Registry
+	helmReleases          *helmrelease.Client
+	apiDocs               bool
+	accessLog             bool
+	accessLogProbes       bool
+	maxRequestBodyBytes   int64
+	cors                  *corsPolicy
+	claimRateLimiter      *rateLimiter
+	client                client.Client
+	apiReader             client.Reader
+	claimLifetime         prometheus.ObserverVec
+	claimTotalTTL         prometheus.ObserverVec
+	claimIdleDuration     prometheus.ObserverVec
+	claimUsageDuration    prometheus.ObserverVec
+	rerenderRatePerMinute int
+	rerenderRunning       atomic.Bool
+	ctx                   atomic.Pointer[context.Context]
+	preProvisionCount     atomic.Int64
+	poolTrigger           chan struct{}
+	mux                   *http.ServeMux
+	logger                logr.Logger
+	readinessChecks       map[string]healthz.Checker
+	renderCheckEvery      time.Duration
+	renderCheck           atomic.Pointer[renderCheckResult]
+	readyDurationAverage  atomic.Int64
+	drainMu               sync.RWMutex
+	draining              bool
+	claimRequests         sync.WaitGroup
+	drainCtx              context.Context
+	stopDrain             context.CancelFunc
 }
 
 type claimRequest struct {
-	TTL string `json:"ttl"`
+	TTL                string         `json:"ttl"`
+	ExpiresAt          string         `json:"expiresAt,omitempty"`
+	Profile            string         `json:"profile,omitempty"`
+	Namespace          string         `json:"namespace,omitempty"`
+	Cluster            string         `json:"cluster,omitempty"`
+	StartAt            string         `json:"startAt,omitempty"`
+	Team               string         `json:"team,omitempty"`
+	Priority           string         `json:"priority,omitempty"`
+	IdempotencyKey     string         `json:"idempotencyKey,omitempty"`
+	ReadyTimeout       string         `json:"readyTimeout,omitempty"`
+	MinReady           string         `json:"minReady,omitempty"`
+	Metadata           *ClaimMetadata `json:"metadata,omitempty"`
+	Values             map[string]any `json:"values,omitempty"`
+	WorkingHoursExempt bool           `json:"workingHoursExempt,omitempty"`
 }
 
 func NewServer(cfg Config) *Server {
-	maxTTL := cfg.MaxTTL
-	if maxTTL <= 0 {
-		maxTTL = cfg.DefaultTTL
+	tunables := Tunables{
+		DefaultTTL:        cfg.DefaultTTL,
+		MaxTTL:            cfg.MaxTTL,
+		PreProvisionCount: cfg.PreProvisionCount,
+		Quotas:            cfg.Quotas,
+	}.normalized()
+	maxTTL := tunables.MaxTTL
+
+	s := &Server{
+		namespace:             cfg.Namespace,
+		valuesAllowlist:       cfg.ValuesAllowlist,
+		credentialKeys:        cfg.CredentialKeys,
+		releaseWaitTimeout:    cfg.ReleaseWaitTimeout,
+		releaseRetention:      cfg.ReleaseRetention,
+		historyRetention:      cfg.ClaimHistoryRetention,
+		claimReadyTimeout:     cfg.ClaimReadyTimeout,
+		maxClaimReadyTimeout:  max(cfg.MaxClaimReadyTimeout, cfg.ClaimReadyTimeout),
+		maxSuspendDuration:    cfg.MaxSuspendDuration,
+		maxStartAtHorizon:     cfg.MaxStartAtHorizon,
+		workingHours:          cfg.WorkingHours,
+		notifier:              cfg.Notifier,
+		auditSink:             cfg.AuditSink,
+		authenticators:        cfg.Authenticators,
+		authRoutes:            map[string]bool{},
+		adminSubjects:         map[string]bool{},
+		idempotencyKeyTTL:     cfg.IdempotencyKeyTTL,
+		maxActiveClaims:       cfg.MaxActiveClaims,
+		poolReserve:           max(0, cfg.PoolReserve),
+		poolMaxIdleAge:        cfg.PoolMaxIdleAge,
+		provisioningMode:      cfg.ProvisioningMode,
+		poolReplenishEvery:    cfg.PoolReplenishEvery,
+		claimQueue:            newClaimQueue(cfg.ClaimQueueTimeout),
+		claimChanges:          newClaimChanges(),
+		namespacePerClaim:     cfg.NamespacePerClaim,
+		namespaces:            []string{cfg.Namespace},
+		tenants:               map[string]*Tenant{},
+		clusters:              cfg.Clusters,
+		helmReleases:          cfg.HelmReleases,
+		apiDocs:               cfg.APIDocs,
+		accessLog:             cfg.AccessLog,
+		accessLogProbes:       cfg.AccessLogProbes,
+		maxRequestBodyBytes:   cfg.MaxRequestBodyBytes,
+		cors:                  newCORSPolicy(cfg.CORSAllowedOrigins, cfg.CORSAllowedMethods, cfg.CORSAllowedHeaders),
+		claimRateLimiter:      newRateLimiter(cfg.ClaimRatePerMinute, cfg.ClaimRateBurst),
+		client:                cfg.Client,
+		apiReader:             cfg.APIReader,
+		rerenderRatePerMinute: cfg.RerenderRatePerMinute,
+		claimLifetime:         newClaimLifetimeDurationHistogram(cfg.DefaultTTL),
+		claimTotalTTL:         newClaimTotalDurationHistogram(maxTTL),
+		claimIdleDuration:     newClaimIdleDurationHistogram(maxTTL),
+		claimUsageDuration:    newClaimUsageDurationHistogram(maxTTL),
+		poolTrigger:           make(chan struct{}, 1),
+		mux:                   http.NewServeMux(),
+		logger:                cfg.Logger,
+		renderCheckEvery:      cfg.RenderCheckEvery,
+	}
+
+	if s.poolReplenishEvery <= 0 {
+		s.poolReplenishEvery = defaultPoolReplenishInterval
+	}
+	if s.releaseRetention <= 0 {
+		s.releaseRetention = defaultReleaseRetention
+	}
+	if s.maxSuspendDuration <= 0 {
+		s.maxSuspendDuration = defaultMaxSuspendDuration
+	}
+	if s.maxStartAtHorizon <= 0 {
+		s.maxStartAtHorizon = defaultMaxStartAtHorizon
+	}
+	if s.rerenderRatePerMinute <= 0 {
+		s.rerenderRatePerMinute = defaultRerenderRatePerMinute
+	}
+	s.tunables.Store(&tunables)
+	if s.provisioningMode != ProvisioningModeOnDemand {
+		s.preProvisionCount.Store(int64(tunables.PreProvisionCount))
+	}
+	poolTargetGauge.Set(float64(s.poolTarget()))
+	for _, namespace := range cfg.WatchNamespaces {
+		if namespace != "" && !slices.Contains(s.namespaces, namespace) {
+			s.namespaces = append(s.namespaces, namespace)
+		}
+	}
+	for i := range cfg.Tenants {
+		tenant := cfg.Tenants[i]
+		s.tenants[tenant.Name] = &tenant
+	}
+	for _, route := range cfg.AuthRoutes {
+		s.authRoutes[route] = true
 	}
-	if maxTTL < cfg.DefaultTTL {
-		maxTTL = cfg.DefaultTTL
+	for _, subject := range cfg.AdminSubjects {
+		s.adminSubjects[subject] = true
 	}
 
-	s := &Server{
-		namespace:          cfg.Namespace,
-		defaultTTL:         cfg.DefaultTTL,
-		maxTTL:             maxTTL,
-		templatePath:       cfg.TemplatePath,
-		valuesProvider:     cfg.ValuesProvider,
-		client:             cfg.Client,
-		claimLifetime:      newClaimLifetimeDurationHistogram(cfg.DefaultTTL),
-		claimTotalTTL:      newClaimTotalDurationHistogram(maxTTL),
-		claimIdleDuration:  newClaimIdleDurationHistogram(maxTTL),
-		claimUsageDuration: newClaimUsageDurationHistogram(maxTTL),
-		preProvisionCount:  max(0, cfg.PreProvisionCount),
-		mux:                http.NewServeMux(),
+	s.defaultProfile = &Profile{
+		Name:            DefaultProfileName,
+		Backend:         cfg.Backend,
+		Renderer:        cfg.Renderer,
+		ValuesProvider:  cfg.ValuesProvider,
+		SchemaValidator: cfg.SchemaValidator,
+	}
+	s.profiles = map[string]*Profile{DefaultProfileName: s.defaultProfile}
+	for i := range cfg.Profiles {
+		profile := cfg.Profiles[i]
+		s.profiles[profile.Name] = &profile
+		if profile.Name == DefaultProfileName {
+			s.defaultProfile = s.profiles[profile.Name]
+		}
 	}
+
+	s.drainCtx, s.stopDrain = context.WithCancel(context.Background())
+	s.readinessChecks = map[string]healthz.Checker{"values": s.checkValues, "draining": s.checkDraining}
+	if s.renderCheckEvery > 0 {
+		s.readinessChecks["render"] = s.checkRender
+	}
+	for name, check := range cfg.ReadinessChecks {
+		s.readinessChecks[name] = check
+	}
+
 	s.routes()
 	return s
 }
 
 func (s *Server) Start(ctx context.Context) error {
-	if s.valuesProvider == nil {
-		return nil
+	s.ctx.Store(&ctx)
+	for _, name := range s.profileNames() {
+		if err := s.profiles[name].start(ctx); err != nil {
+			return err
+		}
 	}
-	if err := s.valuesProvider.Start(ctx); err != nil {
+	if err := s.validateProfiles(); err != nil {
 		return err
 	}
+	if s.renderCheckEvery > 0 {
+		s.renderCheck.Store(&renderCheckResult{})
+		go s.runRenderCheck(ctx)
+	}
 
-	if s.preProvisionCount <= 0 {
+	if s.provisioningMode == ProvisioningModeOnDemand {
 		return nil
 	}
 
-	go func() {
-		timer := time.NewTimer(0)
-		defer timer.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-timer.C:
-				if err := s.ensurePreProvisionedClaims(ctx); err != nil {
-					log.Printf("failed to ensure pre-provisioned claims: %v", err)
-				}
-				timer.Reset(15 * time.Second)
-			}
-		}
-	}()
+	go s.runPoolReplenisher(ctx)
 
 	return nil
 }
 
 func (s *Server) Handler() http.Handler {
-	return s.mux
+	return withRequestID(s.accessLogged(s.instrumented(s.withCORS(s.recovered(s.limitedBody(s.mux))))))
 }
 
 func (s *Server) routes() {
-	s.mux.HandleFunc("/claim", s.handleClaim)
-	s.mux.HandleFunc("/release/{id}", s.handleRelease)
-	s.mux.HandleFunc("/renew/{id}", s.handleRenew)
-	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-	s.mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
+	s.versionedRoutes()
+	s.mux.HandleFunc("/openapi.json", s.openAPIHandler())
+	if s.apiDocs {
+		s.mux.HandleFunc("/docs", handleAPIDocs)
+	}
+	s.probe("/healthz", map[string]healthz.Checker{"ping": healthz.Ping})
+	s.probe("/readyz", s.readinessChecks)
 }
 
 func (s *Server) handleClaim(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	ttl, err := s.ttlFromRequest(r)
+	req, err := decodeClaimRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeProblem(w, r, err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+	s.serveClaim(w, r, req, "")
+}
+
+func (s *Server) serveClaim(w http.ResponseWriter, r *http.Request, req claimRequest, clonedFrom string) {
+	if !s.beginClaimRequest() {
+		writeDrainingProblem(w, r)
+		return
+	}
+	defer s.endClaimRequest()
+
+	if err := s.validateValuesOverrides(req.Values); err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+
+	tenant, err := s.resolveTenant(r)
+	if err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	tenantName := ""
+	if tenant != nil {
+		tenantName = tenant.Name
+		if req.Profile == "" {
+			req.Profile = tenant.Profile
+		}
+		if tenant.Namespace != "" {
+			if req.Namespace != "" && req.Namespace != tenant.Namespace {
+				writeProblem(w, r, http.StatusForbidden, problemForbidden, fmt.Sprintf("tenant %q can only create claims in namespace %q", tenant.Name, tenant.Namespace))
+				return
+			}
+			req.Namespace = tenant.Namespace
+		}
+	}
+
+	profile, err := s.resolveProfile(req.Profile)
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	if err := validateProfileOverrides(profile, req.Values); err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	namespace, err := s.resolveClaimNamespace(req.Namespace, profile)
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+
+	ttl, err := s.ttlFromClaimRequest(req, s.ttlPolicyFor(profile, tenant))
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	startAt, err := parseStartAt(req.StartAt)
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	if time.Until(startAt) > s.maxStartAtHorizon {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, fmt.Sprintf("startAt must not be more than %s in the future", s.maxStartAtHorizon))
+		return
+	}
+	if req.WorkingHoursExempt && !s.isAdmin(r) {
+		writeProblem(w, r, http.StatusForbidden, problemForbidden, "only admin subjects can exempt a claim from working hours")
+		return
+	}
+	activatesAt := time.Now()
+	if !startAt.IsZero() {
+		activatesAt = startAt
+	}
+	if s.workingHours != nil && !req.WorkingHoursExempt && !s.workingHours.Contains(activatesAt) {
+		claimsRejectedTotal.WithLabelValues("working_hours").Inc()
+		writeProblem(w, r, http.StatusConflict, problemOutsideWorkingHours, "claims cannot be active outside working hours")
+		return
+	}
+
+	clusterName, err := s.resolveClaimCluster(r.Context(), req.Cluster, profile)
+	if err != nil {
+		if errors.Is(err, cluster.ErrUnknownCluster) {
+			writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+			return
+		}
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to resolve target cluster")
+		return
+	}
+	if clusterName != "" && profile.Type == vcluster.ProfileType {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "vcluster profiles cannot target a remote cluster")
+		return
+	}
+	if profile.Backend == helmrelease.BackendHelmRelease {
+		if clusterName != "" {
+			writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "helm-release profiles cannot target a remote cluster")
+			return
+		}
+		if !startAt.IsZero() {
+			writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "helm-release profiles do not support startAt")
+			return
+		}
+	}
 
-	claim, claimID, expiresAt, isPreProvisioned, err := s.acquireClaim(ctx, ttl)
+	if errs := validation.IsValidLabelValue(req.Team); len(errs) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, fmt.Sprintf("invalid team: %s", strings.Join(errs, "; ")))
+		return
+	}
+
+	if err := req.Metadata.validate(); err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+
+	priority, err := parsePriority(req.Priority)
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	if priority == PriorityHigh && !s.isAdmin(r) {
+		writeProblem(w, r, http.StatusForbidden, problemForbidden, "only admin subjects can request high priority")
+		return
+	}
+	readyTimeout, err := s.readyTimeoutFromClaimRequest(req)
 	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	minReady, err := controller.ParseMinReady(req.MinReady)
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+
+	idempotencyKey := idempotencyKeyFromRequest(r, req)
+	if idempotencyKey != "" {
+		existing, err := s.findIdempotentClaim(r.Context(), idempotencyKey)
+		if err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to look up idempotency key")
+			return
+		}
+		if existing != nil {
+			s.replayIdempotentClaim(w, r, existing, readyTimeout)
+			return
+		}
+	}
+
+	if err := s.checkQuota(r.Context(), req.Team, tenant, ttl); err != nil {
+		var quotaErr *quotaExceededError
+		if errors.As(err, &quotaErr) {
+			claimsRejectedTotal.WithLabelValues("quota").Inc()
+			if quotaErr.unsatisfiable {
+				writeErrorProblem(w, r, http.StatusUnprocessableEntity, quotaErr, problemQuotaExceeded)
+				return
+			}
+			// Claims without a known expiry give no hint of when quota frees up.
+			if quotaErr.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(quotaErr.retryAfter)))
+			}
+			writeErrorProblem(w, r, http.StatusTooManyRequests, quotaErr, problemQuotaExceeded)
+			return
+		}
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to check quota")
+		return
+	}
+
+	hasCapacity, retryAfter, err := s.checkCapacity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to check capacity")
+		return
+	}
+	if !hasCapacity && priority == PriorityHigh {
+		preempted, err := s.preemptExpiredClaim(r.Context())
+		if err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to preempt claim")
+			return
+		}
+		hasCapacity = preempted
+	}
+	if !hasCapacity && s.claimQueue == nil {
+		claimsRejectedTotal.WithLabelValues("capacity").Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+		writeProblem(w, r, http.StatusTooManyRequests, problemCapacityReached, "maximum number of active claims reached")
+		return
+	}
+
+	opts := claimOptions{namespace: namespace, cluster: clusterName, tenant: tenantName, profile: profile, overrides: req.Values, team: req.Team, idempotencyKey: idempotencyKey, metadata: req.Metadata, priority: priority, clonedFrom: clonedFrom, startAt: startAt, minReady: minReady, workingHoursExempt: req.WorkingHoursExempt}
+	var (
+		claim            *corev1.ConfigMap
+		claimID          string
+		expiresAt        time.Time
+		isPreProvisioned bool
+	)
+	acquire := func() error {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		var err error
+		claim, claimID, expiresAt, isPreProvisioned, err = s.acquireClaim(ctx, ttl, opts)
+		return err
+	}
+
+	if hasCapacity && s.claimQueue.pending() == 0 {
+		err = acquire()
+	}
+	if s.claimQueue != nil && claim == nil && (err == nil || errors.Is(err, errPoolExhausted)) {
+		extendWriteDeadline(w, s.claimQueue.timeout)
+		queueCtx, cancelQueue := s.drainAware(r.Context())
+		err = s.claimQueue.wait(queueCtx, func() (bool, error) {
+			available, _, err := s.checkCapacity(r.Context())
+			if err != nil || !available {
+				return err != nil, err
+			}
+			if err := acquire(); !errors.Is(err, errPoolExhausted) {
+				return true, err
+			}
+			return false, nil
+		})
+		drained := errors.Is(context.Cause(queueCtx), errDrained)
+		cancelQueue()
+		if drained && claim == nil {
+			writeDrainingProblem(w, r)
+			return
+		}
+		if errors.Is(err, errClaimQueueTimeout) {
+			if available, _, _ := s.checkCapacity(r.Context()); !available {
+				claimsRejectedTotal.WithLabelValues("capacity").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+				writeProblem(w, r, http.StatusTooManyRequests, problemCapacityReached, "maximum number of active claims reached")
+				return
+			}
+			err = errPoolExhausted
+		}
+	}
+	if err != nil && idempotencyKey != "" && apierrors.IsAlreadyExists(err) {
+		existing, lookupErr := s.idempotentClaim(r.Context(), namespace, idempotencyKey)
+		if lookupErr != nil {
+			writeProblem(w, r, http.StatusConflict, problemConflict, "the claim of this idempotency key is being deleted, retry later")
+			return
+		}
+		s.replayIdempotentClaim(w, r, existing, readyTimeout)
+		return
+	}
+	if err != nil {
+		var schemaErr *template.SchemaValidationError
+		if errors.As(err, &schemaErr) {
+			writeSchemaProblem(w, r, schemaErr)
+			return
+		}
+		if errors.Is(err, errNotPoolable) {
+			writeErrorProblem(w, r, http.StatusUnprocessableEntity, err, problemNotPoolable)
+			return
+		}
+		if errors.Is(err, errPoolExhausted) {
+			claimsRejectedTotal.WithLabelValues("pool_exhausted").Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(s.poolRetryAfter())))
+			writeProblem(w, r, http.StatusServiceUnavailable, problemPoolExhausted, "no pre-provisioned claim available, retry later")
+			return
+		}
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			http.Error(w, "upstream timeout while creating claim", http.StatusGatewayTimeout)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(apiTimeoutRetryAfter)))
+			writeProblem(w, r, http.StatusGatewayTimeout, problemTimeout, "upstream timeout while creating claim")
 			return
 		}
-		http.Error(w, "failed to create claim", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to create claim")
+		return
+	}
+
+	s.notify(r.Context(), notify.EventClaimCreated, claim, "")
+
+	if !startAt.IsZero() {
+		setAccessLogClaimID(r.Context(), claimID)
+		writeJSON(w, http.StatusAccepted, scheduledClaimResponse{
+			Status:        controller.ClaimStatusScheduled,
+			ID:            claimID,
+			StartAt:       startAt.Format(time.RFC3339),
+			ExpiresAt:     expiresAt.Format(time.RFC3339),
+			Profile:       profile.Name,
+			StatusPath:    apiV1.path("/claims/%s", claimID),
+			ReleasePath:   apiV1.path("/release/%s", claimID),
+			ReleaseMethod: http.MethodPost,
+		})
 		return
 	}
 
 	readyStart := time.Now()
-	if err := s.waitForClaimReady(r.Context(), claim.Name, 120*time.Second); err != nil {
+	extendWriteDeadline(w, readyTimeout)
+	readyClaim, drained, err := s.waitForClaimReadyOrDrain(r.Context(), claim, readyTimeout)
+	if drained {
+		writeClaimTicket(w, r, claimID, expiresAt, profile.Name)
+		return
+	}
+	if errors.Is(err, errClaimFailed) {
+		writeProblem(w, r, http.StatusBadGateway, problemClaimFailed, err.Error())
+		return
+	}
+	if err != nil {
+		s.notify(context.WithoutCancel(r.Context()), notify.EventClaimFailed, claim, err.Error())
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 			timedOutClaimsTotal.Inc()
-			http.Error(w, "timed out waiting for claim resources to become ready", http.StatusGatewayTimeout)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(s.readyRetryAfter())))
+			writeProblem(w, r, http.StatusGatewayTimeout, problemTimeout, "timed out waiting for claim resources to become ready")
 			return
 		}
-		http.Error(w, "failed while waiting for claim readiness", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed while waiting for claim readiness")
 		return
 	}
+	s.observeReadyDuration(time.Since(readyStart))
 	readyDurationSeconds := time.Since(readyStart).Seconds()
-	claimReadyDurationSeconds.Observe(readyDurationSeconds)
-	log.Printf("claim became ready: id=%s name=%s ready_duration_seconds=%.6f", claimID, claim.Name, readyDurationSeconds)
+	claimReadyDurationSeconds.WithLabelValues(profile.Name).Observe(readyDurationSeconds)
+	s.requestLogger(r.Context()).Info("claim became ready", "id", claimID, "claim", claim.Name, "readyDurationSeconds", readyDurationSeconds)
+	if isPreProvisioned {
+		s.notify(r.Context(), notify.EventClaimReady, readyClaim, readyClaim.Data[controller.ClaimStatusMessageDataKey])
+	}
 
+	s.writeClaimResponse(w, r, http.StatusCreated, readyClaim, claimID, expiresAt, isPreProvisioned, profile.Name)
+
+	if isPreProvisioned {
+		s.triggerPoolReconcile()
+	}
+}
+
+func (s *Server) writeClaimResponse(w http.ResponseWriter, r *http.Request, status int, readyClaim *corev1.ConfigMap, claimID string, expiresAt time.Time, isPreProvisioned bool, profileName string) {
+	setAccessLogClaimID(r.Context(), claimID)
 	returnValues := map[string]string{}
-	if raw := strings.TrimSpace(claim.Data[controller.ReturnValuesDataKey]); raw != "" {
+	if raw := strings.TrimSpace(readyClaim.Data[controller.ReturnValuesDataKey]); raw != "" {
 		_ = json.Unmarshal([]byte(raw), &returnValues)
 	}
 
-	body := make(map[string]any)
-	body["status"] = "ok"
-	body["id"] = claimID
-	body["expiresAt"] = expiresAt.Format(time.RFC3339)
-	body["data"] = returnValues
-	body["releasePath"] = fmt.Sprintf("/release/%s", claimID)
-	body["releaseMethod"] = http.MethodPost
-	body["renewPath"] = fmt.Sprintf("/renew/%s", claimID)
-	body["renewMethod"] = http.MethodPost
-	body["preProvisioned"] = isPreProvisioned
-
-	writeJSON(w, http.StatusCreated, body)
-
-	if isPreProvisioned {
-		go func() {
-			if err := s.ensurePreProvisionedClaims(context.Background()); err != nil {
-				log.Printf("failed to replenish pre-provisioned claims: %v", err)
-			}
-		}()
+	body := claimResponse{
+		Status:         "ok",
+		ID:             claimID,
+		ExpiresAt:      expiresAt.Format(time.RFC3339),
+		Profile:        profileName,
+		PreProvisioned: isPreProvisioned,
+		Data:           returnValues,
+		Namespace:      readyClaim.Annotations[controller.ClaimNamespaceAnnotationKey],
+		Cluster:        readyClaim.Annotations[controller.ClusterAnnotationKey],
+		ClonedFrom:     readyClaim.Annotations[controller.ClonedFromAnnotationKey],
+		Metadata:       claimMetadataFrom(readyClaim),
+		ReleasePath:    apiV1.path("/release/%s", claimID),
+		ReleaseMethod:  http.MethodPost,
+		RenewPath:      apiV1.path("/renew/%s", claimID),
+		RenewMethod:    http.MethodPost,
+	}
+	if readyClaim.Annotations[controller.ProfileTypeAnnotationKey] == vcluster.ProfileType {
+		kubeconfig, err := s.issueVClusterKubeconfig(r.Context(), readyClaim, expiresAt)
+		if err != nil {
+			s.requestLogger(r.Context()).Error(err, "failed to issue vcluster kubeconfig", "claim", readyClaim.Name)
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to issue vcluster kubeconfig")
+			return
+		}
+		body.Kubeconfig = kubeconfig
+	}
+	if len(s.credentialKeys) > 0 {
+		credentials, err := s.readClaimCredentials(r.Context(), readyClaim)
+		if err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to load claim credentials")
+			return
+		}
+		body.Credentials = credentials
+		body.CredentialsSecret = credentialsSecretName(readyClaim.Name)
 	}
+
+	writeJSON(w, status, body)
 }
 
 func (s *Server) handleRelease(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	claimID := strings.TrimSpace(r.PathValue("id"))
 	if claimID == "" {
-		http.Error(w, "id is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "id is required")
+		return
+	}
+
+	waitForCleanup, err := parseBoolParam(r, "wait")
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+	retain, err := parseBoolParam(r, "retain")
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	claims, err := s.findManagedClaimsByID(ctx, claimID)
+	claims, err := s.findOwnedClaims(ctx, r, claimID)
 	if err != nil {
-		if errors.Is(err, errClaimNotFound) {
-			http.Error(w, "claim not found", http.StatusNotFound)
-			return
-		}
-		if errors.Is(err, errClaimNotManaged) {
-			http.Error(w, "claim not managed by controller", http.StatusForbidden)
-			return
+		writeClaimLookupProblem(w, r, err)
+		return
+	}
+
+	if retain {
+		var retainUntil time.Time
+		for _, claim := range claims {
+			if retainUntil, err = s.softReleaseClaim(ctx, claim); err != nil {
+				writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to release claim")
+				return
+			}
+			s.notify(r.Context(), notify.EventClaimReleased, &claim, fmt.Sprintf("resources retained until %s", retainUntil.Format(time.RFC3339)))
 		}
-		http.Error(w, "failed to load claim", http.StatusInternalServerError)
+		claimsReleasedTotal.Add(float64(len(claims)))
+		writeJSON(w, http.StatusAccepted, releaseResponse{
+			Status:        "released",
+			RetainedUntil: retainUntil.Format(time.RFC3339),
+		})
 		return
 	}
 
 	for _, claim := range claims {
-		if err := s.client.Delete(ctx, claim.DeepCopy()); err != nil {
+		if err := s.client.Delete(ctx, claim.DeepCopy(), foregroundPropagation()); err != nil {
 			if apierrors.IsNotFound(err) {
-				http.Error(w, "claim not found", http.StatusNotFound)
+				writeProblem(w, r, http.StatusNotFound, problemClaimNotFound, "claim not found")
 				return
 			}
-			http.Error(w, "failed to delete claim", http.StatusInternalServerError)
+			writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to delete claim")
 			return
 		}
+		s.recordTombstone(ctx, &claim, controller.EndReasonReleased)
+
+		s.notify(r.Context(), notify.EventClaimReleased, &claim, "")
+		s.claimQueue.signal()
 
+		profile := s.profileMetricLabel(&claim)
 		if totalActualSeconds, ok := claimTotalActualDurationSeconds(claim, time.Now().UTC()); ok {
-			s.claimLifetime.Observe(totalActualSeconds)
+			s.claimLifetime.WithLabelValues(profile).Observe(totalActualSeconds)
 		}
 		if idleSeconds, ok := claimIdleDurationSeconds(claim); ok {
-			s.claimIdleDuration.Observe(idleSeconds)
+			s.claimIdleDuration.WithLabelValues(profile).Observe(idleSeconds)
 		}
 		if usageActualSeconds, ok := claimUsageActualDurationSeconds(claim, time.Now().UTC()); ok {
-			s.claimUsageDuration.Observe(usageActualSeconds)
+			s.claimUsageDuration.WithLabelValues(profile).Observe(usageActualSeconds)
 		}
 		if totalDurationSeconds, ok := claimExpectedLifetimeSeconds(claim); ok {
-			s.claimTotalTTL.Observe(totalDurationSeconds)
+			s.claimTotalTTL.WithLabelValues(profile).Observe(totalDurationSeconds)
 		}
 		if ratio, ok := claimLifetimeRatio(claim); ok {
-			claimLifetimeExpectedRatio.Observe(ratio)
+			claimLifetimeExpectedRatio.WithLabelValues(profile).Observe(ratio)
 		}
 		if usageRatio, ok := claimUsageRatio(claim); ok {
-			claimUsageExpectedRatio.Observe(usageRatio)
+			claimUsageExpectedRatio.WithLabelValues(profile).Observe(usageRatio)
 		}
 	}
 	claimsReleasedTotal.Add(float64(len(claims)))
+
+	if waitForCleanup {
+		if err := s.waitForClaimsDeleted(r.Context(), claims, s.releaseWaitTimeout); err != nil {
+			writeJSON(w, http.StatusAccepted, releaseResponse{
+				Status:  controller.ClaimStatusDeleting,
+				Message: "claim resources are still being deleted",
+			})
+			return
+		}
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (s *Server) handleRenew(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	claimID := strings.TrimSpace(r.PathValue("id"))
 	if claimID == "" {
-		http.Error(w, "id is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "id is required")
 		return
 	}
 
-	ttl, err := s.ttlFromRequest(r)
+	req, err := decodeClaimRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeProblem(w, r, err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	claims, err := s.findManagedClaimsByID(ctx, claimID)
+	claims, err := s.findOwnedClaims(ctx, r, claimID)
 	if err != nil {
-		if errors.Is(err, errClaimNotFound) {
-			http.Error(w, "claim not found", http.StatusNotFound)
-			return
-		}
-		if errors.Is(err, errClaimNotManaged) {
-			http.Error(w, "claim not managed by controller", http.StatusForbidden)
-			return
-		}
-		http.Error(w, "failed to load claim", http.StatusInternalServerError)
+		writeClaimLookupProblem(w, r, err)
+		return
+	}
+
+	ttl, err := s.ttlFromClaimRequest(req, s.ttlPolicyForClaim(&claims[0]))
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
 		return
 	}
 
 	updatedClaim, err := s.renewClaim(ctx, claims[0], ttl)
 	if err != nil {
-		if errors.Is(err, errMaxTTLReached) {
-			http.Error(w, err.Error(), http.StatusConflict)
+		if errors.Is(err, errMaxTTLReached) || errors.Is(err, errClaimReleased) {
+			writeErrorProblem(w, r, http.StatusConflict, err, problemConflict)
 			return
 		}
-		http.Error(w, "failed to renew claim", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to renew claim")
 		return
 	}
 
-	body := map[string]any{
-		"status":      "ok",
-		"id":          claimID,
-		"expiresAt":   updatedClaim.Annotations[controller.ExpiresAtAnnotationKey],
-		"renewPath":   fmt.Sprintf("/renew/%s", claimID),
-		"renewMethod": http.MethodPost,
-	}
+	s.notify(r.Context(), notify.EventClaimRenewed, updatedClaim, "")
 
-	writeJSON(w, http.StatusOK, body)
+	writeJSON(w, http.StatusOK, renewResponse{
+		Status:      "ok",
+		ID:          claimID,
+		ExpiresAt:   updatedClaim.Annotations[controller.ExpiresAtAnnotationKey],
+		RenewPath:   apiV1.path("/renew/%s", claimID),
+		RenewMethod: http.MethodPost,
+	})
 }
 
+const apiFieldOwner = "claim-controller-api"
+
 var errClaimNotFound = errors.New("claim not found")
 var errClaimNotManaged = errors.New("claim not managed by controller")
 var errMaxTTLReached = errors.New("max ttl already reached")
 
+func (s *Server) notify(ctx context.Context, eventType string, claim *corev1.ConfigMap, message string) {
+	if s.notifier == nil {
+		return
+	}
+	s.notifier.Notify(ctx, controller.ClaimEvent(eventType, claim, message))
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/template"
+)
+
+const testNamespace = "claims"
+
+func newTestServer(t *testing.T, cfg Config, objects ...client.Object) *Server {
+	t.Helper()
+	kubeClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+	cfg.Namespace = testNamespace
+	cfg.Client = kubeClient
+	cfg.APIReader = kubeClient
+	cfg.Logger = logr.Discard()
+	if cfg.DefaultTTL == 0 {
+		cfg.DefaultTTL = time.Hour
+	}
+	if cfg.MaxTTL == 0 {
+		cfg.MaxTTL = 4 * time.Hour
+	}
+	if cfg.Renderer == nil {
+		cfg.Renderer = testRenderer{}
+	}
+	if cfg.ValuesProvider == nil {
+		cfg.ValuesProvider = testValuesProvider{}
+	}
+	return NewServer(cfg)
+}
+
+type testRenderer struct{}
+
+func (testRenderer) Render(_ []byte, claim template.ClaimContext) (template.ResourceTemplate, error) {
+	resource := template.RenderedResource{APIVersion: "v1", Kind: "ConfigMap", Name: claim.Name + "-data", Namespace: claim.Namespace}
+	object, err := json.Marshal(map[string]any{
+		"apiVersion": resource.APIVersion,
+		"kind":       resource.Kind,
+		"metadata":   map[string]any{"name": resource.Name, "namespace": resource.Namespace},
+	})
+	if err != nil {
+		return template.ResourceTemplate{}, err
+	}
+	return template.ResourceTemplate{Resources: []template.RenderedResource{resource}, RenderedObjects: []json.RawMessage{object}}, nil
+}
+func (testRenderer) Description() string      { return "test" }
+func (testRenderer) Version() (string, error) { return "v1", nil }
+
+type testValuesProvider struct{}
+
+func (testValuesProvider) Start(context.Context) error               { return nil }
+func (testValuesProvider) GetValues() ([]byte, error)                { return []byte("{}\n"), nil }
+func (testValuesProvider) GetOwnerReference() *metav1.OwnerReference { return nil }
+func (testValuesProvider) Description() string                       { return "test" }
+
+func withIdentity(r *http.Request, subject string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityContextKey{}, &Identity{Subject: subject}))
+}
+
+func testClaim(id string, annotations map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claim-" + id,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				controller.ManagedByLabelKey: controller.ManagedByLabelValue,
+				controller.ClaimLabelKeyId:   id,
+			},
+			Annotations: annotations,
+		},
+		Data: map[string]string{},
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+type claimStatus struct {
+	ID             string           `json:"id"`
+	Name           string           `json:"name"`
+	Status         string           `json:"status"`
+	Message        string           `json:"message,omitempty"`
+	Profile        string           `json:"profile,omitempty"`
+	Team           string           `json:"team,omitempty"`
+	Tenant         string           `json:"tenant,omitempty"`
+	Cluster        string           `json:"cluster,omitempty"`
+	TemplateHash   string           `json:"templateHash,omitempty"`
+	TemplateSource string           `json:"templateSource,omitempty"`
+	ValuesHash     string           `json:"valuesHash,omitempty"`
+	ValuesSource   string           `json:"valuesSource,omitempty"`
+	Owner          string           `json:"owner,omitempty"`
+	RequestID      string           `json:"requestId,omitempty"`
+	CreatedAt      string           `json:"createdAt"`
+	ClaimedAt      string           `json:"claimedAt,omitempty"`
+	StartAt        string           `json:"startAt,omitempty"`
+	ExpiresAt      string           `json:"expiresAt,omitempty"`
+	ReleasedAt     string           `json:"releasedAt,omitempty"`
+	Suspended      bool             `json:"suspended,omitempty"`
+	SuspendedUntil string           `json:"suspendedUntil,omitempty"`
+	PreProvisioned bool             `json:"preProvisioned"`
+	MinReady       string           `json:"minReady,omitempty"`
+	Metadata       *ClaimMetadata   `json:"metadata,omitempty"`
+	Conditions     []claimCondition `json:"conditions,omitempty"`
+	Resources      json.RawMessage  `json:"resources,omitempty"`
+}
+
+type claimCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime"`
+}
+
+func claimConditionsFrom(claim *corev1.ConfigMap) []claimCondition {
+	recorded := controller.ClaimConditions(claim)
+	if len(recorded) == 0 {
+		return nil
+	}
+	conditions := make([]claimCondition, 0, len(recorded))
+	for _, condition := range recorded {
+		conditions = append(conditions, claimCondition{
+			Type:               condition.Type,
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.UTC().Format(time.RFC3339),
+		})
+	}
+	return conditions
+}
+
+func claimStatusFrom(claim *corev1.ConfigMap, withResources bool) claimStatus {
+	status := claimStatus{
+		ID:             claim.Labels[controller.ClaimLabelKeyId],
+		Name:           claim.Name,
+		Status:         claim.Data[controller.ClaimStatusDataKey],
+		Message:        claim.Data[controller.ClaimStatusMessageDataKey],
+		Profile:        claim.Labels[controller.ProfileLabelKey],
+		Team:           claim.Labels[controller.TeamLabelKey],
+		Tenant:         claim.Labels[controller.TenantLabelKey],
+		Cluster:        claim.Annotations[controller.ClusterAnnotationKey],
+		TemplateHash:   claim.Annotations[controller.TemplateHashAnnotationKey],
+		TemplateSource: claim.Annotations[controller.TemplateSourceAnnotationKey],
+		ValuesHash:     claim.Annotations[controller.ValuesHashAnnotationKey],
+		ValuesSource:   claim.Annotations[controller.ValuesSourceAnnotationKey],
+		Owner:          claim.Annotations[controller.OwnerAnnotationKey],
+		RequestID:      claim.Annotations[controller.RequestIDAnnotationKey],
+		CreatedAt:      claim.CreationTimestamp.UTC().Format(time.RFC3339),
+		ClaimedAt:      claim.Annotations[controller.ClaimedAtAnnotationKey],
+		StartAt:        claim.Annotations[controller.StartAtAnnotationKey],
+		ExpiresAt:      claim.Annotations[controller.ExpiresAtAnnotationKey],
+		ReleasedAt:     claim.Annotations[controller.ReleasedAtAnnotationKey],
+		Suspended:      claim.Annotations[controller.SuspendedAtAnnotationKey] != "",
+		SuspendedUntil: claim.Annotations[controller.SuspendedUntilAnnotationKey],
+		PreProvisioned: strings.EqualFold(claim.Annotations[controller.PreProvisionedAnnotationKey], "true"),
+		MinReady:       claim.Annotations[controller.MinReadyAnnotationKey],
+		Metadata:       claimMetadataFrom(claim),
+		Conditions:     claimConditionsFrom(claim),
+	}
+	if withResources {
+		if raw := strings.TrimSpace(claim.Data[controller.ClaimResourcesStatusDataKey]); raw != "" && json.Valid([]byte(raw)) {
+			status.Resources = json.RawMessage(raw)
+		}
+	}
+	return status
+}
+
+func (s *Server) handleListClaims(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	includePool := strings.EqualFold(r.URL.Query().Get("all"), "true")
+
+	tenant, err := s.resolveTenant(r)
+	if err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	selector := client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}
+	if tenant != nil {
+		selector[controller.TenantLabelKey] = tenant.Name
+	}
+
+	claims := &corev1.ConfigMapList{}
+	if err := s.listClaims(r.Context(), claims, selector); err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to list claims")
+		return
+	}
+
+	items := make([]claimStatus, 0, len(claims.Items))
+	for i := range claims.Items {
+		status := claimStatusFrom(&claims.Items[i], false)
+		if status.PreProvisioned && !includePool {
+			continue
+		}
+		items = append(items, status)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt < items[j].CreatedAt
+	})
+
+	writeJSON(w, http.StatusOK, claimListResponse{Items: items})
+}
+
+func (s *Server) handleGetClaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	claimID := strings.TrimSpace(r.PathValue("id"))
+	if claimID == "" {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "id is required")
+		return
+	}
+
+	claims, err := s.findRequestClaims(r.Context(), r, claimID)
+	if err != nil {
+		writeClaimLookupProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, claimStatusFrom(&claims[0], true))
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const defaultMaxSuspendDuration = 24 * time.Hour
+
+func (s *Server) handleSuspend(w http.ResponseWriter, r *http.Request) {
+	s.handleSuspension(w, r, true)
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.handleSuspension(w, r, false)
+}
+
+func (s *Server) handleSuspension(w http.ResponseWriter, r *http.Request, suspend bool) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	claimID := strings.TrimSpace(r.PathValue("id"))
+	if claimID == "" {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "id is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	claims, err := s.findOwnedClaims(ctx, r, claimID)
+	if err != nil {
+		writeClaimLookupProblem(w, r, err)
+		return
+	}
+	claim := &claims[0]
+	if claim.DeletionTimestamp != nil || claim.Annotations[controller.PreProvisionedAnnotationKey] == "true" || isReleasedClaim(claim) {
+		writeProblem(w, r, http.StatusConflict, problemConflict, "only active claims can be suspended or resumed")
+		return
+	}
+
+	var updated *corev1.ConfigMap
+	if suspend {
+		updated, err = s.suspendClaim(ctx, claim)
+	} else {
+		updated, err = s.resumeClaim(ctx, claim)
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to update claim")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, claimStatusFrom(updated, false))
+}
+
+func (s *Server) suspendClaim(ctx context.Context, claim *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	if claim.Annotations[controller.SuspendedAtAnnotationKey] != "" {
+		return claim, nil
+	}
+	now := time.Now().UTC()
+	updated := claim.DeepCopy()
+	updated.Annotations[controller.SuspendedAtAnnotationKey] = now.Format(time.RFC3339)
+	updated.Annotations[controller.SuspendedUntilAnnotationKey] = now.Add(s.maxSuspendDuration).Format(time.RFC3339)
+	if err := s.client.Patch(ctx, updated, client.MergeFrom(claim), client.FieldOwner(apiFieldOwner)); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *Server) resumeClaim(ctx context.Context, claim *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	suspendedAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.SuspendedAtAnnotationKey])
+	if err != nil {
+		return claim, nil
+	}
+	now := time.Now().UTC()
+	if suspendedUntil, err := time.Parse(time.RFC3339, claim.Annotations[controller.SuspendedUntilAnnotationKey]); err == nil && suspendedUntil.Before(now) {
+		now = suspendedUntil
+	}
+	updated := claim.DeepCopy()
+	if expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey]); err == nil {
+		updated.Annotations[controller.ExpiresAtAnnotationKey] = expiresAt.Add(now.Sub(suspendedAt)).UTC().Format(time.RFC3339)
+	}
+	delete(updated.Annotations, controller.SuspendedAtAnnotationKey)
+	delete(updated.Annotations, controller.SuspendedUntilAnnotationKey)
+	delete(updated.Annotations, controller.ExpiringSoonAnnotationKey)
+	if err := s.client.Patch(ctx, updated, client.MergeFrom(claim), client.FieldOwner(apiFieldOwner)); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
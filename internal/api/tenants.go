@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+const TenantHeader = "X-Tenant"
+
+var (
+	errTenantForbidden = errors.New("identity is not allowed to act for this tenant")
+	errTenantRequired  = errors.New("a tenant is required: set the X-Tenant header")
+	errUnknownTenant   = errors.New("unknown tenant")
+)
+
+type Tenant struct {
+	Name       string
+	Namespace  string
+	Profile    string
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	Subjects   []string
+	Quota      *QuotaLimit
+}
+
+func (s *Server) resolveTenant(r *http.Request) (*Tenant, error) {
+	subject := ""
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		subject = identity.Subject
+	}
+
+	if name := strings.TrimSpace(r.Header.Get(TenantHeader)); name != "" {
+		tenant, ok := s.tenants[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", errUnknownTenant, name)
+		}
+		if len(tenant.Subjects) > 0 && !slices.Contains(tenant.Subjects, subject) {
+			return nil, errTenantForbidden
+		}
+		return tenant, nil
+	}
+
+	if subject != "" {
+		for _, tenant := range s.tenants {
+			if slices.Contains(tenant.Subjects, subject) {
+				return tenant, nil
+			}
+		}
+	}
+	if len(s.tenants) > 0 {
+		return nil, errTenantRequired
+	}
+	return nil, nil
+}
+
+func isTenantError(err error) bool {
+	return errors.Is(err, errTenantForbidden) || errors.Is(err, errTenantRequired) || errors.Is(err, errUnknownTenant)
+}
+
+// findRequestClaims returns the claims with the given id visible to the
+// caller's tenant; claims of another tenant are reported as not found.
+func (s *Server) findRequestClaims(ctx context.Context, r *http.Request, claimID string) ([]corev1.ConfigMap, error) {
+	tenant, err := s.resolveTenant(r)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.findManagedClaimsByID(ctx, claimID)
+	if err != nil || tenant == nil {
+		return claims, err
+	}
+	for _, claim := range claims {
+		if claim.Labels[controller.TenantLabelKey] != tenant.Name {
+			return nil, errClaimNotFound
+		}
+	}
+	return claims, nil
+}
+
+func writeClaimLookupProblem(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case isTenantError(err):
+		writeTenantError(w, r, err)
+	case errors.Is(err, errNotClaimOwner):
+		writeErrorProblem(w, r, http.StatusForbidden, err, problemForbidden)
+	case errors.Is(err, errClaimNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemClaimNotFound, "claim not found")
+	case errors.Is(err, errClaimNotManaged):
+		writeProblem(w, r, http.StatusForbidden, problemClaimNotManaged, "claim not managed by controller")
+	default:
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to load claim")
+	}
+}
+
+func writeTenantError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errTenantForbidden) {
+		writeErrorProblem(w, r, http.StatusForbidden, err, problemForbidden)
+		return
+	}
+	writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+}
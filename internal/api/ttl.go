@@ -0,0 +1,45 @@
+package api
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nonot/claim-controller/internal/controller"
+)
+
+type ttlPolicy struct {
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+func (s *Server) ttlPolicyFor(profile *Profile, tenant *Tenant) ttlPolicy {
+	tunables := s.currentTunables()
+	policy := ttlPolicy{defaultTTL: tunables.DefaultTTL, maxTTL: tunables.MaxTTL}
+	if profile != nil {
+		policy = policy.override(profile.DefaultTTL, profile.MaxTTL)
+	}
+	if tenant != nil {
+		policy = policy.override(tenant.DefaultTTL, tenant.MaxTTL)
+	}
+	return policy
+}
+
+func (s *Server) ttlPolicyForClaim(claim *corev1.ConfigMap) ttlPolicy {
+	profile := s.profiles[claim.Labels[controller.ProfileLabelKey]]
+	tenant := s.tenants[claim.Labels[controller.TenantLabelKey]]
+	return s.ttlPolicyFor(profile, tenant)
+}
+
+func (p ttlPolicy) override(defaultTTL, maxTTL time.Duration) ttlPolicy {
+	if defaultTTL > 0 {
+		p.defaultTTL = defaultTTL
+	}
+	if maxTTL > 0 {
+		p.maxTTL = maxTTL
+	}
+	if p.maxTTL < p.defaultTTL {
+		p.maxTTL = p.defaultTTL
+	}
+	return p
+}
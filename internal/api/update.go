@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/template"
+	"github.com/nonot/claim-controller/internal/values"
+)
+
+func (s *Server) handlePatchClaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	claimID := strings.TrimSpace(r.PathValue("id"))
+	if claimID == "" {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "id is required")
+		return
+	}
+
+	req, err := decodeClaimRequest(r)
+	if err != nil {
+		writeDecodeProblem(w, r, err)
+		return
+	}
+	if len(req.Values) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "values is required")
+		return
+	}
+	if err := s.validateValuesOverrides(req.Values); err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	claims, err := s.findOwnedClaims(ctx, r, claimID)
+	if err != nil {
+		writeClaimLookupProblem(w, r, err)
+		return
+	}
+	claim := &claims[0]
+	if claim.DeletionTimestamp != nil {
+		writeProblem(w, r, http.StatusConflict, problemClaimDeleting, "claim is being deleted")
+		return
+	}
+	if claim.Annotations[controller.PreProvisionedAnnotationKey] == "true" {
+		writeProblem(w, r, http.StatusConflict, problemConflict, "pre-provisioned claims cannot be updated")
+		return
+	}
+	profile, err := s.resolveProfile(claim.Labels[controller.ProfileLabelKey])
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusConflict, err, problemConflict)
+		return
+	}
+	if err := validateProfileOverrides(profile, req.Values); err != nil {
+		writeErrorProblem(w, r, http.StatusBadRequest, err, problemInvalidRequest)
+		return
+	}
+
+	overrides, err := recordedOverrides(claim)
+	if err != nil {
+		writeErrorProblem(w, r, http.StatusInternalServerError, err, problemInternal)
+		return
+	}
+	if overrides == nil {
+		overrides = map[string]any{}
+	}
+	overrides = values.MergeValues(overrides, req.Values)
+
+	updated, err := s.applyClaimRender(ctx, claim, overrides)
+	if err != nil {
+		var schemaErr *template.SchemaValidationError
+		if errors.As(err, &schemaErr) {
+			writeSchemaProblem(w, r, schemaErr)
+			return
+		}
+		writeProblem(w, r, http.StatusInternalServerError, problemInternal, "failed to update claim")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, claimStatusFrom(updated, false))
+}
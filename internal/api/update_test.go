@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/template"
+	"github.com/nonot/claim-controller/internal/values"
+)
+
+func TestValuesOverridesNeedAValuesRenderer(t *testing.T) {
+	raw, err := template.NewRawRenderer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := testClaim("abc", map[string]string{controller.OwnerAnnotationKey: "owner"})
+	claim.Labels[controller.ProfileLabelKey] = "raw"
+	s := newTestServer(t, Config{
+		ValuesAllowlist: values.NewAllowlist([]string{"image"}),
+		Profiles:        []Profile{{Name: "raw", Renderer: raw, ValuesProvider: testValuesProvider{}}},
+	}, claim)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{name: "create", method: http.MethodPost, target: "/v1/claim", body: `{"profile":"raw","values":{"image":"x"}}`, handler: s.handleClaim},
+		{name: "patch", method: http.MethodPatch, target: "/v1/claim/abc", body: `{"values":{"image":"x"}}`, handler: s.handlePatchClaim},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.SetPathValue("id", "abc")
+			rec := httptest.NewRecorder()
+			tt.handler(rec, withIdentity(req, "owner"))
+
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), problemValuesNotAllowed) {
+				t.Errorf("status = %d, body = %s; want 400 %s", rec.Code, rec.Body.String(), problemValuesNotAllowed)
+			}
+		})
+	}
+}
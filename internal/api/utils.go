@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
@@ -21,13 +20,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/helmrelease"
 	"github.com/nonot/claim-controller/internal/template"
+	"github.com/nonot/claim-controller/internal/values"
 )
 
 func (s *Server) findManagedClaimsByID(ctx context.Context, claimID string) ([]corev1.ConfigMap, error) {
 	claimList := &corev1.ConfigMapList{}
-	if err := s.client.List(ctx, claimList, client.InNamespace(s.namespace), client.MatchingLabels{controller.ClaimLabelKeyId: claimID}); err != nil {
-		log.Printf("failed to list claims: %v", err)
+	if err := s.listClaims(ctx, claimList, client.MatchingLabels{controller.ClaimLabelKeyId: claimID}); err != nil {
+		s.requestLogger(ctx).Error(err, "failed to list claims", "id", claimID)
 		if apierrors.IsNotFound(err) {
 			return nil, errClaimNotFound
 		}
@@ -183,17 +184,99 @@ func claimUsageExpectedDurationSeconds(claim corev1.ConfigMap) (float64, bool) {
 	return usageExpected.Seconds(), true
 }
 
-func (s *Server) loadResourceTemplate(claimID string) (template.ResourceTemplate, error) {
-	if s.valuesProvider == nil {
-		return template.ResourceTemplate{}, errors.New("values provider is not configured")
+type renderVersion struct {
+	templateHash   string
+	templateSource string
+	valuesHash     string
+	valuesSource   string
+}
+
+func (v renderVersion) apply(claim *corev1.ConfigMap) {
+	claim.Annotations[controller.TemplateHashAnnotationKey] = v.templateHash
+	claim.Annotations[controller.TemplateSourceAnnotationKey] = v.templateSource
+	claim.Annotations[controller.ValuesHashAnnotationKey] = v.valuesHash
+	claim.Annotations[controller.ValuesSourceAnnotationKey] = v.valuesSource
+}
+
+func (s *Server) loadResourceTemplate(claimID string, opts claimOptions) (template.ResourceTemplate, renderVersion, error) {
+	profile := opts.profile
+	if profile == nil {
+		profile = s.defaultProfile
+	}
+	valuesData, version, err := s.claimValues(profile, opts)
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, err
+	}
+
+	resourceTemplate, err := profile.Renderer.Render(valuesData, s.claimContext(claimID, profile, opts))
+	if err != nil {
+		return template.ResourceTemplate{}, renderVersion{}, err
+	}
+	return resourceTemplate, version, nil
+}
+
+func (s *Server) claimContext(claimID string, profile *Profile, opts claimOptions) template.ClaimContext {
+	claim := template.ClaimContext{
+		ID:        claimID,
+		Name:      fmt.Sprintf("claim-%s", claimID),
+		Namespace: s.resourceNamespace(claimID, opts),
+		Requester: opts.requester,
+		Profile:   profile.Name,
+	}
+	if !opts.expiresAt.IsZero() {
+		activatesAt := time.Now().UTC()
+		if !opts.startAt.IsZero() {
+			activatesAt = opts.startAt
+		}
+		claim.TTL = opts.expiresAt.Sub(activatesAt).Round(time.Second).String()
+		claim.ExpiresAt = opts.expiresAt.UTC().Format(time.RFC3339)
+	}
+	return claim
+}
+
+func (s *Server) claimValues(profile *Profile, opts claimOptions) ([]byte, renderVersion, error) {
+	if profile.ValuesProvider == nil {
+		return nil, renderVersion{}, fmt.Errorf("values provider is not configured for profile %q", profile.Name)
+	}
+	if profile.Renderer == nil {
+		return nil, renderVersion{}, fmt.Errorf("template renderer is not configured for profile %q", profile.Name)
 	}
 
-	valuesData, err := s.valuesProvider.GetValues()
+	valuesData, err := profile.ValuesProvider.GetValues()
 	if err != nil {
-		return template.ResourceTemplate{}, err
+		return nil, renderVersion{}, err
+	}
+	templateHash, err := profile.Renderer.Version()
+	if err != nil {
+		return nil, renderVersion{}, fmt.Errorf("compute template version: %w", err)
+	}
+	version := renderVersion{
+		templateHash:   templateHash,
+		templateSource: profile.Renderer.Description(),
+		valuesHash:     template.HashBytes(valuesData),
+		valuesSource:   profile.ValuesProvider.Description(),
 	}
 
-	return template.LoadResourceTemplateFromValuesData(s.namespace, s.templatePath, valuesData, claimID)
+	valuesData, err = values.ApplyOverrides(valuesData, opts.overrides)
+	if err != nil {
+		return nil, renderVersion{}, err
+	}
+	if profile.SchemaValidator != nil {
+		if err := profile.SchemaValidator.Validate(valuesData); err != nil {
+			return nil, renderVersion{}, err
+		}
+	}
+	return valuesData, version, nil
+}
+
+func (s *Server) resourceNamespace(claimID string, opts claimOptions) string {
+	if s.namespacePerClaim {
+		return fmt.Sprintf("claim-%s", claimID)
+	}
+	if opts.namespace != "" {
+		return opts.namespace
+	}
+	return s.namespace
 }
 
 var rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -207,40 +290,173 @@ func randomSuffix(n int) string {
 	return strings.ToLower(string(b))
 }
 
-func (s *Server) ttlFromRequest(r *http.Request) (time.Duration, error) {
+func decodeClaimRequest(r *http.Request) (claimRequest, error) {
+	var req claimRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return claimRequest{}, err
+	}
+	return req, nil
+}
+
+// decodeJSONBody accepts an empty body but rejects unknown fields and trailing data,
+// so a typo such as "tttl" is not silently ignored.
+func decodeJSONBody(r *http.Request, dst any) error {
 	if r.Body == nil {
-		return s.defaultTTL, nil
+		return nil
 	}
 
-	var req claimRequest
 	dec := json.NewDecoder(r.Body)
-	err := dec.Decode(&req)
-	if errors.Is(err, io.EOF) {
-		return s.defaultTTL, nil
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return withProblemCode(problemRequestTooLarge, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+		}
+		return fmt.Errorf("invalid request body: %w", err)
 	}
-	if err != nil {
-		return 0, fmt.Errorf("invalid request body: %w", err)
+	if err := dec.Decode(&json.RawMessage{}); !errors.Is(err, io.EOF) {
+		return errors.New("invalid request body: unexpected data after the JSON object")
+	}
+	return nil
+}
+
+func writeDecodeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadRequest
+	if problemCode(err, "") == problemRequestTooLarge {
+		status = http.StatusRequestEntityTooLarge
+	}
+	writeErrorProblem(w, r, status, err, problemInvalidRequest)
+}
+
+func (s *Server) limitedBody(next http.Handler) http.Handler {
+	if s.maxRequestBodyBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.maxRequestBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) validateValuesOverrides(overrides map[string]any) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	if s.valuesAllowlist.Empty() {
+		return withProblemCode(problemValuesNotAllowed, errors.New("values overrides are not allowed"))
 	}
+	if disallowed := s.valuesAllowlist.Disallowed(overrides); len(disallowed) > 0 {
+		return withProblemCode(problemValuesNotAllowed, fmt.Errorf("values overrides not allowed for: %s", strings.Join(disallowed, ", ")))
+	}
+	return nil
+}
+
+func validateProfileOverrides(profile *Profile, overrides map[string]any) error {
+	if len(overrides) > 0 && !template.UsesValues(profile.Renderer) {
+		return withProblemCode(problemValuesNotAllowed, fmt.Errorf("profile %q does not take values overrides", profile.Name))
+	}
+	return nil
+}
 
+func (s *Server) ttlFromClaimRequest(req claimRequest, policy ttlPolicy) (time.Duration, error) {
+	if raw := strings.TrimSpace(req.ExpiresAt); raw != "" {
+		if strings.TrimSpace(req.TTL) != "" {
+			return 0, withProblemCode(problemTTLInvalid, fmt.Errorf("ttl and expiresAt are mutually exclusive"))
+		}
+		return ttlUntil(raw, req.StartAt, policy)
+	}
 	if strings.TrimSpace(req.TTL) == "" {
-		return s.defaultTTL, nil
+		return policy.defaultTTL, nil
 	}
 
 	ttl, err := time.ParseDuration(strings.TrimSpace(req.TTL))
 	if err != nil {
-		return 0, fmt.Errorf("invalid ttl duration: %w", err)
+		return 0, withProblemCode(problemTTLInvalid, fmt.Errorf("invalid ttl duration: %w", err))
 	}
 	if ttl <= 0 {
-		return 0, fmt.Errorf("ttl must be greater than 0")
+		return 0, withProblemCode(problemTTLInvalid, fmt.Errorf("ttl must be greater than 0"))
+	}
+	if ttl > policy.maxTTL {
+		return policy.maxTTL, nil
+	}
+
+	return ttl, nil
+}
+
+// readyTimeoutFromClaimRequest rejects values above the server maximum rather
+// than clamping them, so clients do not silently get a shorter wait.
+func (s *Server) readyTimeoutFromClaimRequest(req claimRequest) (time.Duration, error) {
+	raw := strings.TrimSpace(req.ReadyTimeout)
+	if raw == "" {
+		return s.claimReadyTimeout, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid readyTimeout duration: %w", err)
 	}
-	if ttl > s.maxTTL {
-		return s.maxTTL, nil
+	if timeout <= 0 {
+		return 0, fmt.Errorf("readyTimeout must be greater than 0")
 	}
+	if timeout > s.maxClaimReadyTimeout {
+		return 0, fmt.Errorf("readyTimeout must not exceed %s", s.maxClaimReadyTimeout)
+	}
+	return timeout, nil
+}
 
+func ttlUntil(rawExpiresAt, rawStartAt string, policy ttlPolicy) (time.Duration, error) {
+	expiresAt, err := time.Parse(time.RFC3339, rawExpiresAt)
+	if err != nil {
+		return 0, withProblemCode(problemTTLInvalid, fmt.Errorf("invalid expiresAt: %w", err))
+	}
+	from := time.Now()
+	if startAt, err := parseStartAt(rawStartAt); err == nil && !startAt.IsZero() {
+		from = startAt
+	}
+
+	ttl := expiresAt.Sub(from).Truncate(time.Second)
+	if ttl <= 0 {
+		return 0, withProblemCode(problemTTLInvalid, fmt.Errorf("expiresAt must be in the future"))
+	}
+	if ttl > policy.maxTTL {
+		return 0, withProblemCode(problemMaxTTLReached, fmt.Errorf("expiresAt is %s away, beyond the maximum TTL of %s", ttl, policy.maxTTL))
+	}
 	return ttl, nil
 }
 
-func (s *Server) waitForClaimReady(ctx context.Context, claimName string, timeout time.Duration) error {
+const defaultMaxStartAtHorizon = 7 * 24 * time.Hour
+
+func parseStartAt(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	startAt, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid startAt: %w", err)
+	}
+	if !startAt.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return startAt.UTC(), nil
+}
+
+var errClaimFailed = errors.New("claim failed")
+
+// claimWriteMargin is the time left to write the response after a wait.
+const claimWriteMargin = 10 * time.Second
+
+// extendWriteDeadline lets a handler that waits on purpose outlive the
+// server WriteTimeout, which stays short for every other route.
+func extendWriteDeadline(w http.ResponseWriter, wait time.Duration) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + claimWriteMargin))
+}
+
+func (s *Server) waitForClaimReady(ctx context.Context, namespace, claimName string, timeout time.Duration) (*corev1.ConfigMap, error) {
 	waitCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -249,22 +465,54 @@ func (s *Server) waitForClaimReady(ctx context.Context, claimName string, timeou
 
 	for {
 		claim := &corev1.ConfigMap{}
-		err := s.client.Get(waitCtx, client.ObjectKey{Namespace: s.namespace, Name: claimName}, claim)
+		err := s.client.Get(waitCtx, client.ObjectKey{Namespace: namespace, Name: claimName}, claim)
 		if err == nil {
 			status := strings.TrimSpace(claim.Data[controller.ClaimStatusDataKey])
 			if strings.EqualFold(status, "ready") {
-				return nil
+				return claim, nil
 			}
-			if strings.EqualFold(status, "failed") {
+			if strings.EqualFold(status, controller.ClaimStatusFailed) {
 				message := strings.TrimSpace(claim.Data[controller.ClaimStatusMessageDataKey])
 				if message == "" {
 					message = "resource readiness failed"
 				}
-				return errors.New(message)
+				return nil, fmt.Errorf("%w: %s", errClaimFailed, message)
 			}
 		}
 		if err != nil && !apierrors.IsNotFound(err) {
-			return err
+			return nil, err
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return nil, waitCtx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) waitForClaimsDeleted(ctx context.Context, claims []corev1.ConfigMap, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		remaining := 0
+		for _, claim := range claims {
+			current := &corev1.ConfigMap{}
+			err := s.client.Get(waitCtx, client.ObjectKeyFromObject(&claim), current)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			remaining++
+		}
+		if remaining == 0 {
+			return nil
 		}
 
 		select {
@@ -275,6 +523,18 @@ func (s *Server) waitForClaimReady(ctx context.Context, claimName string, timeou
 	}
 }
 
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get(name))
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %w", name, err)
+	}
+	return value, nil
+}
+
 func (s *Server) renewClaim(ctx context.Context, claim corev1.ConfigMap, ttl time.Duration) (*corev1.ConfigMap, error) {
 	now := time.Now().UTC()
 	claimedAt := claim.CreationTimestamp.Time.UTC()
@@ -284,12 +544,23 @@ func (s *Server) renewClaim(ctx context.Context, claim corev1.ConfigMap, ttl tim
 		}
 	}
 
-	maxExpiresAt := claimedAt.Add(s.maxTTL)
+	if isReleasedClaim(&claim) {
+		return nil, errClaimReleased
+	}
+
+	// A scheduled claim's TTL counts from its activation, not from now.
+	from := now
+	if startAt, err := parseStartAt(claim.Annotations[controller.StartAtAnnotationKey]); err == nil && !startAt.IsZero() {
+		from = startAt
+		claimedAt = startAt
+	}
+
+	maxExpiresAt := claimedAt.Add(s.ttlPolicyForClaim(&claim).maxTTL)
 	if maxExpiresAt.Before(now) || maxExpiresAt.Equal(now) {
 		return nil, errMaxTTLReached
 	}
 
-	requestedExpiresAt := now.Add(ttl)
+	requestedExpiresAt := from.Add(ttl)
 	newExpiresAt := requestedExpiresAt
 	if newExpiresAt.After(maxExpiresAt) {
 		newExpiresAt = maxExpiresAt
@@ -300,19 +571,9 @@ func (s *Server) renewClaim(ctx context.Context, claim corev1.ConfigMap, ttl tim
 		updated.Annotations = map[string]string{}
 	}
 	updated.Annotations[controller.ExpiresAtAnnotationKey] = newExpiresAt.Format(time.RFC3339)
+	delete(updated.Annotations, controller.ExpiringSoonAnnotationKey)
 
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		current := &corev1.ConfigMap{}
-		if err := s.client.Get(ctx, client.ObjectKeyFromObject(updated), current); err != nil {
-			return err
-		}
-		if current.Annotations == nil {
-			current.Annotations = map[string]string{}
-		}
-		current.Annotations[controller.ExpiresAtAnnotationKey] = newExpiresAt.Format(time.RFC3339)
-		return s.client.Update(ctx, current)
-	})
-	if err != nil {
+	if err := s.client.Patch(ctx, updated, client.MergeFrom(&claim), client.FieldOwner(apiFieldOwner)); err != nil {
 		return nil, err
 	}
 
@@ -320,64 +581,122 @@ func (s *Server) renewClaim(ctx context.Context, claim corev1.ConfigMap, ttl tim
 }
 
 func (s *Server) ensurePreProvisionedClaims(ctx context.Context) error {
-	if s.preProvisionCount <= 0 {
-		return nil
+	pool, err := s.listPoolClaims(ctx)
+	if err != nil {
+		return err
 	}
 
-	claimList := &corev1.ConfigMapList{}
-	if err := s.client.List(ctx, claimList, client.InNamespace(s.namespace), client.MatchingLabels{controller.ManagedByLabelKey: controller.ManagedByLabelValue}); err != nil {
+	pool, err = s.recycleStalePoolClaims(ctx, pool)
+	if err != nil {
 		return err
 	}
 
-	currentCount := 0
-	for i := range claimList.Items {
-		claim := &claimList.Items[i]
-		if !strings.EqualFold(strings.TrimSpace(claim.Annotations[controller.PreProvisionedAnnotationKey]), "true") {
-			continue
-		}
+	// The replenisher owns the gauge: it is set once per pass from the pool it saw.
+	available := len(pool)
+	defer func() { poolAvailable.Set(float64(available)) }()
 
-		currentCount++
+	target := s.poolTarget()
+	if len(pool) > target {
+		deleted, err := s.scaleDownPool(ctx, pool, len(pool)-target)
+		available -= deleted
+		return err
 	}
 
-	missing := s.preProvisionCount - currentCount
+	missing := target - len(pool)
 	for i := 0; i < missing; i++ {
 		claimID := randomSuffix(8)
-		expiresAt := time.Now().UTC().Add(s.maxTTL)
-		if _, err := s.createClaim(ctx, claimID, expiresAt, true); err != nil {
+		expiresAt := time.Now().UTC().Add(s.currentTunables().MaxTTL)
+		if _, err := s.createClaim(ctx, claimID, expiresAt, true, claimOptions{profile: s.defaultProfile}); err != nil {
 			return err
 		}
 		claimsPreProvisionedCreatedTotal.Inc()
+		available++
 	}
 
 	return nil
 }
 
-func (s *Server) acquireClaim(ctx context.Context, ttl time.Duration) (*corev1.ConfigMap, string, time.Time, bool, error) {
-	claim, err := s.acquirePreProvisionedClaim(ctx, ttl)
-	if err != nil {
-		return nil, "", time.Time{}, false, err
+func foregroundPropagation() client.DeleteOption {
+	return client.PropagationPolicy(metav1.DeletePropagationForeground)
+}
+
+func (s *Server) acquireClaim(ctx context.Context, ttl time.Duration, opts claimOptions) (*corev1.ConfigMap, string, time.Time, bool, error) {
+	if opts.profile == nil {
+		opts.profile = s.defaultProfile
+	}
+
+	if opts.namespace == "" {
+		opts.namespace = s.namespace
+	}
+
+	var claim *corev1.ConfigMap
+	unpooled := s.unpooledReason(opts)
+	// Keyed requests are created under a name derived from the key when the
+	// mode allows it, which makes retries atomic; a pool claim has its own name.
+	keyed := opts.idempotencyKey != "" && s.provisioningMode != ProvisioningModePoolOnly
+	if s.provisioningMode != ProvisioningModeOnDemand && opts.startAt.IsZero() && unpooled == "" && !keyed {
+		var err error
+		claim, err = s.acquirePreProvisionedClaim(ctx, ttl, opts)
+		if err != nil {
+			return nil, "", time.Time{}, false, err
+		}
+		if claim != nil {
+			poolRequestsTotal.WithLabelValues("hit").Inc()
+		} else {
+			poolRequestsTotal.WithLabelValues("miss").Inc()
+		}
 	}
 	if claim != nil {
 		claimID := strings.TrimSpace(claim.Labels[controller.ClaimLabelKeyId])
 		expiresAt, _ := time.Parse(time.RFC3339, claim.Annotations[controller.ExpiresAtAnnotationKey])
-		claimsReusedPreProvisionedTotal.Inc()
+		claimsReusedPreProvisionedTotal.WithLabelValues(opts.profile.Name).Inc()
 		return claim, claimID, expiresAt, true, nil
 	}
+	if s.provisioningMode == ProvisioningModePoolOnly && opts.startAt.IsZero() {
+		if unpooled != "" {
+			return nil, "", time.Time{}, false, fmt.Errorf("%w (%s)", errNotPoolable, unpooled)
+		}
+		s.triggerPoolReconcile()
+		return nil, "", time.Time{}, false, errPoolExhausted
+	}
 
 	claimID := randomSuffix(8)
-	expiresAt := time.Now().UTC().Add(ttl)
-	created, err := s.createClaim(ctx, claimID, expiresAt, false)
+	if opts.idempotencyKey != "" {
+		claimID = idempotentClaimID(opts.idempotencyKey)
+	}
+	activatesAt := time.Now().UTC()
+	if !opts.startAt.IsZero() {
+		activatesAt = opts.startAt
+	}
+	expiresAt := activatesAt.Add(ttl)
+	created, err := s.createClaim(ctx, claimID, expiresAt, false, opts)
 	if err != nil {
 		return nil, "", time.Time{}, false, err
 	}
 
-	claimsCreatedTotal.Inc()
-	claimsCreatedOnDemandTotal.Inc()
+	claimsCreatedTotal.WithLabelValues(opts.profile.Name).Inc()
+	claimsCreatedOnDemandTotal.WithLabelValues(opts.profile.Name).Inc()
 	return created, claimID, expiresAt, false, nil
 }
 
-func (s *Server) acquirePreProvisionedClaim(ctx context.Context, ttl time.Duration) (*corev1.ConfigMap, error) {
-	if s.preProvisionCount <= 0 {
+// unpooledReason tells why the pre-provisioned pool can never serve the
+// request, or returns an empty string when it can.
+func (s *Server) unpooledReason(opts claimOptions) string {
+	switch {
+	case len(opts.overrides) > 0:
+		return "values overrides"
+	case opts.profile != s.defaultProfile:
+		return fmt.Sprintf("profile %q", opts.profile.Name)
+	case opts.namespace != s.namespace:
+		return fmt.Sprintf("namespace %q", opts.namespace)
+	case opts.cluster != "":
+		return fmt.Sprintf("cluster %q", opts.cluster)
+	}
+	return ""
+}
+
+func (s *Server) acquirePreProvisionedClaim(ctx context.Context, ttl time.Duration, opts claimOptions) (*corev1.ConfigMap, error) {
+	if s.poolTarget() <= 0 {
 		return nil, nil
 	}
 
@@ -386,6 +705,18 @@ func (s *Server) acquirePreProvisionedClaim(ctx context.Context, ttl time.Durati
 		return nil, err
 	}
 
+	if opts.priority != PriorityHigh && s.poolReserve > 0 {
+		available := 0
+		for _, candidate := range claimList.Items {
+			if candidate.DeletionTimestamp == nil && strings.EqualFold(strings.TrimSpace(candidate.Annotations[controller.PreProvisionedAnnotationKey]), "true") {
+				available++
+			}
+		}
+		if available <= s.poolReserve {
+			return nil, nil
+		}
+	}
+
 	now := time.Now().UTC()
 	for i := range claimList.Items {
 		candidate := claimList.Items[i]
@@ -410,7 +741,7 @@ func (s *Server) acquirePreProvisionedClaim(ctx context.Context, ttl time.Durati
 					claimedAt = parsedClaimedAt.UTC()
 				}
 			}
-			maxExpiresAt := claimedAt.Add(s.maxTTL)
+			maxExpiresAt := claimedAt.Add(s.ttlPolicyFor(opts.profile, s.tenants[opts.tenant]).maxTTL)
 			if !maxExpiresAt.After(now) {
 				return apierrors.NewConflict(corev1.Resource("configmaps"), current.Name, errors.New("pre-provisioned claim too old"))
 			}
@@ -426,6 +757,34 @@ func (s *Server) acquirePreProvisionedClaim(ctx context.Context, ttl time.Durati
 			current.Annotations[controller.PreProvisionedAnnotationKey] = "false"
 			current.Annotations[controller.ClaimedAtAnnotationKey] = now.Format(time.RFC3339)
 			current.Annotations[controller.ExpiresAtAnnotationKey] = expiresAt.Format(time.RFC3339)
+			if identity, ok := IdentityFromContext(ctx); ok {
+				current.Annotations[controller.OwnerAnnotationKey] = identity.Subject
+			}
+			if requestID := RequestIDFromContext(ctx); requestID != "" {
+				current.Annotations[controller.RequestIDAnnotationKey] = requestID
+			}
+			if opts.team != "" {
+				current.Labels[controller.TeamLabelKey] = opts.team
+			}
+			if opts.tenant != "" {
+				current.Labels[controller.TenantLabelKey] = opts.tenant
+			}
+			if opts.clonedFrom != "" {
+				current.Annotations[controller.ClonedFromAnnotationKey] = opts.clonedFrom
+			}
+			if opts.idempotencyKey != "" {
+				current.Labels[controller.IdempotencyKeyLabelKey] = opts.idempotencyKey
+			}
+			opts.metadata.apply(current)
+			if opts.priority != "" {
+				current.Labels[controller.PriorityLabelKey] = opts.priority
+			}
+			if opts.minReady != nil {
+				current.Annotations[controller.MinReadyAnnotationKey] = opts.minReady.String()
+			}
+			if opts.workingHoursExempt {
+				current.Annotations[controller.WorkingHoursExemptAnnotationKey] = "true"
+			}
 			return s.client.Update(ctx, current)
 		})
 		if err != nil {
@@ -437,21 +796,45 @@ func (s *Server) acquirePreProvisionedClaim(ctx context.Context, ttl time.Durati
 			return nil, err
 		}
 
-		claimsCreatedTotal.Inc()
+		claimsCreatedTotal.WithLabelValues(s.defaultProfile.Name).Inc()
 		return fresh, nil
 	}
 
 	return nil, nil
 }
 
-func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time.Time, preProvisioned bool) (*corev1.ConfigMap, error) {
+func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time.Time, preProvisioned bool, opts claimOptions) (*corev1.ConfigMap, error) {
+	if opts.profile == nil {
+		opts.profile = s.defaultProfile
+	}
+	if opts.namespace == "" {
+		opts.namespace = s.namespace
+	}
+
 	claimName := fmt.Sprintf("claim-%s", claimID)
 	claimedAt := ""
 	if !preProvisioned {
 		claimedAt = time.Now().UTC().Format(time.RFC3339)
 	}
+	if !opts.startAt.IsZero() {
+		claimedAt = opts.startAt.Format(time.RFC3339)
+	}
 
-	resourceTemplate, err := s.loadResourceTemplate(claimID)
+	opts.expiresAt = expiresAt
+	if identity, ok := IdentityFromContext(ctx); ok && !preProvisioned {
+		opts.requester = identity.Subject
+	}
+
+	helmRelease := opts.profile.Backend == helmrelease.BackendHelmRelease
+	var resourceTemplate template.ResourceTemplate
+	var version renderVersion
+	var releaseValues []byte
+	var err error
+	if helmRelease {
+		resourceTemplate, version, releaseValues, err = s.releaseResourceTemplate(ctx, claimID, opts)
+	} else {
+		resourceTemplate, version, err = s.loadResourceTemplate(claimID, opts)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -463,6 +846,10 @@ func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time
 	if err != nil {
 		return nil, err
 	}
+	renderedResources, renderedChunks, err := controller.PackRenderedResources(renderedResourcesBytes)
+	if err != nil {
+		return nil, err
+	}
 
 	returnValuesBytes, err := json.Marshal(resourceTemplate.ReturnValues)
 	if err != nil {
@@ -472,11 +859,12 @@ func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time
 	claim := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      claimName,
-			Namespace: s.namespace,
+			Namespace: opts.namespace,
 			Labels: map[string]string{
 				controller.ManagedByLabelKey: controller.ManagedByLabelValue,
 				controller.ClaimLabelKey:     claimName,
 				controller.ClaimLabelKeyId:   claimID,
+				controller.ProfileLabelKey:   opts.profile.Name,
 			},
 			Annotations: map[string]string{
 				controller.ExpiresAtAnnotationKey:      expiresAt.Format(time.RFC3339),
@@ -485,7 +873,7 @@ func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time
 			},
 		},
 		Data: map[string]string{
-			controller.RenderedResourcesDataKey:    string(renderedResourcesBytes),
+			controller.RenderedResourcesDataKey:    renderedResources,
 			controller.ReturnValuesDataKey:         string(returnValuesBytes),
 			controller.ClaimStatusDataKey:          "pending",
 			controller.ClaimStatusMessageDataKey:   "waiting for resources to be created",
@@ -493,12 +881,71 @@ func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time
 		},
 	}
 
-	if ownerRef := s.valuesProvider.GetOwnerReference(); ownerRef != nil {
+	version.apply(claim)
+	if ownerRef := opts.profile.ValuesProvider.GetOwnerReference(); ownerRef != nil {
 		claim.OwnerReferences = []metav1.OwnerReference{*ownerRef}
 	}
 	if claimedAt != "" {
 		claim.Annotations[controller.ClaimedAtAnnotationKey] = claimedAt
 	}
+	if s.namespacePerClaim {
+		claim.Annotations[controller.ClaimNamespaceAnnotationKey] = s.resourceNamespace(claimID, opts)
+	}
+	if opts.cluster != "" {
+		claim.Annotations[controller.ClusterAnnotationKey] = opts.cluster
+	}
+	if opts.profile != nil && opts.profile.Type != "" {
+		claim.Annotations[controller.ProfileTypeAnnotationKey] = opts.profile.Type
+	}
+	if opts.clonedFrom != "" {
+		claim.Annotations[controller.ClonedFromAnnotationKey] = opts.clonedFrom
+	}
+	minReady := opts.minReady
+	if minReady == nil {
+		minReady = opts.profile.MinReady
+	}
+	if minReady != nil {
+		claim.Annotations[controller.MinReadyAnnotationKey] = minReady.String()
+	}
+	if helmRelease {
+		claim.Annotations[controller.BackendAnnotationKey] = helmrelease.BackendHelmRelease
+		claim.Finalizers = append(claim.Finalizers, controller.HelmReleaseFinalizer)
+		claim.Data[controller.HelmReleaseValuesDataKey] = string(releaseValues)
+	}
+	if !opts.startAt.IsZero() {
+		claim.Annotations[controller.StartAtAnnotationKey] = opts.startAt.Format(time.RFC3339)
+		claim.Data[controller.ClaimStatusDataKey] = controller.ClaimStatusScheduled
+		claim.Data[controller.ClaimStatusMessageDataKey] = fmt.Sprintf("scheduled to start at %s", opts.startAt.Format(time.RFC3339))
+	}
+	if identity, ok := IdentityFromContext(ctx); ok && !preProvisioned {
+		claim.Annotations[controller.OwnerAnnotationKey] = identity.Subject
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" && !preProvisioned {
+		claim.Annotations[controller.RequestIDAnnotationKey] = requestID
+	}
+	if opts.team != "" {
+		claim.Labels[controller.TeamLabelKey] = opts.team
+	}
+	if opts.tenant != "" {
+		claim.Labels[controller.TenantLabelKey] = opts.tenant
+	}
+	if opts.idempotencyKey != "" {
+		claim.Labels[controller.IdempotencyKeyLabelKey] = opts.idempotencyKey
+	}
+	opts.metadata.apply(claim)
+	if opts.priority != "" {
+		claim.Labels[controller.PriorityLabelKey] = opts.priority
+	}
+	if opts.workingHoursExempt {
+		claim.Annotations[controller.WorkingHoursExemptAnnotationKey] = "true"
+	}
+	if len(opts.overrides) > 0 {
+		overridesBytes, err := json.Marshal(opts.overrides)
+		if err != nil {
+			return nil, err
+		}
+		claim.Annotations[controller.ValuesOverridesAnnotationKey] = string(overridesBytes)
+	}
 
 	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		return s.client.Create(ctx, claim)
@@ -506,6 +953,26 @@ func (s *Server) createClaim(ctx context.Context, claimID string, expiresAt time
 	if err != nil {
 		return nil, err
 	}
+	if len(renderedChunks) > 0 {
+		if err := s.storeRenderedChunks(ctx, claim, renderedChunks); err != nil {
+			s.rollbackClaim(ctx, claim)
+			return nil, err
+		}
+	}
+
+	if err := s.createClaimCredentials(ctx, claim); err != nil {
+		s.rollbackClaim(ctx, claim)
+		return nil, err
+	}
 
 	return claim, nil
 }
+
+// rollbackClaim deletes a claim whose creation failed half-way, so it is never
+// handed out without its rendered chunks or credentials. The helm release and
+// owned objects go with it through the regular cleanup.
+func (s *Server) rollbackClaim(ctx context.Context, claim *corev1.ConfigMap) {
+	if err := s.client.Delete(context.WithoutCancel(ctx), claim, foregroundPropagation()); client.IgnoreNotFound(err) != nil {
+		s.requestLogger(ctx).Error(err, "failed to roll back claim after creation failure", "claim", claim.Name)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nonot/claim-controller/internal/template"
+)
+
+const validationClaimID = "validate"
+
+type validateRequest struct {
+	Profile string `json:"profile,omitempty"`
+}
+
+type profileValidation struct {
+	Profile    string                     `json:"profile"`
+	Valid      bool                       `json:"valid"`
+	Resources  int                        `json:"resources,omitempty"`
+	Error      string                     `json:"error,omitempty"`
+	Violations []template.SchemaViolation `json:"violations,omitempty"`
+}
+
+func (s *Server) validateProfile(profile *Profile) profileValidation {
+	result := profileValidation{Profile: profile.Name}
+	resourceTemplate, _, err := s.loadResourceTemplate(validationClaimID, claimOptions{profile: profile, namespace: profile.Namespace})
+	if err != nil {
+		var schemaErr *template.SchemaValidationError
+		if errors.As(err, &schemaErr) {
+			result.Violations = schemaErr.Violations
+		}
+		result.Error = err.Error()
+		return result
+	}
+	result.Valid = true
+	result.Resources = len(resourceTemplate.RenderedObjects)
+	return result
+}
+
+func (s *Server) validateProfiles() error {
+	for _, name := range s.profileNames() {
+		result := s.validateProfile(s.profiles[name])
+		if !result.Valid {
+			return fmt.Errorf("profile %q does not render: %s", name, result.Error)
+		}
+		s.logger.Info("validated template profile", "profile", name, "resources", result.Resources)
+	}
+	return nil
+}
+
+func (s *Server) handleAdminValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req validateRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeDecodeProblem(w, r, err)
+		return
+	}
+
+	names := s.profileNames()
+	if req.Profile != "" {
+		if _, ok := s.profiles[req.Profile]; !ok {
+			writeProblem(w, r, http.StatusBadRequest, problemUnknownProfile, fmt.Sprintf("unknown profile %q", req.Profile))
+			return
+		}
+		names = []string{req.Profile}
+	}
+
+	valid := true
+	results := make([]profileValidation, 0, len(names))
+	for _, name := range names {
+		result := s.validateProfile(s.profiles[name])
+		valid = valid && result.Valid
+		results = append(results, result)
+	}
+
+	status := http.StatusOK
+	if !valid {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, validateResponse{Valid: valid, Profiles: results})
+}
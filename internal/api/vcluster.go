@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/controller"
+	"github.com/nonot/claim-controller/internal/vcluster"
+)
+
+func (s *Server) issueVClusterKubeconfig(ctx context.Context, claim *corev1.ConfigMap, expiresAt time.Time) (string, error) {
+	if s.apiReader == nil {
+		return "", fmt.Errorf("vcluster claims require an API reader")
+	}
+
+	namespace := claim.Namespace
+	if dedicated := claim.Annotations[controller.ClaimNamespaceAnnotationKey]; dedicated != "" {
+		namespace = dedicated
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: vcluster.SecretName(claim.Name)}, secret); err != nil {
+		return "", fmt.Errorf("read vcluster kubeconfig secret: %w", err)
+	}
+	cfg, err := vcluster.RESTConfig(secret, claim.Name, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfig, err := vcluster.IssueKubeconfig(ctx, cfg, claim.Name, time.Until(expiresAt))
+	if err != nil {
+		return "", err
+	}
+	return string(kubeconfig), nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	APIVersionHeader = "X-API-Version"
+	APIVersionV1     = "v1"
+
+	// Version served by the unprefixed legacy routes when no version is negotiated.
+	legacyAPIVersion = APIVersionV1
+)
+
+type apiRoute struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+type apiVersion struct {
+	name   string
+	routes []apiRoute
+}
+
+func (v apiVersion) prefix() string {
+	return "/" + v.name
+}
+
+func (v apiVersion) path(format string, args ...any) string {
+	return v.prefix() + fmt.Sprintf(format, args...)
+}
+
+var apiV1 = apiVersion{name: APIVersionV1}
+
+func (s *Server) v1Routes() []apiRoute {
+	return []apiRoute{
+		{"/claim", s.audited("claim.create", s.authenticated("claim", s.rateLimited(s.handleClaim)))},
+		{"/claim/{id}", s.audited("claim.update", s.authenticated("claim", s.handlePatchClaim))},
+		{"/claim/{id}/suspend", s.audited("claim.suspend", s.authenticated("renew", s.handleSuspend))},
+		{"/claim/{id}/resume", s.audited("claim.resume", s.authenticated("renew", s.handleResume))},
+		{"/claim/{id}/clone", s.audited("claim.clone", s.authenticated("claim", s.rateLimited(s.handleClone)))},
+		{"/release/{id}", s.audited("claim.release", s.authenticated("release", s.handleRelease))},
+		{"/renew/{id}", s.audited("claim.renew", s.authenticated("renew", s.handleRenew))},
+		{"/claims", s.authenticated("claims", s.handleListClaims)},
+		{"/claims/history", s.authenticated("claims", s.handleClaimHistory)},
+		{"/claims/{id}", s.authenticated("claims", s.handleGetClaim)},
+		{"/admin/pool", s.audited("admin.pool.resize", s.authenticated("admin", s.adminOnly(s.handleAdminPool)))},
+		{"/admin/rerender", s.audited("admin.rerender", s.authenticated("admin", s.adminOnly(s.handleAdminRerender)))},
+		{"/admin/validate", s.audited("admin.validate", s.authenticated("admin", s.adminOnly(s.handleAdminValidate)))},
+	}
+}
+
+func (s *Server) apiVersions() []apiVersion {
+	v1 := apiV1
+	v1.routes = s.v1Routes()
+	return []apiVersion{v1}
+}
+
+// versionedRoutes mounts every version under its prefix and keeps the unprefixed
+// paths as deprecated aliases dispatching on the X-API-Version header.
+func (s *Server) versionedRoutes() {
+	versions := s.apiVersions()
+	aliases := map[string]map[string]http.HandlerFunc{}
+	var patterns []string
+	for _, version := range versions {
+		for _, route := range version.routes {
+			s.mux.HandleFunc(version.prefix()+route.pattern, versioned(version.name, route.handler))
+			if aliases[route.pattern] == nil {
+				aliases[route.pattern] = map[string]http.HandlerFunc{}
+				patterns = append(patterns, route.pattern)
+			}
+			aliases[route.pattern][version.name] = route.handler
+		}
+	}
+
+	supported := make([]string, 0, len(versions))
+	for _, version := range versions {
+		supported = append(supported, version.name)
+	}
+	for _, pattern := range patterns {
+		s.mux.HandleFunc(pattern, legacyAlias(aliases[pattern], supported))
+	}
+}
+
+func versioned(version string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if requested := strings.TrimSpace(r.Header.Get(APIVersionHeader)); requested != "" && requested != version {
+			writeProblem(w, r, http.StatusBadRequest, problemUnsupportedAPIVersion, fmt.Sprintf("%s %q does not match the %s route prefix", APIVersionHeader, requested, version))
+			return
+		}
+		w.Header().Set(APIVersionHeader, version)
+		next(w, r)
+	}
+}
+
+func legacyAlias(handlers map[string]http.HandlerFunc, supported []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := strings.TrimSpace(r.Header.Get(APIVersionHeader))
+		if version == "" {
+			version = legacyAPIVersion
+		}
+		next, ok := handlers[version]
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, problemUnsupportedAPIVersion, fmt.Sprintf("unsupported %s %q, supported versions: %s", APIVersionHeader, version, strings.Join(supported, ", ")))
+			return
+		}
+		w.Header().Set(APIVersionHeader, version)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("</%s%s>; rel=\"successor-version\"", version, r.URL.EscapedPath()))
+		next(w, r)
+	}
+}
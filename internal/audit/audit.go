@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeFailure = "failure"
+)
+
+// Record is one append-only audit trail entry for an API mutation.
+type Record struct {
+	Time      time.Time      `json:"time"`
+	Action    string         `json:"action"`
+	Requester string         `json:"requester"`
+	RequestID string         `json:"requestId,omitempty"`
+	Tenant    string         `json:"tenant,omitempty"`
+	ClaimID   string         `json:"claimId,omitempty"`
+	Method    string         `json:"method"`
+	Path      string         `json:"path"`
+	Status    int            `json:"status"`
+	Outcome   string         `json:"outcome"`
+	Params    map[string]any `json:"params,omitempty"`
+}
+
+// Sink receives audit records. Close delivers what is still buffered and is
+// called once on shutdown.
+type Sink interface {
+	Write(record Record)
+	Close(ctx context.Context) error
+}
+
+type Multi []Sink
+
+func (m Multi) Write(record Record) {
+	for _, sink := range m {
+		if sink != nil {
+			sink.Write(record)
+		}
+	}
+}
+
+func (m Multi) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range m {
+		if sink != nil {
+			errs = append(errs, sink.Close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StreamSink writes one JSON document per line, e.g. to stdout.
+type StreamSink struct {
+	mu     sync.Mutex
+	out    io.Writer
+	logger logr.Logger
+}
+
+func NewStreamSink(out io.Writer, logger logr.Logger) *StreamSink {
+	return &StreamSink{out: out, logger: logger}
+}
+
+func (s *StreamSink) Write(record Record) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		s.logger.Error(err, "failed to encode audit record", "action", record.Action)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.out.Write(append(line, '\n')); err != nil {
+		s.logger.Error(err, "failed to write audit record", "action", record.Action)
+	}
+}
+
+func (s *StreamSink) Close(context.Context) error {
+	return nil
+}
+
+func Outcome(status int) string {
+	switch {
+	case status < 400:
+		return OutcomeSuccess
+	case status == 401 || status == 403 || status == 429:
+		return OutcomeDenied
+	default:
+		return OutcomeFailure
+	}
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/nonot/claim-controller/internal/notify"
+)
+
+const (
+	webhookQueueSize = 1024
+	webhookAttempts  = 3
+)
+
+// WebhookSink POSTs each record as JSON from a single goroutine, so records
+// arrive in order. Records are dropped (and logged) when the queue is full
+// rather than slowing down the API. Close stops accepting records and waits
+// for the queued ones to be delivered.
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+	logger logr.Logger
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+}
+
+func NewWebhookSink(url, secret string, timeout time.Duration, logger logr.Logger) *WebhookSink {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	sink := &WebhookSink{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+		queue:  make(chan []byte, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go sink.run()
+	return sink
+}
+
+func (s *WebhookSink) Write(record Record) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		s.logger.Error(err, "failed to encode audit record", "action", record.Action)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.logger.Info("audit webhook closed, dropping record", "action", record.Action, "requestId", record.RequestID)
+		return
+	}
+	select {
+	case s.queue <- body:
+	default:
+		s.logger.Info("audit webhook queue full, dropping record", "action", record.Action, "requestId", record.RequestID)
+	}
+}
+
+func (s *WebhookSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit webhook: %d records not delivered: %w", len(s.queue), ctx.Err())
+	}
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for body := range s.queue {
+		backoff := time.Second
+		for attempt := 1; attempt <= webhookAttempts; attempt++ {
+			err := s.post(body)
+			if err == nil {
+				break
+			}
+			if attempt == webhookAttempts {
+				s.logger.Error(err, "failed to deliver audit record", "url", s.url)
+				break
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+func (s *WebhookSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		req.Header.Set(notify.SignatureHeader, notify.Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
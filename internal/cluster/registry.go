@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	NameLabelKey      = "claim-controller.io/cluster-name"
+	KubeconfigDataKey = "kubeconfig"
+
+	secretRecheckInterval = time.Minute
+)
+
+var ErrUnknownCluster = errors.New("unknown cluster")
+
+type cachedClient struct {
+	resourceVersion string
+	checkedAt       time.Time
+	client          client.Client
+}
+
+type Registry struct {
+	reader    client.Reader
+	namespace string
+	scheme    *runtime.Scheme
+	mu        sync.Mutex
+	clients   map[string]*cachedClient
+}
+
+func NewRegistry(reader client.Reader, namespace string, scheme *runtime.Scheme) *Registry {
+	return &Registry{
+		reader:    reader,
+		namespace: namespace,
+		scheme:    scheme,
+		clients:   map[string]*cachedClient{},
+	}
+}
+
+func (r *Registry) Client(ctx context.Context, name string) (client.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cached, ok := r.clients[name]
+	if ok && time.Since(cached.checkedAt) < secretRecheckInterval {
+		return cached.client, nil
+	}
+
+	secret, err := r.secretFor(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if ok && cached.resourceVersion == secret.ResourceVersion {
+		cached.checkedAt = time.Now()
+		return cached.client, nil
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[KubeconfigDataKey])
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig of cluster %q: %w", name, err)
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: r.scheme})
+	if err != nil {
+		return nil, fmt.Errorf("create client for cluster %q: %w", name, err)
+	}
+
+	r.clients[name] = &cachedClient{resourceVersion: secret.ResourceVersion, checkedAt: time.Now(), client: c}
+	return c, nil
+}
+
+func (r *Registry) Has(ctx context.Context, name string) error {
+	_, err := r.secretFor(ctx, name)
+	return err
+}
+
+func (r *Registry) secretFor(ctx context.Context, name string) (*corev1.Secret, error) {
+	secrets := &corev1.SecretList{}
+	if err := r.reader.List(ctx, secrets, client.InNamespace(r.namespace), client.MatchingLabels{NameLabelKey: name}); err != nil {
+		return nil, fmt.Errorf("list kubeconfig secrets: %w", err)
+	}
+	switch len(secrets.Items) {
+	case 0:
+		return nil, fmt.Errorf("%w %q", ErrUnknownCluster, name)
+	case 1:
+	default:
+		return nil, fmt.Errorf("cluster %q is registered by %d secrets", name, len(secrets.Items))
+	}
+
+	secret := &secrets.Items[0]
+	if len(secret.Data[KubeconfigDataKey]) == 0 {
+		return nil, fmt.Errorf("secret %s of cluster %q has no %s key", secret.Name, name, KubeconfigDataKey)
+	}
+	return secret, nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -10,15 +12,91 @@ import (
 
 type AppConfig struct {
 	Namespace               string `json:"namespace" yaml:"namespace"`
-	TemplatePath            string `json:"templatePath" yaml:"templatePath"`
+	WatchNamespaces         string `json:"watchNamespaces" yaml:"watchNamespaces"`
 	ValuesPath              string `json:"valuesPath" yaml:"valuesPath"`
+	ValuesConfigMapName     string `json:"valuesConfigMapName" yaml:"valuesConfigMapName"`
+	ValuesConfigMapKey      string `json:"valuesConfigMapKey" yaml:"valuesConfigMapKey"`
+	ValuesSchemaPath        string `json:"valuesSchemaPath" yaml:"valuesSchemaPath"`
+	ValuesOverrideAllowlist string `json:"valuesOverrideAllowlist" yaml:"valuesOverrideAllowlist"`
+	ValuesMerge             string `json:"valuesMerge" yaml:"valuesMerge"`
+	TemplatePath            string `json:"templatePath" yaml:"templatePath"`
+	CredentialKeys          string `json:"credentialKeys" yaml:"credentialKeys"`
+	QuotasPath              string `json:"quotasPath" yaml:"quotasPath"`
+	TenantsPath             string `json:"tenantsPath" yaml:"tenantsPath"`
+	ProfilesPath            string `json:"profilesPath" yaml:"profilesPath"`
+	TemplateRenderer        string `json:"templateRenderer" yaml:"templateRenderer"`
+	ResourceBackend         string `json:"resourceBackend" yaml:"resourceBackend"`
+	TemplateRef             string `json:"templateRef" yaml:"templateRef"`
+	TemplateCacheDir        string `json:"templateCacheDir" yaml:"templateCacheDir"`
+	TemplateRegistrySecret  string `json:"templateRegistrySecret" yaml:"templateRegistrySecret"`
 	APIAddr                 string `json:"apiAddr" yaml:"apiAddr"`
+	GRPCAddr                string `json:"grpcAddr" yaml:"grpcAddr"`
+	APITLSCert              string `json:"apiTLSCert" yaml:"apiTLSCert"`
+	APITLSKey               string `json:"apiTLSKey" yaml:"apiTLSKey"`
+	APIClientCA             string `json:"apiClientCA" yaml:"apiClientCA"`
 	MetricsAddr             string `json:"metricsAddr" yaml:"metricsAddr"`
 	ProbeAddr               string `json:"probeAddr" yaml:"probeAddr"`
+	PprofAddr               string `json:"pprofAddr" yaml:"pprofAddr"`
 	DefaultTTL              string `json:"defaultTTL" yaml:"defaultTTL"`
 	MaxTTL                  string `json:"maxTTL" yaml:"maxTTL"`
 	PreProvisionClaimsCount string `json:"preProvisionClaimsCount" yaml:"preProvisionClaimsCount"`
+	MaxActiveClaims         string `json:"maxActiveClaims" yaml:"maxActiveClaims"`
+	PoolReserve             string `json:"poolReserve" yaml:"poolReserve"`
+	PoolMaxIdleAge          string `json:"poolMaxIdleAge" yaml:"poolMaxIdleAge"`
+	ProvisioningMode        string `json:"provisioningMode" yaml:"provisioningMode"`
+	PoolReplenishInterval   string `json:"poolReplenishInterval" yaml:"poolReplenishInterval"`
+	ClaimQueueTimeout       string `json:"claimQueueTimeout" yaml:"claimQueueTimeout"`
+	RerenderRatePerMinute   string `json:"rerenderRatePerMinute" yaml:"rerenderRatePerMinute"`
+	NamespacePerClaim       string `json:"namespacePerClaim" yaml:"namespacePerClaim"`
+	IdempotencyKeyTTL       string `json:"idempotencyKeyTTL" yaml:"idempotencyKeyTTL"`
+	ClaimRateLimitPerMinute string `json:"claimRateLimitPerMinute" yaml:"claimRateLimitPerMinute"`
+	ClaimRateLimitBurst     string `json:"claimRateLimitBurst" yaml:"claimRateLimitBurst"`
 	ReconcileInterval       string `json:"reconcileInterval" yaml:"reconcileInterval"`
+	ConfigReloadInterval    string `json:"configReloadInterval" yaml:"configReloadInterval"`
+	ReleaseWaitTimeout      string `json:"releaseWaitTimeout" yaml:"releaseWaitTimeout"`
+	ReleaseRetention        string `json:"releaseRetention" yaml:"releaseRetention"`
+	ClaimHistoryRetention   string `json:"claimHistoryRetention" yaml:"claimHistoryRetention"`
+	ClaimReadyTimeout       string `json:"claimReadyTimeout" yaml:"claimReadyTimeout"`
+	MaxClaimReadyTimeout    string `json:"maxClaimReadyTimeout" yaml:"maxClaimReadyTimeout"`
+	MaxSuspendDuration      string `json:"maxSuspendDuration" yaml:"maxSuspendDuration"`
+	MaxStartAtHorizon       string `json:"maxStartAtHorizon" yaml:"maxStartAtHorizon"`
+	WebhookURLs             string `json:"webhookURLs" yaml:"webhookURLs"`
+	WebhookSecret           string `json:"webhookSecret" yaml:"webhookSecret"`
+	SlackWebhookURL         string `json:"slackWebhookURL" yaml:"slackWebhookURL"`
+	SlackEvents             string `json:"slackEvents" yaml:"slackEvents"`
+	PublicURL               string `json:"publicURL" yaml:"publicURL"`
+	APIDocs                 string `json:"apiDocs" yaml:"apiDocs"`
+	AccessLog               string `json:"accessLog" yaml:"accessLog"`
+	AccessLogProbes         string `json:"accessLogProbes" yaml:"accessLogProbes"`
+	AuditLog                string `json:"auditLog" yaml:"auditLog"`
+	AuditWebhookURL         string `json:"auditWebhookURL" yaml:"auditWebhookURL"`
+	MaxRequestBodyBytes     string `json:"maxRequestBodyBytes" yaml:"maxRequestBodyBytes"`
+	RenderCheckInterval     string `json:"renderCheckInterval" yaml:"renderCheckInterval"`
+	ShutdownDrainTimeout    string `json:"shutdownDrainTimeout" yaml:"shutdownDrainTimeout"`
+	CORSAllowedOrigins      string `json:"corsAllowedOrigins" yaml:"corsAllowedOrigins"`
+	CORSAllowedMethods      string `json:"corsAllowedMethods" yaml:"corsAllowedMethods"`
+	CORSAllowedHeaders      string `json:"corsAllowedHeaders" yaml:"corsAllowedHeaders"`
+	APIKeys                 string `json:"apiKeys" yaml:"apiKeys"`
+	AuthTokenReview         string `json:"authTokenReview" yaml:"authTokenReview"`
+	AuthTokenAudiences      string `json:"authTokenAudiences" yaml:"authTokenAudiences"`
+	AuthRoutes              string `json:"authRoutes" yaml:"authRoutes"`
+	AdminSubjects           string `json:"adminSubjects" yaml:"adminSubjects"`
+	OIDCIssuerURL           string `json:"oidcIssuerURL" yaml:"oidcIssuerURL"`
+	OIDCAudience            string `json:"oidcAudience" yaml:"oidcAudience"`
+	OIDCUsernameClaim       string `json:"oidcUsernameClaim" yaml:"oidcUsernameClaim"`
+	ExpiryWarningWindow     string `json:"expiryWarningWindow" yaml:"expiryWarningWindow"`
+	ExpiryJitter            string `json:"expiryJitter" yaml:"expiryJitter"`
+	WorkingHours            string `json:"workingHours" yaml:"workingHours"`
+	WorkingHoursTimezone    string `json:"workingHoursTimezone" yaml:"workingHoursTimezone"`
+	HTTPProbeTimeout        string `json:"httpProbeTimeout" yaml:"httpProbeTimeout"`
+	CrashLoopRestartLimit   string `json:"crashLoopRestartLimit" yaml:"crashLoopRestartLimit"`
+	FailedClaimRetention    string `json:"failedClaimRetention" yaml:"failedClaimRetention"`
+	ZapLogLevel             string `json:"zapLogLevel" yaml:"zapLogLevel"`
+
+	Profiles []ProfileConfig `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	Pool     *PoolConfig     `json:"pool,omitempty" yaml:"pool,omitempty"`
+	Quotas   *QuotasConfig   `json:"quotas,omitempty" yaml:"quotas,omitempty"`
+	Auth     *AuthConfig     `json:"auth,omitempty" yaml:"auth,omitempty"`
 }
 
 func Load(path string) (AppConfig, error) {
@@ -31,12 +109,49 @@ func Load(path string) (AppConfig, error) {
 	if err != nil {
 		return cfg, fmt.Errorf("read config file: %w", err)
 	}
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	// Numbers are kept as written so 1000000 does not become "1e+06".
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw, useNumber); err != nil {
+		return cfg, fmt.Errorf("parse config file: %w", err)
+	}
+
+	normalized := make(map[string]any, len(raw))
+	for key, value := range raw {
+		if sectionKeys[key] {
+			normalized[key] = value
+			continue
+		}
+		switch v := value.(type) {
+		case nil:
+		case string:
+			normalized[key] = v
+		case bool, json.Number:
+			normalized[key] = fmt.Sprint(v)
+		default:
+			return cfg, fmt.Errorf("parse config file: %s must be a scalar value", key)
+		}
+	}
+
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
 		return cfg, fmt.Errorf("parse config file: %w", err)
 	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if err := cfg.applySections(); err != nil {
+		return cfg, fmt.Errorf("config file %s: %w", path, err)
+	}
 	return cfg, nil
 }
 
+func useNumber(d *json.Decoder) *json.Decoder {
+	d.UseNumber()
+	return d
+}
+
 func ParseDurationOrFallback(v string, fallback time.Duration) time.Duration {
 	if v == "" {
 		return fallback
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		check   func(AppConfig) string
+		wantErr string
+	}{
+		{
+			name:    "strings are kept",
+			content: "namespace: claims\ndefaultTTL: 1h\n",
+			check: func(cfg AppConfig) string {
+				if cfg.Namespace != "claims" || cfg.DefaultTTL != "1h" {
+					return cfg.Namespace + " " + cfg.DefaultTTL
+				}
+				return ""
+			},
+		},
+		{
+			name:    "large integers are not formatted in exponent notation",
+			content: "maxRequestBodyBytes: 1000000\n",
+			check: func(cfg AppConfig) string {
+				if cfg.MaxRequestBodyBytes != "1000000" {
+					return cfg.MaxRequestBodyBytes
+				}
+				return ""
+			},
+		},
+		{
+			name:    "integers beyond float precision are kept",
+			content: "maxRequestBodyBytes: 9007199254740993\n",
+			check: func(cfg AppConfig) string {
+				if cfg.MaxRequestBodyBytes != "9007199254740993" {
+					return cfg.MaxRequestBodyBytes
+				}
+				return ""
+			},
+		},
+		{
+			name:    "booleans and integers become strings",
+			content: "namespacePerClaim: true\npreProvisionClaimsCount: 3\n",
+			check: func(cfg AppConfig) string {
+				if cfg.NamespacePerClaim != "true" || cfg.PreProvisionClaimsCount != "3" {
+					return cfg.NamespacePerClaim + " " + cfg.PreProvisionClaimsCount
+				}
+				return ""
+			},
+		},
+		{
+			name:    "null values are ignored",
+			content: "namespace:\n",
+			check: func(cfg AppConfig) string {
+				return cfg.Namespace
+			},
+		},
+		{
+			name:    "sections fill the flat fields",
+			content: "pool:\n  size: 1000000\n  mode: pool-only\n",
+			check: func(cfg AppConfig) string {
+				if cfg.PreProvisionClaimsCount != "1000000" || cfg.ProvisioningMode != "pool-only" {
+					return cfg.PreProvisionClaimsCount + " " + cfg.ProvisioningMode
+				}
+				return ""
+			},
+		},
+		{
+			name:    "lists are rejected for flat keys",
+			content: "namespace:\n  - a\n",
+			wantErr: "namespace must be a scalar value",
+		},
+		{
+			name:    "unknown keys are rejected",
+			content: "nope: 1\n",
+			wantErr: "unknown field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := tt.check(cfg); got != "" {
+				t.Errorf("Load() got %q", got)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutPath(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, AppConfig{}) {
+		t.Errorf("Load() = %+v, want zero config", cfg)
+	}
+}
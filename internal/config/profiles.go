@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/nonot/claim-controller/internal/helmrelease"
+	"github.com/nonot/claim-controller/internal/template"
+	"github.com/nonot/claim-controller/internal/vcluster"
+)
+
+type ProfileConfig struct {
+	Name                string `json:"name" yaml:"name"`
+	Type                string `json:"type" yaml:"type"`
+	Backend             string `json:"backend" yaml:"backend"`
+	Namespace           string `json:"namespace" yaml:"namespace"`
+	Cluster             string `json:"cluster" yaml:"cluster"`
+	DefaultTTL          string `json:"defaultTTL" yaml:"defaultTTL"`
+	MaxTTL              string `json:"maxTTL" yaml:"maxTTL"`
+	Renderer            string `json:"renderer" yaml:"renderer"`
+	TemplatePath        string `json:"templatePath" yaml:"templatePath"`
+	TemplateRef         string `json:"templateRef" yaml:"templateRef"`
+	ValuesPath          string `json:"valuesPath" yaml:"valuesPath"`
+	ValuesConfigMapName string `json:"valuesConfigMapName" yaml:"valuesConfigMapName"`
+	ValuesConfigMapKey  string `json:"valuesConfigMapKey" yaml:"valuesConfigMapKey"`
+	ValuesSchemaPath    string `json:"valuesSchemaPath" yaml:"valuesSchemaPath"`
+	MinReady            string `json:"minReady" yaml:"minReady"`
+}
+
+type ProfilesConfig struct {
+	Profiles []ProfileConfig `json:"profiles" yaml:"profiles"`
+}
+
+func LoadProfiles(path string) ([]ProfileConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read profiles file: %w", err)
+	}
+
+	var cfg ProfilesConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse profiles file: %w", err)
+	}
+	if err := validateProfiles(cfg.Profiles); err != nil {
+		return nil, err
+	}
+	return cfg.Profiles, nil
+}
+
+func validateProfiles(profiles []ProfileConfig) error {
+	seen := map[string]bool{}
+	for i := range profiles {
+		profile := &profiles[i]
+		profile.Name = strings.TrimSpace(profile.Name)
+		if profile.Name == "" {
+			return fmt.Errorf("profile #%d has no name", i+1)
+		}
+		if seen[profile.Name] {
+			return fmt.Errorf("profile %q is defined more than once", profile.Name)
+		}
+		seen[profile.Name] = true
+
+		if profile.TemplatePath == "" && profile.TemplateRef == "" {
+			return fmt.Errorf("profile %q needs templatePath or templateRef", profile.Name)
+		}
+		if profile.ValuesPath == "" && (profile.ValuesConfigMapName == "" || profile.ValuesConfigMapKey == "") {
+			return fmt.Errorf("profile %q needs valuesPath or valuesConfigMapName and valuesConfigMapKey", profile.Name)
+		}
+		if err := validateTTLPair(profile.DefaultTTL, profile.MaxTTL); err != nil {
+			return fmt.Errorf("profile %q: %w", profile.Name, err)
+		}
+		switch profile.Type {
+		case "", vcluster.ProfileType:
+		default:
+			return fmt.Errorf("profile %q has unknown type %q", profile.Name, profile.Type)
+		}
+		if profile.Type == vcluster.ProfileType && profile.Cluster != "" {
+			return fmt.Errorf("profile %q: vcluster profiles cannot target a remote cluster", profile.Name)
+		}
+		backend, err := helmrelease.ParseBackend(profile.Backend)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", profile.Name, err)
+		}
+		profile.Backend = backend
+		if backend == helmrelease.BackendHelmRelease {
+			if renderer, err := template.ParseRendererKind(profile.Renderer); err != nil || renderer != template.RendererHelm {
+				return fmt.Errorf("profile %q: the helm-release backend requires the helm renderer", profile.Name)
+			}
+			if profile.Cluster != "" {
+				return fmt.Errorf("profile %q: helm-release profiles cannot target a remote cluster", profile.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateTTLPair(defaultTTL, maxTTL string) error {
+	if err := validatePositiveDuration("defaultTTL", defaultTTL); err != nil {
+		return err
+	}
+	return validatePositiveDuration("maxTTL", maxTTL)
+}
+
+func validatePositiveDuration(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("%s must be greater than 0", name)
+	}
+	return nil
+}
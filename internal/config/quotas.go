@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+type QuotaLimitConfig struct {
+	MaxActiveClaims int    `json:"maxActiveClaims" yaml:"maxActiveClaims"`
+	MaxTotalTTL     string `json:"maxTotalTTL" yaml:"maxTotalTTL"`
+}
+
+type QuotasConfig struct {
+	Default *QuotaLimitConfig           `json:"default" yaml:"default"`
+	Users   map[string]QuotaLimitConfig `json:"users" yaml:"users"`
+	Teams   map[string]QuotaLimitConfig `json:"teams" yaml:"teams"`
+}
+
+func LoadQuotas(path string) (*QuotasConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read quotas file: %w", err)
+	}
+
+	var cfg QuotasConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse quotas file: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *QuotasConfig) validate() error {
+	if c.Default != nil {
+		if err := c.Default.validate(); err != nil {
+			return fmt.Errorf("default quota: %w", err)
+		}
+	}
+	for name, limit := range c.Users {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("quota of user %q: %w", name, err)
+		}
+	}
+	for name, limit := range c.Teams {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("quota of team %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (l QuotaLimitConfig) validate() error {
+	if l.MaxActiveClaims < 0 {
+		return fmt.Errorf("maxActiveClaims must not be negative")
+	}
+	if l.MaxTotalTTL != "" {
+		if _, err := time.ParseDuration(l.MaxTotalTTL); err != nil {
+			return fmt.Errorf("invalid maxTotalTTL: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l QuotaLimitConfig) TotalTTL() time.Duration {
+	return ParseDurationOrFallback(l.MaxTotalTTL, 0)
+}
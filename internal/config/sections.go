@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sectionKeys = map[string]bool{
+	"profiles": true,
+	"pool":     true,
+	"quotas":   true,
+	"auth":     true,
+}
+
+type PoolConfig struct {
+	Size              *int   `json:"size,omitempty" yaml:"size,omitempty"`
+	Reserve           *int   `json:"reserve,omitempty" yaml:"reserve,omitempty"`
+	Mode              string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	MaxIdleAge        string `json:"maxIdleAge,omitempty" yaml:"maxIdleAge,omitempty"`
+	ReplenishInterval string `json:"replenishInterval,omitempty" yaml:"replenishInterval,omitempty"`
+	QueueTimeout      string `json:"queueTimeout,omitempty" yaml:"queueTimeout,omitempty"`
+}
+
+type AuthConfig struct {
+	APIKeys        []string    `json:"apiKeys,omitempty" yaml:"apiKeys,omitempty"`
+	TokenReview    *bool       `json:"tokenReview,omitempty" yaml:"tokenReview,omitempty"`
+	TokenAudiences []string    `json:"tokenAudiences,omitempty" yaml:"tokenAudiences,omitempty"`
+	Routes         []string    `json:"routes,omitempty" yaml:"routes,omitempty"`
+	OIDC           *OIDCConfig `json:"oidc,omitempty" yaml:"oidc,omitempty"`
+}
+
+type OIDCConfig struct {
+	IssuerURL     string `json:"issuerURL,omitempty" yaml:"issuerURL,omitempty"`
+	Audience      string `json:"audience,omitempty" yaml:"audience,omitempty"`
+	UsernameClaim string `json:"usernameClaim,omitempty" yaml:"usernameClaim,omitempty"`
+}
+
+func (c *AppConfig) applySections() error {
+	if len(c.Profiles) > 0 {
+		if c.ProfilesPath != "" {
+			return fmt.Errorf("profiles and profilesPath cannot both be set")
+		}
+		if err := validateProfiles(c.Profiles); err != nil {
+			return err
+		}
+	}
+	if c.Quotas != nil {
+		if c.QuotasPath != "" {
+			return fmt.Errorf("quotas and quotasPath cannot both be set")
+		}
+		if err := c.Quotas.validate(); err != nil {
+			return err
+		}
+	}
+
+	var fields []sectionField
+	if pool := c.Pool; pool != nil {
+		fields = append(fields,
+			sectionField{"pool.size", "preProvisionClaimsCount", &c.PreProvisionClaimsCount, intValue(pool.Size)},
+			sectionField{"pool.reserve", "poolReserve", &c.PoolReserve, intValue(pool.Reserve)},
+			sectionField{"pool.mode", "provisioningMode", &c.ProvisioningMode, pool.Mode},
+			sectionField{"pool.maxIdleAge", "poolMaxIdleAge", &c.PoolMaxIdleAge, pool.MaxIdleAge},
+			sectionField{"pool.replenishInterval", "poolReplenishInterval", &c.PoolReplenishInterval, pool.ReplenishInterval},
+			sectionField{"pool.queueTimeout", "claimQueueTimeout", &c.ClaimQueueTimeout, pool.QueueTimeout},
+		)
+	}
+	if auth := c.Auth; auth != nil {
+		fields = append(fields,
+			sectionField{"auth.apiKeys", "apiKeys", &c.APIKeys, strings.Join(auth.APIKeys, ",")},
+			sectionField{"auth.tokenReview", "authTokenReview", &c.AuthTokenReview, boolValue(auth.TokenReview)},
+			sectionField{"auth.tokenAudiences", "authTokenAudiences", &c.AuthTokenAudiences, strings.Join(auth.TokenAudiences, ",")},
+			sectionField{"auth.routes", "authRoutes", &c.AuthRoutes, strings.Join(auth.Routes, ",")},
+		)
+		if oidc := auth.OIDC; oidc != nil {
+			fields = append(fields,
+				sectionField{"auth.oidc.issuerURL", "oidcIssuerURL", &c.OIDCIssuerURL, oidc.IssuerURL},
+				sectionField{"auth.oidc.audience", "oidcAudience", &c.OIDCAudience, oidc.Audience},
+				sectionField{"auth.oidc.usernameClaim", "oidcUsernameClaim", &c.OIDCUsernameClaim, oidc.UsernameClaim},
+			)
+		}
+	}
+
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		if *field.target != "" {
+			return fmt.Errorf("%s and %s cannot both be set", field.section, field.flat)
+		}
+		*field.target = field.value
+	}
+	return nil
+}
+
+type sectionField struct {
+	section string
+	flat    string
+	target  *string
+	value   string
+}
+
+func intValue(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func boolValue(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+type TenantConfig struct {
+	Name       string            `json:"name" yaml:"name"`
+	Namespace  string            `json:"namespace" yaml:"namespace"`
+	Profile    string            `json:"profile" yaml:"profile"`
+	DefaultTTL string            `json:"defaultTTL" yaml:"defaultTTL"`
+	MaxTTL     string            `json:"maxTTL" yaml:"maxTTL"`
+	Subjects   []string          `json:"subjects" yaml:"subjects"`
+	Quota      *QuotaLimitConfig `json:"quota" yaml:"quota"`
+}
+
+type TenantsConfig struct {
+	Tenants []TenantConfig `json:"tenants" yaml:"tenants"`
+}
+
+func LoadTenants(path string) ([]TenantConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tenants file: %w", err)
+	}
+
+	var cfg TenantsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse tenants file: %w", err)
+	}
+
+	seen := map[string]bool{}
+	subjects := map[string]string{}
+	for i := range cfg.Tenants {
+		tenant := &cfg.Tenants[i]
+		tenant.Name = strings.TrimSpace(tenant.Name)
+		if tenant.Name == "" {
+			return nil, fmt.Errorf("tenant #%d has no name", i+1)
+		}
+		if seen[tenant.Name] {
+			return nil, fmt.Errorf("tenant %q is defined more than once", tenant.Name)
+		}
+		seen[tenant.Name] = true
+
+		for _, subject := range tenant.Subjects {
+			if other, ok := subjects[subject]; ok {
+				return nil, fmt.Errorf("subject %q is mapped to tenants %q and %q", subject, other, tenant.Name)
+			}
+			subjects[subject] = tenant.Name
+		}
+		if err := validateTTLPair(tenant.DefaultTTL, tenant.MaxTTL); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", tenant.Name, err)
+		}
+		if tenant.Quota != nil {
+			if err := tenant.Quota.validate(); err != nil {
+				return nil, fmt.Errorf("quota of tenant %q: %w", tenant.Name, err)
+			}
+		}
+	}
+
+	return cfg.Tenants, nil
+}
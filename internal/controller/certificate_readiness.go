@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const certManagerGroup = "cert-manager.io"
+
+func assessCertificateReadiness(obj *unstructured.Unstructured) (bool, string) {
+	secretName, _, _ := unstructured.NestedString(obj.Object, "spec", "secretName")
+
+	ready, found := conditionStatus(obj.Object, "status", "conditions", "Ready")
+	if found && ready {
+		notAfter, _, _ := unstructured.NestedString(obj.Object, "status", "notAfter")
+		if notAfter != "" {
+			return true, fmt.Sprintf("certificate issued into secret %s (expires %s)", secretName, notAfter)
+		}
+		return true, fmt.Sprintf("certificate issued into secret %s", secretName)
+	}
+
+	if issuing, found := conditionStatus(obj.Object, "status", "conditions", "Issuing"); found && issuing {
+		return false, conditionMessage(obj, "Issuing", "certificate issuing")
+	}
+	if found {
+		return false, conditionMessage(obj, "Ready", "certificate not ready")
+	}
+	return false, "waiting for certificate to be issued"
+}
@@ -3,22 +3,31 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"helm.sh/helm/v3/pkg/chart"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	"github.com/nonot/claim-controller/internal/cluster"
+	"github.com/nonot/claim-controller/internal/helmrelease"
+	"github.com/nonot/claim-controller/internal/notify"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -32,15 +41,46 @@ var (
 		Name: "claim_controller_active_resources",
 		Help: "Number of managed resources currently present.",
 	})
+	activeClaimsByVersionGauge = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "claim_controller_active_claims_by_version",
+		Help: "Number of managed claims currently present, by profile and template/values hash used at render time.",
+	}, []string{"profile", "template_hash", "values_hash"})
+	claimsByStatusGauge = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "claim_controller_claims",
+		Help: "Number of managed claims currently present, by status and whether they belong to the pre-provisioned pool.",
+	}, []string{"status", "preprovisioned"})
+	resourceErrorsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "claim_controller_resource_errors_total",
+		Help: "Total number of failed API calls on claim resources, by resource kind and operation (create, get or delete).",
+	}, []string{"kind", "operation"})
 )
 
 type ClaimReconciler struct {
 	client.Client
-	Scheme            *runtime.Scheme
-	Namespace         string
-	DefaultTTL        time.Duration
-	ReconcileInterval time.Duration
-	Recorder          record.EventRecorder
+	APIReader           client.Reader
+	Scheme              *runtime.Scheme
+	Namespace           string
+	WatchNamespaces     []string
+	Clusters            *cluster.Registry
+	DefaultTTL          time.Duration
+	ReconcileInterval   time.Duration
+	HTTPProbeTimeout    time.Duration
+	CrashLoopRestarts   int
+	FailedRetention     time.Duration
+	HistoryRetention    time.Duration
+	ExpiryWarningWindow time.Duration
+	WorkingHours        *WorkingHours
+	ExpiryJitter        time.Duration
+	HelmReleases        *helmrelease.Client
+	ReleaseChart        func(claim *corev1.ConfigMap) (*chart.Chart, error)
+	Notifier            notify.Notifier
+	Recorder            record.EventRecorder
+
+	deletingClaims sync.Map
+	watcher        crcontroller.Controller
+	cache          cache.Cache
+	watchedKinds   sync.Map
+	tunables       atomic.Pointer[Tunables]
 }
 
 type resourceReadiness struct {
@@ -48,66 +88,105 @@ type resourceReadiness struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace,omitempty"`
 	Ready     bool   `json:"ready"`
+	Failed    bool   `json:"failed,omitempty"`
 	Message   string `json:"message"`
+	Created   bool   `json:"-"`
 }
 
 func (r *ClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	if req.Namespace != r.Namespace {
+	if !r.watchesNamespace(req.Namespace) {
 		return ctrl.Result{}, nil
 	}
 
-	if err := r.cleanupExpiredClaims(ctx); err != nil {
-		return ctrl.Result{}, err
-	}
-
 	claim := &corev1.ConfigMap{}
 	err := r.Get(ctx, req.NamespacedName, claim)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			_ = r.refreshMetrics(ctx)
+			r.observeClaimCleanup(req.Name)
+			r.forgetClaimMetrics(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
 
 	if claim.Labels[ManagedByLabelKey] != ManagedByLabelValue {
+		r.forgetClaimMetrics(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
+	defer r.observeClaimMetrics(ctx, claim)
+	if requestID := claim.Annotations[RequestIDAnnotationKey]; requestID != "" {
+		ctx = logf.IntoContext(ctx, logf.FromContext(ctx).WithValues("requestID", requestID))
+	}
+
+	if claim.DeletionTimestamp != nil {
+		if err := r.reconcileDeletingClaim(ctx, claim); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
 
 	isPreProvisioned := isPreProvisionedClaim(claim)
 
-	expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[ExpiresAtAnnotationKey])
-	if err != nil {
-		expiresAt = time.Now().UTC().Add(r.DefaultTTL)
+	expiresAt := r.claimExpiresAt(claim)
+	suspendUntil, suspended := suspendedUntil(claim, time.Now().UTC())
+	if expired, _ := r.expiryState(claim, time.Now().UTC()); expired && !isPreProvisioned {
+		return ctrl.Result{}, nil
 	}
 
-	if !isPreProvisioned && time.Now().UTC().After(expiresAt) {
-		if err := r.cleanupClaimResources(ctx, claim); err != nil {
+	if startAt, scheduled := scheduledStart(claim, time.Now().UTC()); scheduled {
+		if err := r.markClaimScheduled(ctx, claim, startAt); err != nil {
 			return ctrl.Result{}, err
 		}
-		if err := r.Delete(ctx, claim); client.IgnoreNotFound(err) != nil {
-			return ctrl.Result{}, err
-		}
-		_ = r.refreshMetrics(ctx)
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: time.Until(startAt)}, nil
 	}
 
 	if err := r.ensureClaimResources(ctx, claim); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	allReady, summary, resourcesStatus, err := r.evaluateClaimReadiness(ctx, claim)
+	allReady, summary, resourcesStatus, liveReturnValues, err := r.evaluateClaimReadiness(ctx, claim)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	if err := r.updateClaimReadinessStatus(ctx, claim, allReady, summary, resourcesStatus); err != nil {
+	// Failed claims are rechecked slowly and recover once their resources are ready.
+	if isFailedClaim(claim) {
+		if !allReady {
+			return ctrl.Result{RequeueAfter: failedClaimRecheckInterval}, nil
+		}
+		if err := r.clearClaimFailure(ctx, claim); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// Claims that were ready once are left alone so a crash never fails an environment in use.
+	if failed := failedResource(resourcesStatus); failed != nil && !allReady && !wasReady(claim.Data[ClaimStatusDataKey]) {
+		if isPreProvisioned {
+			return ctrl.Result{}, r.discardFailedPoolClaim(ctx, claim, failed.Message)
+		}
+		return ctrl.Result{}, r.markClaimFailed(ctx, claim, *failed, resourcesStatus)
+	}
+	expiringSoon := !suspended && r.isExpiringSoon(claim, expiresAt)
+	if expiringSoon {
+		summary = expiringSoonMessage(summary, expiresAt)
+	}
+	if err := r.updateClaimReadinessStatus(ctx, claim, allReady, summary, resourcesStatus, liveReturnValues, expiringSoon, expiresAt); err != nil {
 		return ctrl.Result{}, err
 	}
+	if expiringSoon {
+		if err := r.markClaimExpiringSoon(ctx, claim, expiresAt); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
-	_ = r.refreshMetrics(ctx)
+	reconcileInterval := r.currentTunables().ReconcileInterval
 	nextCheck := time.Until(expiresAt)
 	if isPreProvisioned {
-		nextCheck = r.ReconcileInterval
+		nextCheck = reconcileInterval
+	}
+	if !isPreProvisioned && !expiringSoon && r.ExpiryWarningWindow > 0 {
+		nextCheck = min(nextCheck, time.Until(expiresAt)-r.ExpiryWarningWindow)
+	}
+	if suspended {
+		nextCheck = max(nextCheck, time.Until(suspendUntil))
 	}
 	if nextCheck < 5*time.Second {
 		nextCheck = 5 * time.Second
@@ -115,17 +194,23 @@ func (r *ClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	if !allReady && nextCheck > 3*time.Second {
 		nextCheck = 3 * time.Second
 	}
-	if r.ReconcileInterval > 0 && r.ReconcileInterval < nextCheck {
-		nextCheck = r.ReconcileInterval
+	if reconcileInterval > 0 && reconcileInterval < nextCheck {
+		nextCheck = reconcileInterval
 	}
 
 	return ctrl.Result{RequeueAfter: nextCheck}, nil
 }
 
-func (r *ClaimReconciler) evaluateClaimReadiness(ctx context.Context, claim *corev1.ConfigMap) (bool, string, []resourceReadiness, error) {
-	resources, err := templatesFromClaim(claim)
+func (r *ClaimReconciler) evaluateClaimReadiness(ctx context.Context, claim *corev1.ConfigMap) (bool, string, []resourceReadiness, map[string]string, error) {
+	resources, err := r.templatesFromClaim(ctx, claim)
 	if err != nil {
-		return false, "", nil, err
+		return false, "", nil, nil, err
+	}
+	resources = installResources(resources)
+
+	target, err := r.targetFor(ctx, claim)
+	if err != nil {
+		return false, "", nil, nil, err
 	}
 
 	isPreProvisioned := isPreProvisionedClaim(claim)
@@ -133,6 +218,7 @@ func (r *ClaimReconciler) evaluateClaimReadiness(ctx context.Context, claim *cor
 	allReady := true
 	readyCount := 0
 	statuses := make([]resourceReadiness, 0, len(resources))
+	liveReturnValues := map[string]string{}
 
 	for _, resourceTemplate := range resources {
 		if isPreProvisioned && isLazyProvisionedResource(resourceTemplate) {
@@ -143,15 +229,15 @@ func (r *ClaimReconciler) evaluateClaimReadiness(ctx context.Context, claim *cor
 		resourceObj.SetGroupVersionKind(resourceTemplate.GroupVersionKind())
 		resourceObj.SetName(resourceTemplate.GetName())
 
-		isNamespaced, err := r.isNamespacedResource(resourceObj)
+		isNamespaced, err := target.isNamespaced(resourceObj)
 		if err != nil {
-			return false, "", nil, fmt.Errorf("resolve resource scope for %s %s: %w", resourceObj.GetKind(), resourceObj.GetName(), err)
+			return false, "", nil, nil, fmt.Errorf("resolve resource scope for %s %s: %w", resourceObj.GetKind(), resourceObj.GetName(), err)
 		}
 		if isNamespaced {
-			resourceObj.SetNamespace(claim.Namespace)
+			resourceObj.SetNamespace(resourceNamespace(claim))
 		}
 
-		if err := r.Get(ctx, client.ObjectKeyFromObject(resourceObj), resourceObj); err != nil {
+		if err := target.Get(ctx, client.ObjectKeyFromObject(resourceObj), resourceObj); err != nil {
 			if apierrors.IsNotFound(err) {
 				allReady = false
 				statuses = append(statuses, resourceReadiness{
@@ -159,18 +245,34 @@ func (r *ClaimReconciler) evaluateClaimReadiness(ctx context.Context, claim *cor
 					Name:      resourceTemplate.GetName(),
 					Namespace: resourceObj.GetNamespace(),
 					Ready:     false,
-					Message:   "not created yet",
+					Message:   resourceNotCreatedMessage,
 				})
 				continue
 			}
-			return false, "", nil, err
+			resourceErrorsTotal.WithLabelValues(resourceObj.GetKind(), "get").Inc()
+			return false, "", nil, nil, err
 		}
 
-		ready, message := assessResourceReadiness(resourceObj)
+		ready, message, values, err := r.assessClaimResource(ctx, target, claim, resourceTemplate, resourceObj)
+		if err != nil {
+			return false, "", nil, nil, err
+		}
+		for key, value := range values {
+			liveReturnValues[key] = value
+		}
+
+		failed := false
 		if ready {
 			readyCount++
 		} else {
 			allReady = false
+			reason, err := r.terminalFailure(ctx, target, resourceObj)
+			if err != nil {
+				return false, "", nil, nil, err
+			}
+			if reason != "" {
+				failed, message = true, reason
+			}
 		}
 
 		statuses = append(statuses, resourceReadiness{
@@ -178,55 +280,159 @@ func (r *ClaimReconciler) evaluateClaimReadiness(ctx context.Context, claim *cor
 			Name:      resourceObj.GetName(),
 			Namespace: resourceObj.GetNamespace(),
 			Ready:     ready,
+			Failed:    failed,
 			Message:   message,
+			Created:   true,
+		})
+	}
+
+	minReady := r.claimMinReady(ctx, claim)
+	partial := !allReady && minReady != nil && minReady.satisfied(statuses)
+	if partial {
+		allReady = true
+	}
+
+	if allReady && isVClusterClaim(claim) {
+		ready, message, err := assessVClusterReadiness(ctx, target, claim)
+		if err != nil {
+			return false, "", nil, nil, err
+		}
+		if !ready {
+			allReady = false
+		}
+		statuses = append(statuses, resourceReadiness{
+			Kind:      "VCluster",
+			Name:      claim.Name,
+			Namespace: resourceNamespace(claim),
+			Ready:     ready,
+			Message:   message,
+			Created:   true,
 		})
 	}
 
 	summary := fmt.Sprintf("%d/%d resources ready", readyCount, len(resources))
-	if allReady {
+	if allReady && partial {
+		summary = fmt.Sprintf("%d/%d resources ready, minReady %s met", readyCount, len(resources), minReady)
+	} else if allReady {
 		summary = "all resources ready"
 	}
 
-	return allReady, summary, statuses, nil
+	return allReady, summary, statuses, liveReturnValues, nil
 }
 
-func (r *ClaimReconciler) updateClaimReadinessStatus(ctx context.Context, claim *corev1.ConfigMap, allReady bool, summary string, resources []resourceReadiness) error {
+func (r *ClaimReconciler) updateClaimReadinessStatus(ctx context.Context, claim *corev1.ConfigMap, allReady bool, summary string, resources []resourceReadiness, liveReturnValues map[string]string, expiringSoon bool, expiresAt time.Time) error {
 	resourcesJSON, err := json.Marshal(resources)
 	if err != nil {
 		return err
 	}
 
-	statusValue := "pending"
-	if allReady {
-		statusValue = "ready"
+	current := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(claim), current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	previous := current.Data[ClaimStatusDataKey]
+	statusValue := claimStatusValue(previous, allReady)
+	conditionsJSON, err := mergeClaimConditions(current, readinessConditions(statusValue, summary, resources, expiringSoon, expiresAt)...)
+	if err != nil {
+		return err
 	}
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		current := &corev1.ConfigMap{}
-		if err := r.Get(ctx, client.ObjectKeyFromObject(claim), current); err != nil {
-			return client.IgnoreNotFound(err)
+	returnValuesJSON := current.Data[ReturnValuesDataKey]
+	if allReady && len(liveReturnValues) > 0 {
+		returnValues := map[string]string{}
+		if strings.TrimSpace(returnValuesJSON) != "" {
+			_ = json.Unmarshal([]byte(returnValuesJSON), &returnValues)
+		}
+		for key, value := range liveReturnValues {
+			returnValues[key] = value
 		}
+		merged, err := json.Marshal(returnValues)
+		if err != nil {
+			return err
+		}
+		returnValuesJSON = string(merged)
+	}
+
+	if current.Data[ClaimStatusDataKey] == statusValue &&
+		current.Data[ClaimStatusMessageDataKey] == summary &&
+		current.Data[ClaimResourcesStatusDataKey] == string(resourcesJSON) &&
+		current.Data[ReturnValuesDataKey] == returnValuesJSON &&
+		current.Data[ClaimConditionsDataKey] == conditionsJSON {
+		return nil
+	}
+
+	becameReady := allReady && previous != statusValue
+	err = r.patchClaimStatus(ctx, current, map[string]string{
+		ReturnValuesDataKey:         returnValuesJSON,
+		ClaimStatusDataKey:          statusValue,
+		ClaimStatusMessageDataKey:   summary,
+		ClaimResourcesStatusDataKey: string(resourcesJSON),
+		ClaimConditionsDataKey:      conditionsJSON,
+	})
+	if err != nil {
+		return err
+	}
 
-		if current.Data == nil {
-			current.Data = map[string]string{}
+	r.recordTransition(claim, previous, statusValue, summary, resources)
+	if becameReady {
+		r.notify(ctx, notify.EventClaimReady, claim, summary)
+	}
+	return nil
+}
+
+func (r *ClaimReconciler) assessClaimResource(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap, resourceTemplate, resourceObj *unstructured.Unstructured) (bool, string, map[string]string, error) {
+	var ready bool
+	var message string
+	if expression := strings.TrimSpace(resourceTemplate.GetAnnotations()[ReadyWhenAnnotationKey]); expression != "" {
+		ready, message = evaluateReadyWhen(resourceObj, expression)
+	} else {
+		var err error
+		ready, message, err = r.assessResource(ctx, target, resourceObj)
+		if err != nil {
+			return false, "", nil, err
 		}
+	}
+	if probeURL := strings.TrimSpace(resourceTemplate.GetAnnotations()[HTTPProbeAnnotationKey]); probeURL != "" && ready {
+		ready, message = r.runHTTPProbe(ctx, claim.Name, resourceObj, probeURL)
+	}
 
-		if current.Data[ClaimStatusDataKey] == statusValue &&
-			current.Data[ClaimStatusMessageDataKey] == summary &&
-			current.Data[ClaimResourcesStatusDataKey] == string(resourcesJSON) {
-			return nil
+	var values map[string]string
+	if directive := strings.TrimSpace(resourceTemplate.GetAnnotations()[ReturnFromAnnotationKey]); directive != "" && ready {
+		resolved, err := resolveReturnFromDirective(resourceObj, directive)
+		if err != nil {
+			ready = false
+			message = err.Error()
 		}
+		values = resolved
+	}
+	return ready, message, values, nil
+}
 
-		current.Data[ClaimStatusDataKey] = statusValue
-		current.Data[ClaimStatusMessageDataKey] = summary
-		current.Data[ClaimResourcesStatusDataKey] = string(resourcesJSON)
+func (r *ClaimReconciler) assessResource(ctx context.Context, target *resourceTarget, obj *unstructured.Unstructured) (bool, string, error) {
+	if strings.EqualFold(obj.GetKind(), "Service") && obj.GroupVersionKind().Group == "" {
+		return assessServiceReadiness(ctx, target, obj)
+	}
+	if strings.EqualFold(obj.GetKind(), "Certificate") && obj.GroupVersionKind().Group == certManagerGroup {
+		ready, message := assessCertificateReadiness(obj)
+		return ready, message, nil
+	}
+	if strings.EqualFold(obj.GetKind(), "PersistentVolumeClaim") && obj.GroupVersionKind().Group == "" {
+		ready, message := assessPersistentVolumeClaimReadiness(ctx, target, obj)
+		return ready, message, nil
+	}
 
-		return r.Update(ctx, current)
-	})
+	ready, message := assessResourceReadiness(obj)
+	return ready, message, nil
 }
 
 func assessResourceReadiness(obj *unstructured.Unstructured) (bool, string) {
+	if isCrossplaneResource(obj) {
+		return assessCrossplaneReadiness(obj)
+	}
+
 	switch strings.ToLower(obj.GetKind()) {
+	case "ingress":
+		return assessIngressReadiness(obj)
 	case "pod":
 		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
 		if phase == "Succeeded" {
@@ -251,9 +457,54 @@ func assessResourceReadiness(obj *unstructured.Unstructured) (bool, string) {
 			return true, fmt.Sprintf("deployment ready (%d/%d)", readyReplicas, desiredReplicas)
 		}
 		return false, fmt.Sprintf("deployment not ready (%d/%d)", readyReplicas, desiredReplicas)
-	default:
-		return true, "resource exists"
-	}
+	case "statefulset":
+		desiredReplicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			desiredReplicas = 1
+		}
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if readyReplicas >= desiredReplicas {
+			return true, fmt.Sprintf("statefulset ready (%d/%d)", readyReplicas, desiredReplicas)
+		}
+		return false, fmt.Sprintf("statefulset not ready (%d/%d)", readyReplicas, desiredReplicas)
+	case "replicaset":
+		desiredReplicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			desiredReplicas = 1
+		}
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if readyReplicas >= desiredReplicas {
+			return true, fmt.Sprintf("replicaset ready (%d/%d)", readyReplicas, desiredReplicas)
+		}
+		return false, fmt.Sprintf("replicaset not ready (%d/%d)", readyReplicas, desiredReplicas)
+	case "daemonset":
+		desiredNumber, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		numberReady, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+		observedGeneration, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+		if observedGeneration >= obj.GetGeneration() && numberReady >= desiredNumber {
+			return true, fmt.Sprintf("daemonset ready (%d/%d)", numberReady, desiredNumber)
+		}
+		return false, fmt.Sprintf("daemonset not ready (%d/%d)", numberReady, desiredNumber)
+	case "job":
+		if failed, found := conditionStatus(obj.Object, "status", "conditions", "Failed"); found && failed {
+			return false, "job failed"
+		}
+		if complete, found := conditionStatus(obj.Object, "status", "conditions", "Complete"); found && complete {
+			return true, "job complete"
+		}
+		succeeded, _, _ := unstructured.NestedInt64(obj.Object, "status", "succeeded")
+		active, _, _ := unstructured.NestedInt64(obj.Object, "status", "active")
+		return false, fmt.Sprintf("job running (active=%d succeeded=%d)", active, succeeded)
+	}
+
+	// Kinds without a dedicated check fall back to the kstatus generic rules.
+	switch status, message := computeGenericStatus(obj); status {
+	case genericStatusCurrent:
+		return true, message
+	case genericStatusInProgress, genericStatusFailed:
+		return false, message
+	}
+	return true, "resource exists"
 }
 
 func conditionStatus(object map[string]any, section string, field string, conditionType string) (bool, bool) {
@@ -280,28 +531,60 @@ func conditionStatus(object map[string]any, section string, field string, condit
 	return false, false
 }
 
-func (r *ClaimReconciler) cleanupExpiredClaims(ctx context.Context) error {
-	claims := &corev1.ConfigMapList{}
-	if err := r.List(ctx, claims, client.InNamespace(r.Namespace), client.MatchingLabels{ManagedByLabelKey: ManagedByLabelValue}); err != nil {
+func (r *ClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	watcher, err := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.ConfigMap{}).
+		Build(r)
+	if err != nil {
 		return err
 	}
+	r.watcher = watcher
+	r.cache = mgr.GetCache()
+	return nil
+}
 
-	now := time.Now().UTC()
-	for i := range claims.Items {
-		claim := &claims.Items[i]
-		if isPreProvisionedClaim(claim) {
-			continue
-		}
+func (r *ClaimReconciler) ensureClaimResources(ctx context.Context, claim *corev1.ConfigMap) error {
+	resources, err := r.templatesFromClaim(ctx, claim)
+	if err != nil {
+		return err
+	}
 
-		expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[ExpiresAtAnnotationKey])
-		if err != nil || now.Before(expiresAt) {
-			continue
+	target, err := r.targetFor(ctx, claim)
+	if err != nil {
+		return err
+	}
+	if target.remote() && controllerutil.AddFinalizer(claim, RemoteCleanupFinalizer) {
+		if err := r.Update(ctx, claim); err != nil {
+			return err
 		}
+	}
+	if err := r.ensureClaimNamespace(ctx, target, claim); err != nil {
+		return fmt.Errorf("ensure claim namespace: %w", err)
+	}
+	if isHelmReleaseClaim(claim) {
+		return r.ensureClaimRelease(ctx, claim)
+	}
 
-		if err := r.cleanupClaimResources(ctx, claim); err != nil {
+	if len(preDeleteHooks(resources)) > 0 && controllerutil.AddFinalizer(claim, PreDeleteHooksFinalizer) {
+		if err := r.Update(ctx, claim); err != nil {
 			return err
 		}
-		if err := r.Delete(ctx, claim); client.IgnoreNotFound(err) != nil {
+	}
+
+	isPreProvisioned := isPreProvisionedClaim(claim)
+
+	waves := resourceWaves(installResources(resources))
+	for i, wave := range waves {
+		if i > 0 {
+			ready, err := r.waveReady(ctx, target, claim, waves[i-1])
+			if err != nil {
+				return err
+			}
+			if !ready {
+				return nil
+			}
+		}
+		if err := r.ensureWaveResources(ctx, target, claim, wave, isPreProvisioned); err != nil {
 			return err
 		}
 	}
@@ -309,80 +592,71 @@ func (r *ClaimReconciler) cleanupExpiredClaims(ctx context.Context) error {
 	return nil
 }
 
-func (r *ClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.ConfigMap{}).
-		Complete(r)
-}
-
-func (r *ClaimReconciler) ensureClaimResources(ctx context.Context, claim *corev1.ConfigMap) error {
-	claimName := claim.Name
-	resources, err := templatesFromClaim(claim)
-	if err != nil {
-		return err
-	}
-
-	isPreProvisioned := isPreProvisionedClaim(claim)
-
+func (r *ClaimReconciler) ensureWaveResources(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap, resources []*unstructured.Unstructured, isPreProvisioned bool) error {
 	for _, resourceTemplate := range resources {
 		if isPreProvisioned && isLazyProvisionedResource(resourceTemplate) {
 			continue
 		}
 
 		resourceObj := resourceTemplate.DeepCopy()
-		isNamespaced, err := r.isNamespacedResource(resourceObj)
+		isNamespaced, err := target.isNamespaced(resourceObj)
 		if err != nil {
 			return fmt.Errorf("resolve resource scope for %s %s: %w", resourceObj.GetKind(), resourceObj.GetName(), err)
 		}
 
-		lookupKey := client.ObjectKey{Name: resourceObj.GetName()}
 		if isNamespaced {
-			resourceObj.SetNamespace(claim.Namespace)
-			lookupKey.Namespace = claim.Namespace
+			resourceObj.SetNamespace(resourceNamespace(claim))
 		}
 
-		existing := &unstructured.Unstructured{}
-		existing.SetGroupVersionKind(resourceObj.GroupVersionKind())
-		err = r.Get(ctx, lookupKey, existing)
-		if err == nil {
-			continue
-		}
-		if !apierrors.IsNotFound(err) {
+		exists, err := r.resourceExists(ctx, target, resourceObj)
+		if err != nil {
 			return err
 		}
+		if exists {
+			continue
+		}
 
 		labels := resourceObj.GetLabels()
 		if labels == nil {
 			labels = map[string]string{}
 		}
 		labels[ManagedByLabelKey] = ManagedByLabelValue
-		labels[ClaimLabelKey] = claimName
+		labels[ClaimLabelKey] = claim.Name
+		labels[ClaimNamespaceLabelKey] = claim.Namespace
 		resourceObj.SetLabels(labels)
 
-		if err := ctrl.SetControllerReference(claim, resourceObj, r.Scheme); err != nil {
-			return err
+		if claim.DeletionTimestamp == nil && !hasDedicatedNamespace(claim) && !target.remote() {
+			if err := ctrl.SetControllerReference(claim, resourceObj, r.Scheme); err != nil {
+				return err
+			}
 		}
-		if err := r.Create(ctx, resourceObj); err != nil {
+		if err := target.Create(ctx, resourceObj); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			resourceErrorsTotal.WithLabelValues(resourceObj.GetKind(), "create").Inc()
 			return err
 		}
-		r.Recorder.Eventf(claim, corev1.EventTypeNormal, "CreatedResource", "Created %s %s", resourceObj.GetKind(), resourceObj.GetName())
+		r.recordEvent(claim, corev1.EventTypeNormal, "CreatedResource", "Created %s %s", resourceObj.GetKind(), resourceObj.GetName())
 	}
 
 	return nil
 }
 
-func templatesFromClaim(claim *corev1.ConfigMap) ([]*unstructured.Unstructured, error) {
-	if claim.Data == nil {
-		return nil, fmt.Errorf("claim missing rendered templates")
+var errNoRenderedResources = errors.New("no rendered resources")
+
+func (r *ClaimReconciler) templatesFromClaim(ctx context.Context, claim *corev1.ConfigMap) ([]*unstructured.Unstructured, error) {
+	if claim.Data[RenderedResourcesDataKey] == "" {
+		return nil, fmt.Errorf("%w: claim missing %s", errNoRenderedResources, RenderedResourcesDataKey)
 	}
 
-	renderedResourcesRaw := claim.Data[RenderedResourcesDataKey]
-	if renderedResourcesRaw == "" {
-		return nil, fmt.Errorf("claim missing %s", RenderedResourcesDataKey)
+	renderedResourcesJSON, err := r.renderedResourcesJSON(ctx, claim)
+	if err != nil {
+		return nil, err
 	}
 
 	var rawResources []map[string]any
-	if err := json.Unmarshal([]byte(renderedResourcesRaw), &rawResources); err != nil {
+	if err := json.Unmarshal(renderedResourcesJSON, &rawResources); err != nil {
 		return nil, fmt.Errorf("decode rendered resources from claim: %w", err)
 	}
 	if len(rawResources) == 0 {
@@ -408,7 +682,26 @@ func templatesFromClaim(claim *corev1.ConfigMap) ([]*unstructured.Unstructured,
 }
 
 func (r *ClaimReconciler) cleanupClaimResources(ctx context.Context, claim *corev1.ConfigMap) error {
-	resources, err := templatesFromClaim(claim)
+	if err := r.deleteClaimResources(ctx, claim); err != nil {
+		return err
+	}
+	r.recordEvent(claim, corev1.EventTypeNormal, "Expired", "Claim expired and resources were deleted")
+	r.notify(ctx, notify.EventClaimExpired, claim, "claim expired and resources were deleted")
+	return nil
+}
+
+func (r *ClaimReconciler) deleteClaimResources(ctx context.Context, claim *corev1.ConfigMap) error {
+	if isHelmReleaseClaim(claim) {
+		// Helm deletes the release resources, running its hooks in order.
+		return r.uninstallClaimRelease(ctx, claim)
+	}
+
+	resources, err := r.templatesFromClaim(ctx, claim)
+	if err != nil {
+		return err
+	}
+
+	target, err := r.targetFor(ctx, claim)
 	if err != nil {
 		return err
 	}
@@ -418,58 +711,19 @@ func (r *ClaimReconciler) cleanupClaimResources(ctx context.Context, claim *core
 		resourceObj.SetGroupVersionKind(resourceTemplate.GroupVersionKind())
 		resourceObj.SetName(resourceTemplate.GetName())
 
-		isNamespaced, err := r.isNamespacedResource(resourceObj)
+		isNamespaced, err := target.isNamespaced(resourceObj)
 		if err != nil {
 			return fmt.Errorf("resolve resource scope for %s %s: %w", resourceObj.GetKind(), resourceObj.GetName(), err)
 		}
 		if isNamespaced {
-			resourceObj.SetNamespace(claim.Namespace)
+			resourceObj.SetNamespace(resourceNamespace(claim))
 		}
 
-		if err := r.Delete(ctx, resourceObj); client.IgnoreNotFound(err) != nil {
+		if err := target.Delete(ctx, resourceObj); client.IgnoreNotFound(err) != nil {
+			resourceErrorsTotal.WithLabelValues(resourceObj.GetKind(), "delete").Inc()
 			return err
 		}
 	}
-	r.Recorder.Event(claim, corev1.EventTypeNormal, "Expired", "Claim expired and resources were deleted")
-	return nil
-}
-
-func (r *ClaimReconciler) isNamespacedResource(obj *unstructured.Unstructured) (bool, error) {
-	mapping, err := r.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
-	if err != nil {
-		return false, err
-	}
-	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
-}
-
-func (r *ClaimReconciler) refreshMetrics(ctx context.Context) error {
-	claims := &corev1.ConfigMapList{}
-	if err := r.List(ctx, claims, client.InNamespace(r.Namespace), client.MatchingLabels{ManagedByLabelKey: ManagedByLabelValue}); err != nil {
-		return err
-	}
-	activeClaimsGauge.Set(float64(len(claims.Items)))
-
-	resources := 0
-	for _, claim := range claims.Items {
-		templates, err := templatesFromClaim(&claim)
-		if err != nil {
-			continue
-		}
-
-		if isPreProvisionedClaim(&claim) {
-			for _, template := range templates {
-				if isLazyProvisionedResource(template) {
-					continue
-				}
-				resources++
-			}
-			continue
-		}
-
-		resources += len(templates)
-	}
-	activeResourcesGauge.Set(float64(resources))
-
 	return nil
 }
 
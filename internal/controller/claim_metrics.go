@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// claimMetricsEntry is what a single claim contributes to the claim gauges.
+type claimMetricsEntry struct {
+	version        [3]string
+	status         [2]string
+	resources      int
+	renderedDigest uint64
+}
+
+// claimMetricsIndex keeps the claim gauges up to date from reconciles of
+// individual claims, so no reconcile has to list and decode every claim.
+type claimMetricsIndex struct {
+	mu       sync.Mutex
+	entries  map[types.NamespacedName]claimMetricsEntry
+	versions map[[3]string]int
+	statuses map[[2]string]int
+}
+
+var claimMetrics = &claimMetricsIndex{
+	entries:  map[types.NamespacedName]claimMetricsEntry{},
+	versions: map[[3]string]int{},
+	statuses: map[[2]string]int{},
+}
+
+func (r *ClaimReconciler) observeClaimMetrics(ctx context.Context, claim *corev1.ConfigMap) {
+	key := types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name}
+	preProvisioned := isPreProvisionedClaim(claim)
+	entry := claimMetricsEntry{
+		version: [3]string{
+			claim.Labels[ProfileLabelKey],
+			claim.Annotations[TemplateHashAnnotationKey],
+			claim.Annotations[ValuesHashAnnotationKey],
+		},
+		status:         [2]string{claimMetricStatus(claim), strconv.FormatBool(preProvisioned)},
+		renderedDigest: renderedDigest(claim.Data[RenderedResourcesDataKey], preProvisioned),
+	}
+
+	// Decoding the rendered resources is the expensive part, only redo it when they changed.
+	previous, known := claimMetrics.get(key)
+	if known && previous.renderedDigest == entry.renderedDigest {
+		entry.resources = previous.resources
+	} else {
+		entry.resources = r.countClaimResources(ctx, claim, preProvisioned)
+	}
+	claimMetrics.set(key, entry)
+}
+
+func (r *ClaimReconciler) forgetClaimMetrics(key types.NamespacedName) {
+	claimMetrics.remove(key)
+}
+
+func (r *ClaimReconciler) countClaimResources(ctx context.Context, claim *corev1.ConfigMap, preProvisioned bool) int {
+	templates, err := r.templatesFromClaim(ctx, claim)
+	if err != nil {
+		return 0
+	}
+	if !preProvisioned {
+		return len(templates)
+	}
+	count := 0
+	for _, template := range templates {
+		if !isLazyProvisionedResource(template) {
+			count++
+		}
+	}
+	return count
+}
+
+func renderedDigest(rendered string, preProvisioned bool) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(rendered))
+	_, _ = hash.Write([]byte(strconv.FormatBool(preProvisioned)))
+	return hash.Sum64()
+}
+
+func (i *claimMetricsIndex) get(key types.NamespacedName) (claimMetricsEntry, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	entry, ok := i.entries[key]
+	return entry, ok
+}
+
+func (i *claimMetricsIndex) set(key types.NamespacedName, entry claimMetricsEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if previous, ok := i.entries[key]; ok {
+		i.apply(previous, -1)
+	}
+	i.entries[key] = entry
+	i.apply(entry, 1)
+	activeClaimsGauge.Set(float64(len(i.entries)))
+}
+
+func (i *claimMetricsIndex) remove(key types.NamespacedName) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if previous, ok := i.entries[key]; ok {
+		i.apply(previous, -1)
+		delete(i.entries, key)
+	}
+	activeClaimsGauge.Set(float64(len(i.entries)))
+}
+
+// apply must be called with the lock held. Series dropping to zero are deleted
+// so stale template hashes do not linger.
+func (i *claimMetricsIndex) apply(entry claimMetricsEntry, delta int) {
+	activeResourcesGauge.Add(float64(delta * entry.resources))
+
+	i.versions[entry.version] += delta
+	if count := i.versions[entry.version]; count > 0 {
+		activeClaimsByVersionGauge.WithLabelValues(entry.version[:]...).Set(float64(count))
+	} else {
+		delete(i.versions, entry.version)
+		activeClaimsByVersionGauge.DeleteLabelValues(entry.version[:]...)
+	}
+
+	i.statuses[entry.status] += delta
+	if count := i.statuses[entry.status]; count > 0 {
+		claimsByStatusGauge.WithLabelValues(entry.status[:]...).Set(float64(count))
+	} else {
+		delete(i.statuses, entry.status)
+		claimsByStatusGauge.DeleteLabelValues(entry.status[:]...)
+	}
+}
+
+// claimMetricStatus reports claims that have not been evaluated yet as pending.
+func claimMetricStatus(claim *corev1.ConfigMap) string {
+	if !claim.DeletionTimestamp.IsZero() {
+		return ClaimStatusDeleting
+	}
+	if status := claim.Data[ClaimStatusDataKey]; status != "" {
+		return status
+	}
+	return "pending"
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func resourceNamespace(claim *corev1.ConfigMap) string {
+	if namespace := strings.TrimSpace(claim.Annotations[ClaimNamespaceAnnotationKey]); namespace != "" {
+		return namespace
+	}
+	return claim.Namespace
+}
+
+func hasDedicatedNamespace(claim *corev1.ConfigMap) bool {
+	return resourceNamespace(claim) != claim.Namespace
+}
+
+func (r *ClaimReconciler) ensureClaimNamespace(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap) error {
+	if !hasDedicatedNamespace(claim) {
+		return nil
+	}
+
+	if controllerutil.AddFinalizer(claim, NamespaceCleanupFinalizer) {
+		if err := r.Update(ctx, claim); err != nil {
+			return err
+		}
+	}
+
+	name := resourceNamespace(claim)
+	namespace := &corev1.Namespace{}
+	err := target.Get(ctx, client.ObjectKey{Name: name}, namespace)
+	if err == nil {
+		if namespace.DeletionTimestamp != nil {
+			return fmt.Errorf("claim namespace %s is terminating", name)
+		}
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		resourceErrorsTotal.WithLabelValues("Namespace", "get").Inc()
+		return err
+	}
+
+	namespace = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				ManagedByLabelKey: ManagedByLabelValue,
+				ClaimLabelKey:     claim.Name,
+			},
+		},
+	}
+	if err := target.Create(ctx, namespace); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		resourceErrorsTotal.WithLabelValues("Namespace", "create").Inc()
+		return err
+	}
+	r.recordEvent(claim, corev1.EventTypeNormal, "CreatedNamespace", "Created namespace %s", name)
+	return nil
+}
+
+func (r *ClaimReconciler) releaseClaimNamespace(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap) error {
+	if !controllerutil.ContainsFinalizer(claim, NamespaceCleanupFinalizer) {
+		return nil
+	}
+
+	namespace := &corev1.Namespace{}
+	err := target.Get(ctx, client.ObjectKey{Name: resourceNamespace(claim)}, namespace)
+	if err == nil && namespace.DeletionTimestamp != nil {
+		return nil
+	}
+	if err == nil {
+		err = target.Delete(ctx, namespace)
+		if err == nil {
+			return nil
+		}
+	}
+	if !apierrors.IsNotFound(err) {
+		resourceErrorsTotal.WithLabelValues("Namespace", "delete").Inc()
+		return err
+	}
+
+	controllerutil.RemoveFinalizer(claim, NamespaceCleanupFinalizer)
+	return client.IgnoreNotFound(r.Update(ctx, claim))
+}
+
+func (r *ClaimReconciler) claimNamespaces() []string {
+	namespaces := []string{r.Namespace}
+	for _, namespace := range r.WatchNamespaces {
+		if namespace != "" && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+func (r *ClaimReconciler) watchesNamespace(namespace string) bool {
+	return slices.Contains(r.claimNamespaces(), namespace)
+}
+
+func (r *ClaimReconciler) listClaims(ctx context.Context, claims *corev1.ConfigMapList, opts ...client.ListOption) error {
+	items := []corev1.ConfigMap{}
+	for _, namespace := range r.claimNamespaces() {
+		page := &corev1.ConfigMapList{}
+		listOpts := append([]client.ListOption{client.InNamespace(namespace), client.MatchingLabels{ManagedByLabelKey: ManagedByLabelValue}}, opts...)
+		if err := r.List(ctx, page, listOpts...); err != nil {
+			return err
+		}
+		items = append(items, page.Items...)
+	}
+	claims.Items = items
+	return nil
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/nonot/claim-controller/internal/cluster"
+)
+
+const (
+	ClaimStatusDeleting = "deleting"
+
+	// remoteCleanupTimeout bounds how long a deleting claim waits for an
+	// unreachable remote cluster before its remote resources are abandoned.
+	remoteCleanupTimeout = 30 * time.Minute
+)
+
+var claimCleanupDurationSeconds = promauto.With(metrics.Registry).NewHistogram(prometheus.HistogramOpts{
+	Name:    "claim_controller_claim_cleanup_duration_seconds",
+	Help:    "Time in seconds from claim deletion request until the claim and all its resources are gone.",
+	Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+})
+
+func foregroundDeletion() client.DeleteOption {
+	return client.PropagationPolicy(metav1.DeletePropagationForeground)
+}
+
+func (r *ClaimReconciler) reconcileDeletingClaim(ctx context.Context, claim *corev1.ConfigMap) error {
+	r.deletingClaims.LoadOrStore(claim.Name, claim.DeletionTimestamp.Time)
+
+	done, err := r.runPreDeleteHooks(ctx, claim)
+	if err != nil || !done {
+		return err
+	}
+
+	target, err := r.targetFor(ctx, claim)
+	if err != nil {
+		return r.abandonRemoteCleanup(ctx, claim, err)
+	}
+	if err := r.uninstallClaimRelease(ctx, claim); err != nil {
+		return err
+	}
+	if err := r.releaseClaimNamespace(ctx, target, claim); err != nil {
+		err = fmt.Errorf("delete claim namespace: %w", err)
+		if target.remote() {
+			return r.abandonRemoteCleanup(ctx, claim, err)
+		}
+		return err
+	}
+	if controllerutil.ContainsFinalizer(claim, RemoteCleanupFinalizer) {
+		if err := r.deleteClaimResources(ctx, claim); err != nil {
+			return r.abandonRemoteCleanup(ctx, claim, fmt.Errorf("delete remote claim resources: %w", err))
+		}
+	}
+
+	remaining, err := r.remainingClaimResources(ctx, target, claim)
+	if err != nil {
+		if target.remote() {
+			return r.abandonRemoteCleanup(ctx, claim, err)
+		}
+		return err
+	}
+	if remaining == 0 && controllerutil.RemoveFinalizer(claim, RemoteCleanupFinalizer) {
+		return client.IgnoreNotFound(r.Update(ctx, claim))
+	}
+
+	message := fmt.Sprintf("waiting for %d resources to be deleted", remaining)
+	current := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(claim), current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if current.Data[ClaimStatusDataKey] == ClaimStatusDeleting && current.Data[ClaimStatusMessageDataKey] == message {
+		return nil
+	}
+	return r.patchClaimStatus(ctx, current, map[string]string{
+		ClaimStatusDataKey:        ClaimStatusDeleting,
+		ClaimStatusMessageDataKey: message,
+	})
+}
+
+// abandonRemoteCleanup drops RemoteCleanupFinalizer when the target cluster is
+// no longer registered or has been unreachable for remoteCleanupTimeout, so the
+// claim does not stay terminating forever. Otherwise err is returned to retry.
+func (r *ClaimReconciler) abandonRemoteCleanup(ctx context.Context, claim *corev1.ConfigMap, err error) error {
+	if !controllerutil.ContainsFinalizer(claim, RemoteCleanupFinalizer) {
+		return err
+	}
+	if !errors.Is(err, cluster.ErrUnknownCluster) && time.Since(claim.DeletionTimestamp.Time) < remoteCleanupTimeout {
+		return err
+	}
+
+	logf.FromContext(ctx).Error(err, "abandoning remote cleanup of claim", "claim", claim.Name, "cluster", claim.Annotations[ClusterAnnotationKey])
+	r.recordEvent(claim, corev1.EventTypeWarning, "RemoteCleanupAbandoned", "Resources in cluster %q may be left behind: %v", claim.Annotations[ClusterAnnotationKey], err)
+	controllerutil.RemoveFinalizer(claim, RemoteCleanupFinalizer)
+	return client.IgnoreNotFound(r.Update(ctx, claim))
+}
+
+func (r *ClaimReconciler) observeClaimCleanup(claimName string) {
+	deletedAt, ok := r.deletingClaims.LoadAndDelete(claimName)
+	if !ok {
+		return
+	}
+	claimCleanupDurationSeconds.Observe(time.Since(deletedAt.(time.Time)).Seconds())
+}
+
+func (r *ClaimReconciler) remainingClaimResources(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap) (int, error) {
+	resources, err := r.templatesFromClaim(ctx, claim)
+	if errors.Is(err, errNoRenderedResources) {
+		// Nothing was ever rendered, so nothing can be left behind.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read rendered resources: %w", err)
+	}
+
+	remaining := 0
+	for _, resourceTemplate := range resources {
+		resourceObj := &unstructured.Unstructured{}
+		resourceObj.SetGroupVersionKind(resourceTemplate.GroupVersionKind())
+		resourceObj.SetName(resourceTemplate.GetName())
+
+		isNamespaced, err := target.isNamespaced(resourceObj)
+		if err != nil {
+			return 0, fmt.Errorf("resolve resource scope for %s %s: %w", resourceObj.GetKind(), resourceObj.GetName(), err)
+		}
+		if isNamespaced {
+			resourceObj.SetNamespace(resourceNamespace(claim))
+		}
+
+		exists, err := r.resourceExists(ctx, target, resourceObj)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/cluster"
+)
+
+type resourceTarget struct {
+	client.Client
+	apiReader client.Reader
+	cluster   string
+}
+
+func (t *resourceTarget) remote() bool {
+	return t.cluster != ""
+}
+
+func (t *resourceTarget) isNamespaced(obj *unstructured.Unstructured) (bool, error) {
+	mapping, err := t.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
+	if err != nil {
+		return false, err
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+func (r *ClaimReconciler) targetFor(ctx context.Context, claim *corev1.ConfigMap) (*resourceTarget, error) {
+	name := strings.TrimSpace(claim.Annotations[ClusterAnnotationKey])
+	if name == "" {
+		return &resourceTarget{Client: r.Client, apiReader: r.APIReader}, nil
+	}
+	if r.Clusters == nil {
+		return nil, fmt.Errorf("%w %q: no cluster registry is configured", cluster.ErrUnknownCluster, name)
+	}
+
+	c, err := r.Clusters.Client(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("resolve cluster %q: %w", name, err)
+	}
+	return &resourceTarget{Client: c, apiReader: c, cluster: name}, nil
+}
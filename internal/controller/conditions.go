@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConditionProvisioned    = "Provisioned"
+	ConditionResourcesReady = "ResourcesReady"
+	ConditionExpiring       = "Expiring"
+	ConditionFailed         = "Failed"
+
+	resourceNotCreatedMessage = "not created yet"
+)
+
+// ClaimConditions returns the conditions recorded on the claim, nil when the
+// controller has not evaluated it yet.
+func ClaimConditions(claim *corev1.ConfigMap) []metav1.Condition {
+	raw := strings.TrimSpace(claim.Data[ClaimConditionsDataKey])
+	if raw == "" {
+		return nil
+	}
+	var conditions []metav1.Condition
+	if err := json.Unmarshal([]byte(raw), &conditions); err != nil {
+		return nil
+	}
+	return conditions
+}
+
+// mergeClaimConditions applies updates over the recorded conditions;
+// lastTransitionTime only moves when a condition status changes.
+func mergeClaimConditions(claim *corev1.ConfigMap, updates ...metav1.Condition) (string, error) {
+	conditions := ClaimConditions(claim)
+	for _, update := range updates {
+		meta.SetStatusCondition(&conditions, update)
+	}
+	encoded, err := json.Marshal(conditions)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func newCondition(conditionType string, status bool, reason, message string) metav1.Condition {
+	conditionStatus := metav1.ConditionFalse
+	if status {
+		conditionStatus = metav1.ConditionTrue
+	}
+	return metav1.Condition{Type: conditionType, Status: conditionStatus, Reason: reason, Message: message}
+}
+
+func readinessConditions(statusValue, summary string, resources []resourceReadiness, expiringSoon bool, expiresAt time.Time) []metav1.Condition {
+	missing := 0
+	for _, resource := range resources {
+		if !resource.Created {
+			missing++
+		}
+	}
+	provisioned := newCondition(ConditionProvisioned, true, "ResourcesCreated", fmt.Sprintf("%d resources created", len(resources)))
+	if missing > 0 {
+		provisioned = newCondition(ConditionProvisioned, false, "CreatingResources", fmt.Sprintf("%d/%d resources not created yet", missing, len(resources)))
+	}
+
+	var ready metav1.Condition
+	switch statusValue {
+	case "ready":
+		ready = newCondition(ConditionResourcesReady, true, "ResourcesReady", summary)
+	case ClaimStatusDegraded:
+		ready = newCondition(ConditionResourcesReady, false, "Degraded", blockingResource(resources))
+	default:
+		ready = newCondition(ConditionResourcesReady, false, "WaitingForResources", blockingResource(resources))
+	}
+
+	expiring := newCondition(ConditionExpiring, false, "NotExpiring", fmt.Sprintf("expires at %s", expiresAt.UTC().Format(time.RFC3339)))
+	if expiringSoon {
+		expiring = newCondition(ConditionExpiring, true, "ExpiringSoon", fmt.Sprintf("expires at %s", expiresAt.UTC().Format(time.RFC3339)))
+	}
+
+	return []metav1.Condition{
+		provisioned,
+		ready,
+		expiring,
+		newCondition(ConditionFailed, false, "NoFailure", ""),
+	}
+}
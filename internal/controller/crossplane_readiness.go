@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func isCrossplaneResource(obj *unstructured.Unstructured) bool {
+	for _, field := range []string{"forProvider", "compositionRef", "compositionSelector", "resourceRef", "resourceRefs"} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", field); found {
+			return true
+		}
+	}
+	_, syncedFound := conditionStatus(obj.Object, "status", "conditions", "Synced")
+	_, readyFound := conditionStatus(obj.Object, "status", "conditions", "Ready")
+	return syncedFound && readyFound
+}
+
+func assessCrossplaneReadiness(obj *unstructured.Unstructured) (bool, string) {
+	if synced, found := conditionStatus(obj.Object, "status", "conditions", "Synced"); found && !synced {
+		return false, conditionMessage(obj, "Synced", "not synced")
+	}
+
+	ready, found := conditionStatus(obj.Object, "status", "conditions", "Ready")
+	if !found {
+		return false, "waiting for Ready condition"
+	}
+	if !ready {
+		return false, conditionMessage(obj, "Ready", "not ready")
+	}
+	return true, conditionMessage(obj, "Ready", "ready and synced")
+}
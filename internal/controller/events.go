@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nonot/claim-controller/internal/notify"
+)
+
+func ClaimEvent(eventType string, claim *corev1.ConfigMap, message string) notify.Event {
+	event := notify.Event{
+		Type:      eventType,
+		Time:      time.Now().UTC(),
+		ClaimID:   claim.Labels[ClaimLabelKeyId],
+		ClaimName: claim.Name,
+		Namespace: claim.Namespace,
+		Profile:   claim.Labels[ProfileLabelKey],
+		ExpiresAt: claim.Annotations[ExpiresAtAnnotationKey],
+		Message:   message,
+	}
+	for key, annotation := range map[string]string{
+		"owner":       OwnerAnnotationKey,
+		"purpose":     PurposeAnnotationKey,
+		"repo":        RepoAnnotationKey,
+		"pipelineUrl": PipelineURLAnnotationKey,
+		"requestId":   RequestIDAnnotationKey,
+	} {
+		if value := claim.Annotations[annotation]; value != "" {
+			if event.Metadata == nil {
+				event.Metadata = map[string]string{}
+			}
+			event.Metadata[key] = value
+		}
+	}
+	return event
+}
+
+// recordEvent attaches the id of the API request behind the claim so events can be correlated with API logs.
+func (r *ClaimReconciler) recordEvent(claim *corev1.ConfigMap, eventType, reason, messageFmt string, args ...any) {
+	if requestID := claim.Annotations[RequestIDAnnotationKey]; requestID != "" {
+		r.Recorder.AnnotatedEventf(claim, map[string]string{RequestIDAnnotationKey: requestID}, eventType, reason, messageFmt, args...)
+		return
+	}
+	r.Recorder.Eventf(claim, eventType, reason, messageFmt, args...)
+}
+
+func (r *ClaimReconciler) notify(ctx context.Context, eventType string, claim *corev1.ConfigMap, message string) {
+	if r.Notifier == nil || isPreProvisionedClaim(claim) {
+		return
+	}
+	r.Notifier.Notify(ctx, ClaimEvent(eventType, claim, message))
+}
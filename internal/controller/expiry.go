@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+type ExpiryReconciler struct {
+	*ClaimReconciler
+}
+
+func (r *ExpiryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.watchesNamespace(req.Namespace) {
+		return ctrl.Result{}, nil
+	}
+
+	claim := &corev1.ConfigMap{}
+	if err := r.Get(ctx, req.NamespacedName, claim); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if claim.Labels[ManagedByLabelKey] != ManagedByLabelValue || claim.DeletionTimestamp != nil || isPreProvisionedClaim(claim) {
+		return ctrl.Result{}, nil
+	}
+
+	return r.expireIfDue(ctx, claim)
+}
+
+func (r *ExpiryReconciler) expireIfDue(ctx context.Context, claim *corev1.ConfigMap) (ctrl.Result, error) {
+	now := time.Now().UTC()
+	expired, next := r.expiryState(claim, now)
+	if !expired {
+		return ctrl.Result{RequeueAfter: max(next, time.Second)}, nil
+	}
+
+	done, err := r.runPreDeleteHooks(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !done {
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+	}
+	if err := r.cleanupClaimResources(ctx, claim); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.recordTombstone(ctx, claim, r.endReason(claim, now), claim.Annotations[ReleasedByAnnotationKey])
+	if err := r.Delete(ctx, claim, foregroundDeletion()); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ExpiryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.ConfigMap{}, expiryIndexKey, r.indexExpiry); err != nil {
+		return fmt.Errorf("index claims by expiry: %w", err)
+	}
+	if err := mgr.Add(manager.RunnableFunc(r.runExpirySweep)); err != nil {
+		return err
+	}
+	if r.HistoryRetention > 0 {
+		if err := mgr.Add(manager.RunnableFunc(r.runTombstoneSweep)); err != nil {
+			return err
+		}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("claim-expiry").
+		For(&corev1.ConfigMap{}).
+		Complete(r)
+}
+
+func (r *ClaimReconciler) claimExpiresAt(claim *corev1.ConfigMap) time.Time {
+	expiresAt, err := time.Parse(time.RFC3339, claim.Annotations[ExpiresAtAnnotationKey])
+	if err != nil {
+		expiresAt = claim.CreationTimestamp.Add(r.currentTunables().DefaultTTL)
+	}
+	return r.effectiveExpiry(claim, expiresAt)
+}
+
+func (r *ClaimReconciler) expiryState(claim *corev1.ConfigMap, now time.Time) (bool, time.Duration) {
+	if until, suspended := suspendedUntil(claim, now); suspended {
+		return false, until.Sub(now)
+	}
+
+	expiresAt := r.claimExpiresAt(claim)
+	if !now.Before(expiresAt) || r.outsideWorkingHours(claim, now) {
+		return true, 0
+	}
+
+	next := expiresAt.Sub(now)
+	if r.WorkingHours != nil {
+		if closes, open := r.WorkingHours.closesAt(now); open {
+			next = min(next, closes.Sub(now))
+		}
+	}
+	if startAt, scheduled := scheduledStart(claim, now); scheduled {
+		next = min(next, startAt.Sub(now))
+	}
+	return false, next
+}
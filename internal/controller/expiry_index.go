@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	expiryIndexKey       = "claimExpiryHour"
+	expiryBucketLayout   = "2006-01-02T15"
+	defaultSweepInterval = 30 * time.Second
+)
+
+func expiryBucket(t time.Time) string {
+	return t.UTC().Format(expiryBucketLayout)
+}
+
+func (r *ExpiryReconciler) indexExpiry(obj client.Object) []string {
+	claim, ok := obj.(*corev1.ConfigMap)
+	if !ok || claim.Labels[ManagedByLabelKey] != ManagedByLabelValue || isPreProvisionedClaim(claim) {
+		return nil
+	}
+	return []string{expiryBucket(r.claimExpiresAt(claim))}
+}
+
+func (r *ExpiryReconciler) sweepInterval() time.Duration {
+	if interval := r.currentTunables().ReconcileInterval; interval > 0 {
+		return interval
+	}
+	return defaultSweepInterval
+}
+
+func (r *ExpiryReconciler) runExpirySweep(ctx context.Context) error {
+	interval := r.sweepInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastSwept time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if current := r.sweepInterval(); current != interval {
+			interval = current
+			ticker.Reset(interval)
+		}
+
+		now := time.Now().UTC()
+		if err := r.sweepDueClaims(ctx, lastSwept, now); err != nil {
+			logf.FromContext(ctx).Error(err, "failed to sweep expired claims")
+			continue
+		}
+		lastSwept = now
+	}
+}
+
+func (r *ExpiryReconciler) sweepDueClaims(ctx context.Context, from, now time.Time) error {
+	var due []corev1.ConfigMap
+	if from.IsZero() {
+		claims := &corev1.ConfigMapList{}
+		if err := r.listClaims(ctx, claims); err != nil {
+			return err
+		}
+		due = claims.Items
+	} else {
+		for bucket := from.Truncate(time.Hour); !bucket.After(now); bucket = bucket.Add(time.Hour) {
+			claims := &corev1.ConfigMapList{}
+			if err := r.listClaims(ctx, claims, client.MatchingFields{expiryIndexKey: expiryBucket(bucket)}); err != nil {
+				return err
+			}
+			due = append(due, claims.Items...)
+		}
+	}
+
+	for i := range due {
+		claim := &due[i]
+		if claim.DeletionTimestamp != nil || isPreProvisionedClaim(claim) {
+			continue
+		}
+		if expired, _ := r.expiryState(claim, now); !expired {
+			continue
+		}
+		if _, err := r.expireIfDue(ctx, claim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
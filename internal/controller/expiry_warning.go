@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/notify"
+)
+
+func (r *ClaimReconciler) isExpiringSoon(claim *corev1.ConfigMap, expiresAt time.Time) bool {
+	if r.ExpiryWarningWindow <= 0 || isPreProvisionedClaim(claim) {
+		return false
+	}
+	return time.Until(expiresAt) <= r.ExpiryWarningWindow
+}
+
+func (r *ClaimReconciler) markClaimExpiringSoon(ctx context.Context, claim *corev1.ConfigMap, expiresAt time.Time) error {
+	expiresAtValue := expiresAt.UTC().Format(time.RFC3339)
+	if claim.Annotations[ExpiringSoonAnnotationKey] == expiresAtValue {
+		return nil
+	}
+
+	marked := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(claim), current); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if current.Annotations[ExpiresAtAnnotationKey] != claim.Annotations[ExpiresAtAnnotationKey] || current.Annotations[ExpiringSoonAnnotationKey] == expiresAtValue {
+			return nil
+		}
+		if current.Annotations == nil {
+			current.Annotations = map[string]string{}
+		}
+		current.Annotations[ExpiringSoonAnnotationKey] = expiresAtValue
+		if err := r.Update(ctx, current); err != nil {
+			return err
+		}
+		marked = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if marked {
+		message := fmt.Sprintf("Claim expires at %s (in %s)", expiresAtValue, time.Until(expiresAt).Round(time.Second))
+		r.recordEvent(claim, corev1.EventTypeWarning, "ExpiringSoon", "%s", message)
+		r.notify(ctx, notify.EventClaimExpiringSoon, claim, message)
+	}
+	return nil
+}
+
+func expiringSoonMessage(summary string, expiresAt time.Time) string {
+	return fmt.Sprintf("%s, expiring soon (at %s)", summary, expiresAt.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/nonot/claim-controller/internal/notify"
+)
+
+const (
+	ClaimStatusFailed = "failed"
+
+	// persistentWaitingGracePeriod is how long a container may wait on a
+	// persistentWaitingReasons reason before the pod is considered failed.
+	persistentWaitingGracePeriod = 5 * time.Minute
+	failedClaimRecheckInterval   = time.Minute
+)
+
+// Waiting reasons that never resolve without a change to the claim resources.
+var terminalWaitingReasons = map[string]bool{
+	"InvalidImageName": true,
+}
+
+// Waiting reasons that are often transient (a registry hiccup, an image or a
+// Secret pushed shortly after the claim) and only fail the claim once they
+// persist for persistentWaitingGracePeriod.
+var persistentWaitingReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+func isFailedClaim(claim *corev1.ConfigMap) bool {
+	return claim.Data[ClaimStatusDataKey] == ClaimStatusFailed
+}
+
+// terminalFailure returns why a resource that is not ready will never become
+// ready, or an empty string while it may still converge.
+func (r *ClaimReconciler) terminalFailure(ctx context.Context, target *resourceTarget, obj *unstructured.Unstructured) (string, error) {
+	if obj.GroupVersionKind().Group == "batch" && strings.EqualFold(obj.GetKind(), "Job") {
+		if failed, found := conditionStatus(obj.Object, "status", "conditions", "Failed"); found && failed {
+			return conditionMessage(obj, "Failed", fmt.Sprintf("job %s failed", obj.GetName())), nil
+		}
+		return "", nil
+	}
+	if obj.GroupVersionKind().Group == "" && strings.EqualFold(obj.GetKind(), "Pod") {
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+			return "", nil
+		}
+		if pod.Status.Phase == corev1.PodFailed {
+			return fmt.Sprintf("pod %s failed: %s", pod.Name, pod.Status.Reason), nil
+		}
+		return r.podFailure(pod), nil
+	}
+
+	if obj.GroupVersionKind().Group != "apps" {
+		return "", nil
+	}
+	switch strings.ToLower(obj.GetKind()) {
+	case "deployment", "statefulset", "replicaset", "daemonset":
+	default:
+		return "", nil
+	}
+	selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	if len(selector) == 0 {
+		return "", nil
+	}
+	// Pods are read uncached so the manager does not start a pod informer.
+	var reader client.Reader = target
+	if target.apiReader != nil {
+		reader = target.apiReader
+	}
+	pods := &corev1.PodList{}
+	if err := reader.List(ctx, pods, client.InNamespace(obj.GetNamespace()), client.MatchingLabels(selector)); err != nil {
+		return "", err
+	}
+	for i := range pods.Items {
+		if reason := r.podFailure(&pods.Items[i]); reason != "" {
+			return fmt.Sprintf("%s %s: %s", strings.ToLower(obj.GetKind()), obj.GetName(), reason), nil
+		}
+	}
+	return "", nil
+}
+
+func (r *ClaimReconciler) podFailure(pod *corev1.Pod) string {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting == nil {
+			continue
+		}
+		if terminalWaitingReasons[waiting.Reason] {
+			return fmt.Sprintf("pod %s container %s: %s: %s", pod.Name, status.Name, waiting.Reason, waiting.Message)
+		}
+		if persistentWaitingReasons[waiting.Reason] {
+			if waitingFor := time.Since(containersNotReadySince(pod)); waitingFor >= persistentWaitingGracePeriod {
+				return fmt.Sprintf("pod %s container %s: %s for %s: %s", pod.Name, status.Name, waiting.Reason, waitingFor.Round(time.Second), waiting.Message)
+			}
+		}
+		if waiting.Reason == "CrashLoopBackOff" && r.CrashLoopRestarts > 0 && int(status.RestartCount) >= r.CrashLoopRestarts {
+			return fmt.Sprintf("pod %s container %s: CrashLoopBackOff after %d restarts", pod.Name, status.Name, status.RestartCount)
+		}
+	}
+	return ""
+}
+
+// containersNotReadySince returns when the pod containers stopped being ready,
+// or the pod creation time when they never were.
+func containersNotReadySince(pod *corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.ContainersReady && condition.Status != corev1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+func failedResource(statuses []resourceReadiness) *resourceReadiness {
+	for i := range statuses {
+		if statuses[i].Failed {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// markClaimFailed records the failure once. With FailedRetention set, the
+// expiry is shortened so the regular expiry path cleans the claim up.
+func (r *ClaimReconciler) markClaimFailed(ctx context.Context, claim *corev1.ConfigMap, failed resourceReadiness, resources []resourceReadiness) error {
+	resourcesJSON, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+	reason := failed.Message
+	now := time.Now().UTC()
+	marked := false
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(claim), current); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if isFailedClaim(current) {
+			return nil
+		}
+		if current.Annotations == nil {
+			current.Annotations = map[string]string{}
+		}
+		if current.Data == nil {
+			current.Data = map[string]string{}
+		}
+		current.Annotations[FailedAtAnnotationKey] = now.Format(time.RFC3339)
+		if r.FailedRetention > 0 {
+			cleanupAt := now.Add(r.FailedRetention)
+			if expiresAt, err := time.Parse(time.RFC3339, current.Annotations[ExpiresAtAnnotationKey]); err != nil || cleanupAt.Before(expiresAt) {
+				current.Annotations[ExpiresAtAnnotationKey] = cleanupAt.Format(time.RFC3339)
+			}
+		}
+		conditionsJSON, err := mergeClaimConditions(current,
+			newCondition(ConditionResourcesReady, false, "Failed", reason),
+			newCondition(ConditionFailed, true, "TerminalFailure", fmt.Sprintf("%s %s: %s", failed.Kind, failed.Name, reason)),
+		)
+		if err != nil {
+			return err
+		}
+		current.Data[ClaimConditionsDataKey] = conditionsJSON
+		current.Data[ClaimStatusDataKey] = ClaimStatusFailed
+		current.Data[ClaimStatusMessageDataKey] = reason
+		current.Data[ClaimResourcesStatusDataKey] = string(resourcesJSON)
+		if err := r.Update(ctx, current); err != nil {
+			return err
+		}
+		marked = true
+		return nil
+	})
+	if err != nil || !marked {
+		return err
+	}
+
+	r.recordEvent(claim, corev1.EventTypeWarning, "Failed", "Claim failed, blocked by %s %s: %s", failed.Kind, failed.Name, reason)
+	r.notify(ctx, notify.EventClaimFailed, claim, reason)
+	return nil
+}
+
+// clearClaimFailure drops the failure marker of a claim whose resources became
+// ready after all. An expiry shortened by FailedRetention is kept.
+func (r *ClaimReconciler) clearClaimFailure(ctx context.Context, claim *corev1.ConfigMap) error {
+	base := claim.DeepCopy()
+	delete(claim.Annotations, FailedAtAnnotationKey)
+	if err := r.Patch(ctx, claim, client.MergeFrom(base)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	r.recordEvent(claim, corev1.EventTypeNormal, "Recovered", "Claim resources became ready after the claim failed")
+	return nil
+}
+
+// Pre-provisioned claims are never handed out once failed: they are deleted
+// right away so the pool replaces them.
+func (r *ClaimReconciler) discardFailedPoolClaim(ctx context.Context, claim *corev1.ConfigMap, reason string) error {
+	logf.FromContext(ctx).Info("deleting failed pre-provisioned claim", "claim", claim.Name, "reason", reason)
+	r.recordEvent(claim, corev1.EventTypeWarning, "Failed", "%s", reason)
+	if err := r.deleteClaimResources(ctx, claim); err != nil {
+		return err
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, claim, foregroundDeletion()))
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type genericStatus string
+
+const (
+	genericStatusUnknown    genericStatus = ""
+	genericStatusInProgress genericStatus = "InProgress"
+	genericStatusCurrent    genericStatus = "Current"
+	genericStatusFailed     genericStatus = "Failed"
+)
+
+func computeGenericStatus(obj *unstructured.Unstructured) (genericStatus, string) {
+	observedGeneration, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if found && observedGeneration < obj.GetGeneration() {
+		return genericStatusInProgress, fmt.Sprintf("%s generation %d not yet observed (observed %d)", obj.GetKind(), obj.GetGeneration(), observedGeneration)
+	}
+
+	if stalled, found := conditionStatus(obj.Object, "status", "conditions", "Stalled"); found && stalled {
+		return genericStatusFailed, conditionMessage(obj, "Stalled", "stalled")
+	}
+	if reconciling, found := conditionStatus(obj.Object, "status", "conditions", "Reconciling"); found && reconciling {
+		return genericStatusInProgress, conditionMessage(obj, "Reconciling", "reconciling")
+	}
+	if ready, found := conditionStatus(obj.Object, "status", "conditions", "Ready"); found {
+		if ready {
+			return genericStatusCurrent, conditionMessage(obj, "Ready", "ready")
+		}
+		return genericStatusInProgress, conditionMessage(obj, "Ready", "not ready")
+	}
+
+	return genericStatusUnknown, ""
+}
+
+func conditionMessage(obj *unstructured.Unstructured, conditionType string, fallback string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, rawCondition := range conditions {
+		conditionMap, ok := rawCondition.(map[string]any)
+		if !ok || conditionMap["type"] != conditionType {
+			continue
+		}
+		reason, _ := conditionMap["reason"].(string)
+		message, _ := conditionMap["message"].(string)
+		switch {
+		case reason != "" && message != "":
+			return fmt.Sprintf("%s: %s (%s)", fallback, message, reason)
+		case message != "":
+			return fmt.Sprintf("%s: %s", fallback, message)
+		case reason != "":
+			return fmt.Sprintf("%s (%s)", fallback, reason)
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/nonot/claim-controller/internal/helmrelease"
+)
+
+func isHelmReleaseClaim(claim *corev1.ConfigMap) bool {
+	return claim.Annotations[BackendAnnotationKey] == helmrelease.BackendHelmRelease
+}
+
+// releaseRevision identifies the chart version and values the API recorded for
+// the claim release.
+func releaseRevision(claim *corev1.ConfigMap) string {
+	sum := sha256.Sum256([]byte(claim.Annotations[TemplateHashAnnotationKey] + "\n" + claim.Data[HelmReleaseValuesDataKey]))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ensureClaimRelease installs or upgrades the claim release whenever the API
+// recorded a new revision, so Helm never runs in the API request path.
+func (r *ClaimReconciler) ensureClaimRelease(ctx context.Context, claim *corev1.ConfigMap) error {
+	revision := releaseRevision(claim)
+	if claim.Annotations[HelmReleaseAppliedAnnotationKey] == revision {
+		return nil
+	}
+	if r.HelmReleases == nil || r.ReleaseChart == nil {
+		return fmt.Errorf("claim %s uses a helm release but no helm client is configured", claim.Name)
+	}
+
+	chartObj, err := r.ReleaseChart(claim)
+	if err != nil {
+		return fmt.Errorf("resolve release chart: %w", err)
+	}
+	values, err := chartutil.ReadValues([]byte(claim.Data[HelmReleaseValuesDataKey]))
+	if err != nil {
+		return fmt.Errorf("decode release values: %w", err)
+	}
+	if err := r.HelmReleases.Ensure(ctx, resourceNamespace(claim), claim.Name, chartObj, values); err != nil {
+		r.recordEvent(claim, corev1.EventTypeWarning, "ReleaseFailed", "%v", err)
+		return err
+	}
+	r.recordEvent(claim, corev1.EventTypeNormal, "AppliedRelease", "Applied helm release %s", claim.Name)
+
+	base := claim.DeepCopy()
+	claim.Annotations[HelmReleaseAppliedAnnotationKey] = revision
+	return client.IgnoreNotFound(r.Patch(ctx, claim, client.MergeFrom(base)))
+}
+
+func (r *ClaimReconciler) uninstallClaimRelease(ctx context.Context, claim *corev1.ConfigMap) error {
+	if !controllerutil.ContainsFinalizer(claim, HelmReleaseFinalizer) {
+		return nil
+	}
+	if r.HelmReleases == nil {
+		return fmt.Errorf("claim %s uses a helm release but no helm client is configured", claim.Name)
+	}
+
+	if err := r.HelmReleases.Uninstall(resourceNamespace(claim), claim.Name); err != nil {
+		return err
+	}
+	r.recordEvent(claim, corev1.EventTypeNormal, "UninstalledRelease", "Uninstalled helm release %s", claim.Name)
+	controllerutil.RemoveFinalizer(claim, HelmReleaseFinalizer)
+	return client.IgnoreNotFound(r.Update(ctx, claim))
+}
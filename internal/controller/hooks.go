@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	HelmHookAnnotationKey       = "helm.sh/hook"
+	HelmHookWeightAnnotationKey = "helm.sh/hook-weight"
+	PreDeleteHooksFinalizer     = "claim-controller.io/pre-delete-hooks"
+
+	hookPreInstall  = "pre-install"
+	hookPostInstall = "post-install"
+	hookPreDelete   = "pre-delete"
+
+	preInstallStage  = math.MinInt32 / 2
+	postInstallStage = math.MaxInt32 / 2
+)
+
+func helmHooks(resource *unstructured.Unstructured) []string {
+	raw := strings.TrimSpace(resource.GetAnnotations()[HelmHookAnnotationKey])
+	if raw == "" {
+		return nil
+	}
+	hooks := strings.Split(raw, ",")
+	for i := range hooks {
+		hooks[i] = strings.TrimSpace(hooks[i])
+	}
+	return hooks
+}
+
+func helmHookWeight(resource *unstructured.Unstructured) int {
+	weight, err := strconv.Atoi(strings.TrimSpace(resource.GetAnnotations()[HelmHookWeightAnnotationKey]))
+	if err != nil {
+		return 0
+	}
+	return weight
+}
+
+func isPreDeleteHook(resource *unstructured.Unstructured) bool {
+	hooks := helmHooks(resource)
+	return slices.Contains(hooks, hookPreDelete) && !slices.Contains(hooks, hookPreInstall) && !slices.Contains(hooks, hookPostInstall)
+}
+
+func installResources(resources []*unstructured.Unstructured) []*unstructured.Unstructured {
+	return slices.DeleteFunc(slices.Clone(resources), isPreDeleteHook)
+}
+
+func preDeleteHooks(resources []*unstructured.Unstructured) []*unstructured.Unstructured {
+	return slices.DeleteFunc(slices.Clone(resources), func(resource *unstructured.Unstructured) bool {
+		return !isPreDeleteHook(resource)
+	})
+}
+
+func (r *ClaimReconciler) runPreDeleteHooks(ctx context.Context, claim *corev1.ConfigMap) (bool, error) {
+	if !controllerutil.ContainsFinalizer(claim, PreDeleteHooksFinalizer) {
+		return true, nil
+	}
+
+	resources, err := r.templatesFromClaim(ctx, claim)
+	if err != nil {
+		return false, err
+	}
+	target, err := r.targetFor(ctx, claim)
+	if err != nil {
+		return false, err
+	}
+
+	hooks := preDeleteHooks(resources)
+	if err := r.ensureWaveResources(ctx, target, claim, hooks, false); err != nil {
+		return false, err
+	}
+	done, err := r.waveReady(ctx, target, claim, hooks)
+	if err != nil || !done {
+		return false, err
+	}
+
+	for _, hook := range hooks {
+		hookObj := &unstructured.Unstructured{}
+		hookObj.SetGroupVersionKind(hook.GroupVersionKind())
+		hookObj.SetName(hook.GetName())
+		isNamespaced, err := target.isNamespaced(hookObj)
+		if err != nil {
+			return false, err
+		}
+		if isNamespaced {
+			hookObj.SetNamespace(resourceNamespace(claim))
+		}
+		if err := target.Delete(ctx, hookObj, foregroundDeletion()); client.IgnoreNotFound(err) != nil {
+			resourceErrorsTotal.WithLabelValues(hookObj.GetKind(), "delete").Inc()
+			return false, err
+		}
+	}
+
+	r.recordEvent(claim, corev1.EventTypeNormal, "PreDeleteHooksCompleted", "Pre-delete hooks completed")
+	controllerutil.RemoveFinalizer(claim, PreDeleteHooksFinalizer)
+	return true, client.IgnoreNotFound(r.Update(ctx, claim))
+}
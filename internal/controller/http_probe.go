@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const defaultHTTPProbeTimeout = 5 * time.Second
+
+func (r *ClaimReconciler) runHTTPProbe(ctx context.Context, claimName string, obj *unstructured.Unstructured, rawURL string) (bool, string) {
+	probeURL := strings.NewReplacer(
+		"{service}", obj.GetName(),
+		"{name}", obj.GetName(),
+		"{ns}", obj.GetNamespace(),
+		"{namespace}", obj.GetNamespace(),
+		"{claim}", claimName,
+	).Replace(rawURL)
+
+	timeout := r.HTTPProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultHTTPProbeTimeout
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return false, fmt.Sprintf("invalid http probe %q: %v", probeURL, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("http probe %s failed: %v", probeURL, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Sprintf("http probe %s returned %d", probeURL, resp.StatusCode)
+	}
+	return true, fmt.Sprintf("http probe %s returned %d", probeURL, resp.StatusCode)
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"hash/fnv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func (r *ClaimReconciler) effectiveExpiry(claim *corev1.ConfigMap, expiresAt time.Time) time.Time {
+	if r.ExpiryJitter < time.Second {
+		return expiresAt
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(claim.Namespace + "/" + claim.Name))
+	offset := time.Duration(hash.Sum64()%uint64(r.ExpiryJitter/time.Second)) * time.Second
+	return expiresAt.Add(offset)
+}
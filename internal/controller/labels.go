@@ -1,19 +1,62 @@
 package controller
 
 const (
-	ManagedByLabelKey             = "claim-controller.io/managed-by"
-	ManagedByLabelValue           = "claim-controller"
-	ClaimLabelKey                 = "claim-controller.io/claim"
-	ClaimLabelKeyId               = "claim-controller.io/claim.id"
-	ExpiresAtAnnotationKey        = "claim-controller.io/expires-at"
-	ClaimedAtAnnotationKey        = "claim-controller.io/claimed-at"
-	CreatedByAnnotationKey        = "claim-controller.io/created-by"
-	CreatedByAnnotationValue      = "claim-controller"
-	PreProvisionedAnnotationKey   = "claim-controller.io/pre-provisioned"
-	LazyProvisioningAnnotationKey = "claim.controller/lazy-provisionning"
-	RenderedResourcesDataKey      = "renderedResources"
-	ReturnValuesDataKey           = "returnValues"
-	ClaimStatusDataKey            = "claimStatus"
-	ClaimStatusMessageDataKey     = "claimStatusMessage"
-	ClaimResourcesStatusDataKey   = "claimResourcesStatus"
+	ManagedByLabelKey               = "claim-controller.io/managed-by"
+	ManagedByLabelValue             = "claim-controller"
+	ClaimLabelKey                   = "claim-controller.io/claim"
+	ClaimLabelKeyId                 = "claim-controller.io/claim.id"
+	ClaimNamespaceLabelKey          = "claim-controller.io/claim-namespace"
+	ProfileLabelKey                 = "claim-controller.io/profile"
+	TeamLabelKey                    = "claim-controller.io/team"
+	TenantLabelKey                  = "claim-controller.io/tenant"
+	PriorityLabelKey                = "claim-controller.io/priority"
+	IdempotencyKeyLabelKey          = "claim-controller.io/idempotency-key"
+	ExpiresAtAnnotationKey          = "claim-controller.io/expires-at"
+	ClaimedAtAnnotationKey          = "claim-controller.io/claimed-at"
+	ExpiringSoonAnnotationKey       = "claim-controller.io/expiring-soon"
+	CreatedByAnnotationKey          = "claim-controller.io/created-by"
+	CreatedByAnnotationValue        = "claim-controller"
+	OwnerAnnotationKey              = "claim-controller.io/owner"
+	PurposeAnnotationKey            = "claim-controller.io/purpose"
+	RepoAnnotationKey               = "claim-controller.io/repo"
+	PipelineURLAnnotationKey        = "claim-controller.io/pipeline-url"
+	MetadataLabelPrefix             = "meta.claim-controller.io/"
+	PreProvisionedAnnotationKey     = "claim-controller.io/pre-provisioned"
+	LazyProvisioningAnnotationKey   = "claim.controller/lazy-provisionning"
+	ValuesOverridesAnnotationKey    = "claim-controller.io/values-overrides"
+	ReturnFromAnnotationKey         = "claim.controller/return-from"
+	ReadyWhenAnnotationKey          = "claim.controller/ready-when"
+	WaveAnnotationKey               = "claim.controller/wave"
+	HTTPProbeAnnotationKey          = "claim.controller/http-probe"
+	ClaimNamespaceAnnotationKey     = "claim-controller.io/namespace"
+	NamespaceCleanupFinalizer       = "claim-controller.io/namespace-cleanup"
+	ClusterAnnotationKey            = "claim-controller.io/cluster"
+	RemoteCleanupFinalizer          = "claim-controller.io/remote-cleanup"
+	HelmReleaseFinalizer            = "claim-controller.io/helm-release"
+	HelmReleaseAppliedAnnotationKey = "claim-controller.io/helm-release-applied"
+	BackendAnnotationKey            = "claim-controller.io/backend"
+	ProfileTypeAnnotationKey        = "claim-controller.io/profile-type"
+	TemplateHashAnnotationKey       = "claim-controller.io/template-hash"
+	TemplateSourceAnnotationKey     = "claim-controller.io/template-source"
+	ValuesHashAnnotationKey         = "claim-controller.io/values-hash"
+	ValuesSourceAnnotationKey       = "claim-controller.io/values-source"
+	ClonedFromAnnotationKey         = "claim-controller.io/cloned-from"
+	ReleasedAtAnnotationKey         = "claim-controller.io/released-at"
+	SuspendedAtAnnotationKey        = "claim-controller.io/suspended-at"
+	SuspendedUntilAnnotationKey     = "claim-controller.io/suspended-until"
+	StartAtAnnotationKey            = "claim-controller.io/start-at"
+	WorkingHoursExemptAnnotationKey = "claim-controller.io/working-hours-exempt"
+	RequestIDAnnotationKey          = "claim-controller.io/request-id"
+	MinReadyAnnotationKey           = "claim-controller.io/min-ready"
+	FailedAtAnnotationKey           = "claim-controller.io/failed-at"
+	ReleasedByAnnotationKey         = "claim-controller.io/released-by"
+	TombstoneLabelKey               = "claim-controller.io/tombstone"
+	RenderedResourcesDataKey        = "renderedResources"
+	ReturnValuesDataKey             = "returnValues"
+	ClaimStatusDataKey              = "claimStatus"
+	ClaimStatusMessageDataKey       = "claimStatusMessage"
+	ClaimResourcesStatusDataKey     = "claimResourcesStatus"
+	ClaimConditionsDataKey          = "claimConditions"
+	HelmReleaseValuesDataKey        = "helmReleaseValues"
+	TombstoneDataKey                = "tombstone"
 )
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MinReady relaxes claim readiness: the claim is ready once the listed
+// resources (Kind or Kind/name) are ready, or once the given percentage of
+// its resources is ready. Both may be combined, e.g. "Deployment/api,50%".
+type MinReady struct {
+	percent  int
+	required []minReadyResource
+}
+
+type minReadyResource struct {
+	kind string
+	name string
+}
+
+func ParseMinReady(spec string) (*MinReady, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	policy := &MinReady{}
+	for _, raw := range strings.Split(spec, ",") {
+		entry := strings.TrimSpace(raw)
+		if entry == "" {
+			continue
+		}
+		if percent, ok := strings.CutSuffix(entry, "%"); ok {
+			value, err := strconv.Atoi(strings.TrimSpace(percent))
+			if err != nil || value <= 0 || value > 100 {
+				return nil, fmt.Errorf("invalid minReady percentage %q: must be between 1%% and 100%%", entry)
+			}
+			if policy.percent != 0 {
+				return nil, fmt.Errorf("minReady %q sets more than one percentage", spec)
+			}
+			policy.percent = value
+			continue
+		}
+		kind, name, _ := strings.Cut(entry, "/")
+		kind, name = strings.TrimSpace(kind), strings.TrimSpace(name)
+		if kind == "" || strings.Contains(name, "/") || (strings.Contains(entry, "/") && name == "") {
+			return nil, fmt.Errorf("invalid minReady entry %q: expected Kind, Kind/name or a percentage", entry)
+		}
+		policy.required = append(policy.required, minReadyResource{kind: kind, name: name})
+	}
+	if policy.percent == 0 && len(policy.required) == 0 {
+		return nil, fmt.Errorf("minReady %q defines no requirement", spec)
+	}
+	return policy, nil
+}
+
+func (p *MinReady) String() string {
+	parts := make([]string, 0, len(p.required)+1)
+	for _, resource := range p.required {
+		if resource.name == "" {
+			parts = append(parts, resource.kind)
+			continue
+		}
+		parts = append(parts, resource.kind+"/"+resource.name)
+	}
+	if p.percent > 0 {
+		parts = append(parts, strconv.Itoa(p.percent)+"%")
+	}
+	return strings.Join(parts, ",")
+}
+
+// satisfied reports whether the policy is met. Every required entry must match
+// at least one resource, so a typo in a kind never makes a claim ready early.
+func (p *MinReady) satisfied(statuses []resourceReadiness) bool {
+	if len(statuses) == 0 {
+		return false
+	}
+	readyCount := 0
+	for _, status := range statuses {
+		if status.Ready {
+			readyCount++
+		}
+	}
+	if p.percent > 0 && readyCount*100 < p.percent*len(statuses) {
+		return false
+	}
+	for _, resource := range p.required {
+		matched := false
+		for _, status := range statuses {
+			if !resource.matches(status) {
+				continue
+			}
+			if !status.Ready {
+				return false
+			}
+			matched = true
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (r minReadyResource) matches(status resourceReadiness) bool {
+	if !strings.EqualFold(r.kind, status.Kind) {
+		return false
+	}
+	return r.name == "" || r.name == status.Name
+}
+
+// claimMinReady returns the claim minReady policy. An unparsable annotation
+// is reported and ignored, so the claim waits for every resource.
+func (r *ClaimReconciler) claimMinReady(ctx context.Context, claim *corev1.ConfigMap) *MinReady {
+	policy, err := ParseMinReady(claim.Annotations[MinReadyAnnotationKey])
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "ignoring invalid minReady annotation", "claim", claim.Name)
+		r.recordEvent(claim, corev1.EventTypeWarning, "InvalidMinReady", "Ignoring %s annotation: %v", MinReadyAnnotationKey, err)
+		return nil
+	}
+	return policy
+}
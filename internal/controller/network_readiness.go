@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func assessServiceReadiness(ctx context.Context, target *resourceTarget, obj *unstructured.Unstructured) (bool, string, error) {
+	serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	if strings.EqualFold(serviceType, "ExternalName") {
+		externalName, _, _ := unstructured.NestedString(obj.Object, "spec", "externalName")
+		return true, fmt.Sprintf("external name %s", externalName), nil
+	}
+
+	if strings.EqualFold(serviceType, "LoadBalancer") {
+		addresses := loadBalancerAddresses(obj)
+		if len(addresses) == 0 {
+			return false, "waiting for load balancer address", nil
+		}
+	}
+
+	selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+	if len(selector) == 0 {
+		return true, "service without selector", nil
+	}
+
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := target.List(ctx, slices, client.InNamespace(obj.GetNamespace()), client.MatchingLabels{discoveryv1.LabelServiceName: obj.GetName()}); err != nil {
+		return false, "", err
+	}
+
+	var addresses []string
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			addresses = append(addresses, endpoint.Addresses...)
+		}
+	}
+	if len(addresses) == 0 {
+		return false, "service has no ready endpoints", nil
+	}
+
+	message := fmt.Sprintf("service endpoints ready (%s)", strings.Join(addresses, ", "))
+	if lbAddresses := loadBalancerAddresses(obj); len(lbAddresses) > 0 {
+		message = fmt.Sprintf("%s, load balancer %s", message, strings.Join(lbAddresses, ", "))
+	}
+	return true, message, nil
+}
+
+func assessIngressReadiness(obj *unstructured.Unstructured) (bool, string) {
+	addresses := loadBalancerAddresses(obj)
+	if len(addresses) == 0 {
+		return false, "waiting for ingress address"
+	}
+	return true, fmt.Sprintf("ingress address %s", strings.Join(addresses, ", "))
+}
+
+func loadBalancerAddresses(obj *unstructured.Unstructured) []string {
+	ingress, found, _ := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+	if !found {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(ingress))
+	for _, rawEntry := range ingress {
+		entry, ok := rawEntry.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ip, _, _ := unstructured.NestedString(entry, "ip"); ip != "" {
+			addresses = append(addresses, ip)
+			continue
+		}
+		if hostname, _, _ := unstructured.NestedString(entry, "hostname"); hostname != "" {
+			addresses = append(addresses, hostname)
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	readyWhenEnvOnce sync.Once
+	readyWhenEnv     *cel.Env
+	readyWhenEnvErr  error
+	readyWhenCache   sync.Map
+)
+
+func readyWhenEnvironment() (*cel.Env, error) {
+	readyWhenEnvOnce.Do(func() {
+		readyWhenEnv, readyWhenEnvErr = cel.NewEnv(
+			cel.Variable("self", cel.DynType),
+			cel.Variable("apiVersion", cel.StringType),
+			cel.Variable("kind", cel.StringType),
+			cel.Variable("metadata", cel.DynType),
+			cel.Variable("spec", cel.DynType),
+			cel.Variable("status", cel.DynType),
+		)
+	})
+	return readyWhenEnv, readyWhenEnvErr
+}
+
+func compileReadyWhen(expression string) (cel.Program, error) {
+	if cached, ok := readyWhenCache.Load(expression); ok {
+		return cached.(cel.Program), nil
+	}
+
+	env, err := readyWhenEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("compile ready-when expression %q: %w", expression, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("ready-when expression %q must evaluate to a bool, got %s", expression, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("build ready-when expression %q: %w", expression, err)
+	}
+
+	readyWhenCache.Store(expression, program)
+	return program, nil
+}
+
+func evaluateReadyWhen(obj *unstructured.Unstructured, expression string) (bool, string) {
+	program, err := compileReadyWhen(expression)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	activation := map[string]any{
+		"self":       obj.Object,
+		"apiVersion": obj.GetAPIVersion(),
+		"kind":       obj.GetKind(),
+		"metadata":   objectField(obj, "metadata"),
+		"spec":       objectField(obj, "spec"),
+		"status":     objectField(obj, "status"),
+	}
+
+	out, _, err := program.Eval(activation)
+	if err != nil {
+		return false, fmt.Sprintf("ready-when %q not satisfied: %v", expression, err)
+	}
+
+	ready, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Sprintf("ready-when %q returned %T, expected bool", expression, out.Value())
+	}
+	if !ready {
+		return false, fmt.Sprintf("ready-when %q is false", expression)
+	}
+	return true, fmt.Sprintf("ready-when %q is true", expression)
+}
+
+func objectField(obj *unstructured.Unstructured, field string) map[string]any {
+	value, ok := obj.Object[field].(map[string]any)
+	if !ok {
+		return map[string]any{}
+	}
+	return value
+}
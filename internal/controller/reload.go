@@ -0,0 +1,19 @@
+package controller
+
+import "time"
+
+type Tunables struct {
+	DefaultTTL        time.Duration
+	ReconcileInterval time.Duration
+}
+
+func (r *ClaimReconciler) Reload(tunables Tunables) {
+	r.tunables.Store(&tunables)
+}
+
+func (r *ClaimReconciler) currentTunables() Tunables {
+	if tunables := r.tunables.Load(); tunables != nil {
+		return *tunables
+	}
+	return Tunables{DefaultTTL: r.DefaultTTL, ReconcileInterval: r.ReconcileInterval}
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	renderedResourcesGzipPrefix    = "gzip+base64:"
+	renderedResourcesSpilledPrefix = "secrets:"
+	renderedResourcesBudget        = 900 * 1024
+	RenderedChunkDataKey           = "chunk"
+)
+
+func RenderedChunkSecretName(claimName string, index int) string {
+	return fmt.Sprintf("%s-rendered-%d", claimName, index)
+}
+
+func PackRenderedResources(raw []byte) (string, [][]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(raw); err != nil {
+		return "", nil, fmt.Errorf("compress rendered resources: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", nil, fmt.Errorf("compress rendered resources: %w", err)
+	}
+
+	if base64.StdEncoding.EncodedLen(compressed.Len()) <= renderedResourcesBudget {
+		return renderedResourcesGzipPrefix + base64.StdEncoding.EncodeToString(compressed.Bytes()), nil, nil
+	}
+
+	data := compressed.Bytes()
+	chunks := make([][]byte, 0, len(data)/renderedResourcesBudget+1)
+	for len(data) > 0 {
+		size := min(len(data), renderedResourcesBudget)
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return renderedResourcesSpilledPrefix + strconv.Itoa(len(chunks)), chunks, nil
+}
+
+func (r *ClaimReconciler) renderedResourcesJSON(ctx context.Context, claim *corev1.ConfigMap) ([]byte, error) {
+	return RenderedResourcesJSON(ctx, r.Client, claim)
+}
+
+// RenderedResourcesJSON returns the rendered resources recorded on the claim,
+// reading the spilled chunk Secrets when needed.
+func RenderedResourcesJSON(ctx context.Context, reader client.Reader, claim *corev1.ConfigMap) ([]byte, error) {
+	stored := claim.Data[RenderedResourcesDataKey]
+	if rawCount, spilled := strings.CutPrefix(stored, renderedResourcesSpilledPrefix); spilled {
+		count, err := strconv.Atoi(rawCount)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid rendered resources chunk count %q", rawCount)
+		}
+		var compressed bytes.Buffer
+		for i := range count {
+			secret := &corev1.Secret{}
+			if err := reader.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: RenderedChunkSecretName(claim.Name, i)}, secret); err != nil {
+				return nil, fmt.Errorf("read rendered resources chunk %d: %w", i, err)
+			}
+			compressed.Write(secret.Data[RenderedChunkDataKey])
+		}
+		return gunzip(compressed.Bytes())
+	}
+
+	encoded, compressed := strings.CutPrefix(stored, renderedResourcesGzipPrefix)
+	if !compressed {
+		return []byte(stored), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode rendered resources: %w", err)
+	}
+	return gunzip(data)
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress rendered resources: %w", err)
+	}
+	defer reader.Close()
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decompress rendered resources: %w", err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+func resolveReturnFromDirective(obj *unstructured.Unstructured, directive string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, entry := range splitReturnFromDirective(directive) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		expression := strings.TrimSpace(parts[1])
+		if key == "" || expression == "" {
+			continue
+		}
+
+		parser := jsonpath.New(key)
+		if err := parser.Parse(expression); err != nil {
+			return nil, fmt.Errorf("invalid return-from expression for %q: %w", key, err)
+		}
+
+		var buf bytes.Buffer
+		if err := parser.Execute(&buf, obj.Object); err != nil {
+			return nil, fmt.Errorf("return value %q not available yet: %w", key, err)
+		}
+		value := strings.TrimSpace(buf.String())
+		if value == "" {
+			return nil, fmt.Errorf("return value %q not available yet", key)
+		}
+		out[key] = value
+	}
+
+	return out, nil
+}
+
+func splitReturnFromDirective(directive string) []string {
+	var entries []string
+	var current strings.Builder
+	depth := 0
+
+	flush := func() {
+		if entry := strings.TrimSpace(current.String()); entry != "" {
+			entries = append(entries, entry)
+		}
+		current.Reset()
+	}
+
+	for _, char := range directive {
+		switch {
+		case char == '{':
+			depth++
+		case char == '}' && depth > 0:
+			depth--
+		case depth == 0 && (char == ',' || char == ';' || char == '\n'):
+			flush()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	flush()
+
+	return entries
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const ClaimStatusScheduled = "scheduled"
+
+func scheduledStart(claim *corev1.ConfigMap, now time.Time) (time.Time, bool) {
+	raw := claim.Annotations[StartAtAnnotationKey]
+	if raw == "" {
+		return time.Time{}, false
+	}
+	startAt, err := time.Parse(time.RFC3339, raw)
+	if err != nil || !now.Before(startAt) {
+		return time.Time{}, false
+	}
+	return startAt, true
+}
+
+func (r *ClaimReconciler) markClaimScheduled(ctx context.Context, claim *corev1.ConfigMap, startAt time.Time) error {
+	message := fmt.Sprintf("scheduled to start at %s", startAt.UTC().Format(time.RFC3339))
+	current := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(claim), current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if current.Data[ClaimStatusDataKey] == ClaimStatusScheduled && current.Data[ClaimStatusMessageDataKey] == message {
+		return nil
+	}
+	conditionsJSON, err := mergeClaimConditions(current, newCondition(ConditionProvisioned, false, "Scheduled", message))
+	if err != nil {
+		return err
+	}
+	return r.patchClaimStatus(ctx, current, map[string]string{
+		ClaimStatusDataKey:        ClaimStatusScheduled,
+		ClaimStatusMessageDataKey: message,
+		ClaimConditionsDataKey:    conditionsJSON,
+	})
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestScheduledStart(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		startAt       string
+		wantScheduled bool
+	}{
+		{name: "no startAt"},
+		{name: "future startAt", startAt: now.Add(time.Hour).Format(time.RFC3339), wantScheduled: true},
+		{name: "startAt reached", startAt: now.Format(time.RFC3339)},
+		{name: "past startAt", startAt: now.Add(-time.Hour).Format(time.RFC3339)},
+		{name: "invalid startAt", startAt: "tomorrow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+			if tt.startAt != "" {
+				claim.Annotations[StartAtAnnotationKey] = tt.startAt
+			}
+			startAt, scheduled := scheduledStart(claim, now)
+			if scheduled != tt.wantScheduled {
+				t.Fatalf("scheduled = %v, want %v", scheduled, tt.wantScheduled)
+			}
+			if scheduled && startAt.Format(time.RFC3339) != tt.startAt {
+				t.Errorf("startAt = %s, want %s", startAt, tt.startAt)
+			}
+		})
+	}
+}
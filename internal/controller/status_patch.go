@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const StatusFieldOwner = "claim-controller-status"
+
+func (r *ClaimReconciler) patchClaimStatus(ctx context.Context, current *corev1.ConfigMap, updates map[string]string) error {
+	base := current.DeepCopy()
+	if current.Data == nil {
+		current.Data = map[string]string{}
+	}
+	for key, value := range updates {
+		current.Data[key] = value
+	}
+	return client.IgnoreNotFound(r.Patch(ctx, current, client.MergeFrom(base), client.FieldOwner(StatusFieldOwner)))
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func assessPersistentVolumeClaimReadiness(ctx context.Context, target *resourceTarget, obj *unstructured.Unstructured) (bool, string) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Bound":
+		volumeName, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeName")
+		return true, fmt.Sprintf("pvc bound to %s", volumeName)
+	case "Lost":
+		return false, "pvc lost its volume"
+	}
+
+	if waitsForFirstConsumer(ctx, target, obj) {
+		return true, "pvc pending until first consumer (WaitForFirstConsumer)"
+	}
+
+	if phase == "" {
+		phase = "Pending"
+	}
+	return false, fmt.Sprintf("pvc phase=%s", phase)
+}
+
+func waitsForFirstConsumer(ctx context.Context, target *resourceTarget, obj *unstructured.Unstructured) bool {
+	if _, selected := obj.GetAnnotations()["volume.kubernetes.io/selected-node"]; selected {
+		return false
+	}
+
+	storageClassName, _, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName")
+	if storageClassName == "" || target.apiReader == nil {
+		return false
+	}
+
+	storageClass := &storagev1.StorageClass{}
+	if err := target.apiReader.Get(ctx, client.ObjectKey{Name: storageClassName}, storageClass); err != nil {
+		return false
+	}
+
+	return storageClass.VolumeBindingMode != nil && *storageClass.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+}
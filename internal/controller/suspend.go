@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func suspendedUntil(claim *corev1.ConfigMap, now time.Time) (time.Time, bool) {
+	if claim.Annotations[SuspendedAtAnnotationKey] == "" {
+		return time.Time{}, false
+	}
+	until, err := time.Parse(time.RFC3339, claim.Annotations[SuspendedUntilAnnotationKey])
+	if err != nil || !now.Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
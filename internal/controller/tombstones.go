@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	EndReasonReleased            = "released"
+	EndReasonExpired             = "expired"
+	EndReasonOutsideWorkingHours = "outside_working_hours"
+	EndReasonPreempted           = "preempted"
+	EndReasonFailed              = "failed"
+
+	tombstoneSuffix        = "-tombstone"
+	tombstoneSweepInterval = 5 * time.Minute
+)
+
+// Tombstone is the lightweight record kept once a claim is gone. Tombstones
+// are ConfigMaps carrying only TombstoneLabelKey, so claim listings, quotas
+// and the controller cache never see them.
+type Tombstone struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Namespace       string  `json:"namespace"`
+	Profile         string  `json:"profile,omitempty"`
+	Team            string  `json:"team,omitempty"`
+	Tenant          string  `json:"tenant,omitempty"`
+	Owner           string  `json:"owner,omitempty"`
+	Purpose         string  `json:"purpose,omitempty"`
+	RequestID       string  `json:"requestId,omitempty"`
+	Reason          string  `json:"reason"`
+	EndedBy         string  `json:"endedBy,omitempty"`
+	LastStatus      string  `json:"lastStatus,omitempty"`
+	LastMessage     string  `json:"lastMessage,omitempty"`
+	CreatedAt       string  `json:"createdAt"`
+	ClaimedAt       string  `json:"claimedAt,omitempty"`
+	ExpiresAt       string  `json:"expiresAt,omitempty"`
+	EndedAt         string  `json:"endedAt"`
+	LifetimeSeconds float64 `json:"lifetimeSeconds"`
+}
+
+func newTombstone(claim *corev1.ConfigMap, reason, endedBy string, now time.Time) Tombstone {
+	tombstone := Tombstone{
+		ID:          claim.Labels[ClaimLabelKeyId],
+		Name:        claim.Name,
+		Namespace:   claim.Namespace,
+		Profile:     claim.Labels[ProfileLabelKey],
+		Team:        claim.Labels[TeamLabelKey],
+		Tenant:      claim.Labels[TenantLabelKey],
+		Owner:       claim.Annotations[OwnerAnnotationKey],
+		Purpose:     claim.Annotations[PurposeAnnotationKey],
+		RequestID:   claim.Annotations[RequestIDAnnotationKey],
+		Reason:      reason,
+		EndedBy:     endedBy,
+		LastStatus:  claim.Data[ClaimStatusDataKey],
+		LastMessage: claim.Data[ClaimStatusMessageDataKey],
+		CreatedAt:   claim.CreationTimestamp.UTC().Format(time.RFC3339),
+		ClaimedAt:   claim.Annotations[ClaimedAtAnnotationKey],
+		ExpiresAt:   claim.Annotations[ExpiresAtAnnotationKey],
+		EndedAt:     now.UTC().Format(time.RFC3339),
+	}
+	if !claim.CreationTimestamp.IsZero() {
+		tombstone.LifetimeSeconds = max(0, now.Sub(claim.CreationTimestamp.Time).Seconds())
+	}
+	return tombstone
+}
+
+// RecordTombstone stores the history record of a claim about to be deleted.
+// It is a no-op when retention is disabled and for pre-provisioned claims,
+// which were never handed out.
+func RecordTombstone(ctx context.Context, c client.Client, claim *corev1.ConfigMap, reason, endedBy string, retention time.Duration) error {
+	if retention <= 0 || isPreProvisionedClaim(claim) {
+		return nil
+	}
+	encoded, err := json.Marshal(newTombstone(claim, reason, endedBy, time.Now()))
+	if err != nil {
+		return err
+	}
+	tombstone := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      claim.Name + tombstoneSuffix,
+			Namespace: claim.Namespace,
+			Labels:    map[string]string{TombstoneLabelKey: "true"},
+		},
+		Data: map[string]string{TombstoneDataKey: string(encoded)},
+	}
+	if err := c.Create(ctx, tombstone); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// TombstoneFrom decodes a tombstone ConfigMap.
+func TombstoneFrom(cm *corev1.ConfigMap) (Tombstone, bool) {
+	var tombstone Tombstone
+	if cm.Labels[TombstoneLabelKey] != "true" || json.Unmarshal([]byte(cm.Data[TombstoneDataKey]), &tombstone) != nil {
+		return Tombstone{}, false
+	}
+	return tombstone, true
+}
+
+func (r *ClaimReconciler) recordTombstone(ctx context.Context, claim *corev1.ConfigMap, reason, endedBy string) {
+	if err := RecordTombstone(ctx, r.Client, claim, reason, endedBy, r.HistoryRetention); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to record claim tombstone", "claim", claim.Name)
+	}
+}
+
+func (r *ClaimReconciler) endReason(claim *corev1.ConfigMap, now time.Time) string {
+	switch {
+	case isFailedClaim(claim):
+		return EndReasonFailed
+	case claim.Annotations[ReleasedAtAnnotationKey] != "":
+		return EndReasonReleased
+	case now.Before(r.claimExpiresAt(claim)) && r.outsideWorkingHours(claim, now):
+		return EndReasonOutsideWorkingHours
+	default:
+		return EndReasonExpired
+	}
+}
+
+// runTombstoneSweep deletes tombstones older than the history retention.
+// Tombstones are outside the cache label selector, so they are read uncached.
+func (r *ExpiryReconciler) runTombstoneSweep(ctx context.Context) error {
+	ticker := time.NewTicker(tombstoneSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := r.sweepTombstones(ctx, time.Now().Add(-r.HistoryRetention)); err != nil {
+			logf.FromContext(ctx).Error(err, "failed to sweep claim tombstones")
+		}
+	}
+}
+
+func (r *ExpiryReconciler) sweepTombstones(ctx context.Context, cutoff time.Time) error {
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	for _, namespace := range r.claimNamespaces() {
+		tombstones := &corev1.ConfigMapList{}
+		if err := reader.List(ctx, tombstones, client.InNamespace(namespace), client.MatchingLabels{TombstoneLabelKey: "true"}); err != nil {
+			return err
+		}
+		for i := range tombstones.Items {
+			if tombstones.Items[i].CreationTimestamp.After(cutoff) {
+				continue
+			}
+			if err := r.Delete(ctx, &tombstones.Items[i]); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ClaimStatusDegraded is reported instead of pending once a claim has been
+// ready, so clients can tell a broken environment from one still starting.
+const ClaimStatusDegraded = "degraded"
+
+func claimStatusValue(previous string, allReady bool) string {
+	switch {
+	case allReady:
+		return "ready"
+	case wasReady(previous):
+		return ClaimStatusDegraded
+	default:
+		return "pending"
+	}
+}
+
+func wasReady(status string) bool {
+	return status == "ready" || status == ClaimStatusDegraded
+}
+
+// recordTransition emits an event when the claim readiness changes, naming the
+// resource holding it back so `kubectl describe` explains the change.
+func (r *ClaimReconciler) recordTransition(claim *corev1.ConfigMap, previous, next, summary string, resources []resourceReadiness) {
+	if previous == next {
+		return
+	}
+	switch next {
+	case "ready":
+		r.recordEvent(claim, corev1.EventTypeNormal, "Ready", "Claim is ready: %s", summary)
+	case ClaimStatusDegraded:
+		if previous == "ready" {
+			r.recordEvent(claim, corev1.EventTypeWarning, "Degraded", "Claim is no longer ready: %s", blockingResource(resources))
+		}
+	}
+}
+
+func blockingResource(resources []resourceReadiness) string {
+	var blocking []resourceReadiness
+	for _, resource := range resources {
+		if !resource.Ready {
+			blocking = append(blocking, resource)
+		}
+	}
+	if len(blocking) == 0 {
+		return "no resource reported as not ready"
+	}
+	message := fmt.Sprintf("blocked by %s %s (%s)", blocking[0].Kind, blocking[0].Name, blocking[0].Message)
+	if len(blocking) > 1 {
+		message = fmt.Sprintf("%s and %d more", message, len(blocking)-1)
+	}
+	return message
+}
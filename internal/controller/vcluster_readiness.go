@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nonot/claim-controller/internal/vcluster"
+)
+
+func isVClusterClaim(claim *corev1.ConfigMap) bool {
+	return strings.TrimSpace(claim.Annotations[ProfileTypeAnnotationKey]) == vcluster.ProfileType
+}
+
+func assessVClusterReadiness(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap) (bool, string, error) {
+	if target.apiReader == nil {
+		return false, "vcluster readiness requires an API reader", nil
+	}
+
+	namespace := resourceNamespace(claim)
+	secret := &corev1.Secret{}
+	if err := target.apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: vcluster.SecretName(claim.Name)}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, "vcluster kubeconfig secret not created yet", nil
+		}
+		return false, "", err
+	}
+	if target.remote() {
+		return true, "vcluster kubeconfig available", nil
+	}
+
+	cfg, err := vcluster.RESTConfig(secret, claim.Name, namespace)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	if err := vcluster.CheckReady(ctx, cfg); err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "vcluster apiserver ready", nil
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+func (r *ClaimReconciler) ensureResourceWatch(gvk schema.GroupVersionKind) error {
+	if r.watcher == nil || r.cache == nil {
+		return nil
+	}
+	if _, watched := r.watchedKinds.LoadOrStore(gvk, struct{}{}); watched {
+		return nil
+	}
+
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	if err := r.watcher.Watch(source.Kind[client.Object](r.cache, obj, handler.EnqueueRequestsFromMapFunc(claimForResource))); err != nil {
+		r.watchedKinds.Delete(gvk)
+		return fmt.Errorf("watch %s: %w", gvk.String(), err)
+	}
+	return nil
+}
+
+func claimForResource(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	claimName := labels[ClaimLabelKey]
+	if claimName == "" || labels[ManagedByLabelKey] != ManagedByLabelValue {
+		return nil
+	}
+	namespace := labels[ClaimNamespaceLabelKey]
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: claimName}}}
+}
+
+func (r *ClaimReconciler) resourceExists(ctx context.Context, target *resourceTarget, obj *unstructured.Unstructured) (bool, error) {
+	existing := &metav1.PartialObjectMetadata{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	key := client.ObjectKeyFromObject(obj)
+
+	if !target.remote() && obj.GetNamespace() != "" && r.cache != nil {
+		if err := r.ensureResourceWatch(obj.GroupVersionKind()); err != nil {
+			return false, err
+		}
+		err := r.cache.Get(ctx, key, existing)
+		if err == nil {
+			return true, nil
+		}
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+	}
+
+	if err := target.Get(ctx, key, existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		resourceErrorsTotal.WithLabelValues(obj.GetKind(), "get").Inc()
+		return false, err
+	}
+	return true, nil
+}
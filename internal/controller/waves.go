@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func resourceWave(resource *unstructured.Unstructured) int {
+	hooks := helmHooks(resource)
+	if slices.Contains(hooks, hookPreInstall) {
+		return preInstallStage + helmHookWeight(resource)
+	}
+	if slices.Contains(hooks, hookPostInstall) {
+		return postInstallStage + helmHookWeight(resource)
+	}
+
+	wave, err := strconv.Atoi(strings.TrimSpace(resource.GetAnnotations()[WaveAnnotationKey]))
+	if err != nil {
+		return 0
+	}
+	return wave
+}
+
+func resourceWaves(resources []*unstructured.Unstructured) [][]*unstructured.Unstructured {
+	byWave := map[int][]*unstructured.Unstructured{}
+	for _, resource := range resources {
+		wave := resourceWave(resource)
+		byWave[wave] = append(byWave[wave], resource)
+	}
+
+	order := make([]int, 0, len(byWave))
+	for wave := range byWave {
+		order = append(order, wave)
+	}
+	slices.Sort(order)
+
+	waves := make([][]*unstructured.Unstructured, 0, len(order))
+	for _, wave := range order {
+		waves = append(waves, byWave[wave])
+	}
+	return waves
+}
+
+func (r *ClaimReconciler) waveReady(ctx context.Context, target *resourceTarget, claim *corev1.ConfigMap, resources []*unstructured.Unstructured) (bool, error) {
+	isPreProvisioned := isPreProvisionedClaim(claim)
+	for _, resourceTemplate := range resources {
+		if isPreProvisioned && isLazyProvisionedResource(resourceTemplate) {
+			continue
+		}
+
+		resourceObj := &unstructured.Unstructured{}
+		resourceObj.SetGroupVersionKind(resourceTemplate.GroupVersionKind())
+		resourceObj.SetName(resourceTemplate.GetName())
+		isNamespaced, err := target.isNamespaced(resourceObj)
+		if err != nil {
+			return false, err
+		}
+		if isNamespaced {
+			resourceObj.SetNamespace(resourceNamespace(claim))
+		}
+
+		if err := target.Get(ctx, client.ObjectKeyFromObject(resourceObj), resourceObj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			resourceErrorsTotal.WithLabelValues(resourceObj.GetKind(), "get").Inc()
+			return false, err
+		}
+		ready, _, _, err := r.assessClaimResource(ctx, target, claim, resourceTemplate, resourceObj)
+		if err != nil || !ready {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+type WorkingHours struct {
+	windows  []workingWindow
+	location *time.Location
+}
+
+type workingWindow struct {
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func ParseWorkingHours(spec, timezone string) (*WorkingHours, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	location := time.UTC
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("load working hours timezone: %w", err)
+		}
+		location = loaded
+	}
+
+	hours := &WorkingHours{location: location}
+	for _, raw := range strings.Split(spec, ";") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		window, err := parseWorkingWindow(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid working hours window %q: %w", strings.TrimSpace(raw), err)
+		}
+		hours.windows = append(hours.windows, window)
+	}
+	if len(hours.windows) == 0 {
+		return nil, fmt.Errorf("working hours %q define no window", spec)
+	}
+	return hours, nil
+}
+
+func parseWorkingWindow(raw string) (workingWindow, error) {
+	fields := strings.Fields(raw)
+	if len(fields) != 2 {
+		return workingWindow{}, fmt.Errorf("expected \"<days> <HH:MM>-<HH:MM>\"")
+	}
+
+	var window workingWindow
+	for _, part := range strings.Split(strings.ToLower(fields[0]), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[from]
+		if !ok {
+			return workingWindow{}, fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[to]; !ok {
+				return workingWindow{}, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			window.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	startRaw, endRaw, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return workingWindow{}, fmt.Errorf("expected a <HH:MM>-<HH:MM> time range")
+	}
+	start, err := parseClock(startRaw)
+	if err != nil {
+		return workingWindow{}, err
+	}
+	end, err := parseClock(endRaw)
+	if err != nil {
+		return workingWindow{}, err
+	}
+	if end <= start {
+		return workingWindow{}, fmt.Errorf("end %s must be after start %s", endRaw, startRaw)
+	}
+	window.start, window.end = start, end
+	return window, nil
+}
+
+func parseClock(raw string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", raw)
+	if err != nil {
+		if raw == "24:00" {
+			return 24 * time.Hour, nil
+		}
+		return 0, fmt.Errorf("invalid time %q", raw)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func (h *WorkingHours) Contains(now time.Time) bool {
+	_, open := h.closesAt(now)
+	return open
+}
+
+func (h *WorkingHours) closesAt(now time.Time) (time.Time, bool) {
+	local := now.In(h.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, h.location)
+	offset := local.Sub(midnight)
+
+	var closes time.Time
+	open := false
+	for _, window := range h.windows {
+		if !window.days[local.Weekday()] || offset < window.start || offset >= window.end {
+			continue
+		}
+		if end := midnight.Add(window.end); end.After(closes) {
+			closes = end
+		}
+		open = true
+	}
+	return closes, open
+}
+
+func (r *ClaimReconciler) outsideWorkingHours(claim *corev1.ConfigMap, now time.Time) bool {
+	if r.WorkingHours == nil || strings.EqualFold(claim.Annotations[WorkingHoursExemptAnnotationKey], "true") {
+		return false
+	}
+	if _, scheduled := scheduledStart(claim, now); scheduled {
+		return false
+	}
+	return !r.WorkingHours.Contains(now)
+}
@@ -0,0 +1,143 @@
+package helmrelease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	BackendApply       = "apply"
+	BackendHelmRelease = "helm-release"
+
+	storageDriver = "secret"
+)
+
+func ParseBackend(raw string) (string, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(raw)); backend {
+	case "":
+		return BackendApply, nil
+	case BackendApply, BackendHelmRelease:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("unknown resource backend %q (expected %s or %s)", raw, BackendApply, BackendHelmRelease)
+	}
+}
+
+type Client struct {
+	config *rest.Config
+}
+
+func New(config *rest.Config) *Client {
+	return &Client{config: config}
+}
+
+// Render renders the release manifest without contacting the cluster.
+func (c *Client) Render(ctx context.Context, namespace, name string, chartObj *chart.Chart, values map[string]any) (string, error) {
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...any) {}})
+	install.ReleaseName = name
+	install.Namespace = namespace
+	install.DryRun = true
+	install.ClientOnly = true
+	rel, err := install.RunWithContext(ctx, chartObj, values)
+	if err != nil {
+		return "", fmt.Errorf("render helm release %s/%s: %w", namespace, name, err)
+	}
+	return rel.Manifest, nil
+}
+
+// Ensure installs the release, or upgrades it when it already exists.
+func (c *Client) Ensure(ctx context.Context, namespace, name string, chartObj *chart.Chart, values map[string]any) error {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	history, err := cfg.Releases.History(name)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return fmt.Errorf("read helm release %s/%s: %w", namespace, name, err)
+	}
+	if len(history) == 0 {
+		install := action.NewInstall(cfg)
+		install.ReleaseName = name
+		install.Namespace = namespace
+		install.CreateNamespace = true
+		if _, err := install.RunWithContext(ctx, chartObj, values); err != nil {
+			return fmt.Errorf("install helm release %s/%s: %w", namespace, name, err)
+		}
+		return nil
+	}
+
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = namespace
+	if _, err := upgrade.RunWithContext(ctx, name, chartObj, values); err != nil {
+		return fmt.Errorf("upgrade helm release %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+func (c *Client) Uninstall(namespace, name string) error {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+
+	uninstall := action.NewUninstall(cfg)
+	uninstall.IgnoreNotFound = true
+	if _, err := uninstall.Run(name); err != nil {
+		return fmt.Errorf("uninstall helm release %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+func (c *Client) actionConfig(namespace string) (*action.Configuration, error) {
+	cfg := &action.Configuration{}
+	getter := &restClientGetter{config: c.config, namespace: namespace}
+	if err := cfg.Init(getter, namespace, storageDriver, func(string, ...any) {}); err != nil {
+		return nil, fmt.Errorf("initialize helm client: %w", err)
+	}
+	return cfg, nil
+}
+
+type restClientGetter struct {
+	config    *rest.Config
+	namespace string
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(g.config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(client), nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	client, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	return restmapper.NewDeferredDiscoveryRESTMapper(client), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), &clientcmd.ConfigOverrides{
+		Context: clientcmdapi.Context{Namespace: g.namespace},
+	})
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	EventClaimCreated      = "claim.created"
+	EventClaimReady        = "claim.ready"
+	EventClaimRenewed      = "claim.renewed"
+	EventClaimExpiringSoon = "claim.expiring_soon"
+	EventClaimFailed       = "claim.failed"
+	EventClaimExpired      = "claim.expired"
+	EventClaimReleased     = "claim.released"
+)
+
+type Event struct {
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	ClaimID   string            `json:"claimId"`
+	ClaimName string            `json:"claimName"`
+	Namespace string            `json:"namespace"`
+	Profile   string            `json:"profile,omitempty"`
+	ExpiresAt string            `json:"expiresAt,omitempty"`
+	Message   string            `json:"message,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, event Event)
+}
+
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, event Event) {
+	for _, notifier := range m {
+		if notifier != nil {
+			notifier.Notify(ctx, event)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+var DefaultSlackEvents = []string{EventClaimExpiringSoon, EventClaimFailed}
+
+type SlackNotifier struct {
+	webhookURL string
+	publicURL  string
+	events     map[string]bool
+	client     *http.Client
+	logger     logr.Logger
+}
+
+func NewSlackNotifier(webhookURL, publicURL string, events []string, logger logr.Logger) *SlackNotifier {
+	if len(events) == 0 {
+		events = DefaultSlackEvents
+	}
+	enabled := make(map[string]bool, len(events))
+	for _, event := range events {
+		enabled[event] = true
+	}
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		events:     enabled,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+func (n *SlackNotifier) Notify(_ context.Context, event Event) {
+	if !n.events[event.Type] {
+		return
+	}
+
+	body, err := json.Marshal(map[string]string{"text": n.format(event)})
+	if err != nil {
+		n.logger.Error(err, "failed to encode slack message", "event", event.Type)
+		return
+	}
+
+	go func() {
+		if err := n.post(body); err != nil {
+			n.logger.Error(err, "failed to send slack notification", "event", event.Type, "claim", event.ClaimID)
+		}
+	}()
+}
+
+func (n *SlackNotifier) format(event Event) string {
+	var text strings.Builder
+	switch event.Type {
+	case EventClaimExpiringSoon:
+		fmt.Fprintf(&text, ":hourglass: Claim `%s` is about to expire", event.ClaimID)
+	case EventClaimFailed:
+		fmt.Fprintf(&text, ":x: Claim `%s` failed to become ready", event.ClaimID)
+	case EventClaimExpired:
+		fmt.Fprintf(&text, ":wastebasket: Claim `%s` expired", event.ClaimID)
+	default:
+		fmt.Fprintf(&text, "Claim `%s`: %s", event.ClaimID, event.Type)
+	}
+	fmt.Fprintf(&text, " (namespace `%s`", event.Namespace)
+	if event.Profile != "" {
+		fmt.Fprintf(&text, ", profile `%s`", event.Profile)
+	}
+	text.WriteString(")")
+
+	if event.ExpiresAt != "" {
+		fmt.Fprintf(&text, "\nExpires at: %s", event.ExpiresAt)
+	}
+	if event.Message != "" {
+		fmt.Fprintf(&text, "\n%s", event.Message)
+	}
+
+	keys := make([]string, 0, len(event.Metadata))
+	for key := range event.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&text, "\n%s: %s", key, event.Metadata[key])
+	}
+
+	if event.Type == EventClaimExpiringSoon {
+		fmt.Fprintf(&text, "\nRenew: `POST %s/renew/%s`", n.publicURL, event.ClaimID)
+	}
+	return text.String()
+}
+
+func (n *SlackNotifier) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	SignatureHeader = "X-Claim-Controller-Signature"
+	EventHeader     = "X-Claim-Controller-Event"
+
+	webhookAttempts = 3
+)
+
+type WebhookNotifier struct {
+	urls   []string
+	secret []byte
+	client *http.Client
+	logger logr.Logger
+}
+
+func NewWebhookNotifier(urls []string, secret string, timeout time.Duration, logger logr.Logger) *WebhookNotifier {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookNotifier{
+		urls:   urls,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+	}
+}
+
+func (n *WebhookNotifier) Notify(_ context.Context, event Event) {
+	if len(n.urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.logger.Error(err, "failed to encode webhook event", "event", event.Type)
+		return
+	}
+
+	for _, url := range n.urls {
+		go n.deliver(url, event.Type, body)
+	}
+}
+
+func (n *WebhookNotifier) deliver(url, eventType string, body []byte) {
+	backoff := time.Second
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err := n.post(url, eventType, body)
+		if err == nil {
+			return
+		}
+		if attempt == webhookAttempts {
+			n.logger.Error(err, "failed to deliver webhook event", "event", eventType, "url", url)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(url, eventType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,125 @@
+package template
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const valuesCacheSize = 32
+
+var renderCacheLookupsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_render_cache_lookups_total",
+	Help: "Template and values cache lookups during rendering, by cache and result (hit or miss).",
+}, []string{"cache", "result"})
+
+type templateFile struct {
+	modTime time.Time
+	size    int64
+	data    []byte
+	hash    string
+}
+
+type templateFileCache struct {
+	mu    sync.Mutex
+	files map[string]*templateFile
+}
+
+var templateFiles = &templateFileCache{files: map[string]*templateFile{}}
+
+func (c *templateFileCache) load(path string) (*templateFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template file: %w", err)
+	}
+
+	c.mu.Lock()
+	cached, ok := c.files[path]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		renderCacheLookupsTotal.WithLabelValues("template", "hit").Inc()
+		return cached, nil
+	}
+	renderCacheLookupsTotal.WithLabelValues("template", "miss").Inc()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template file: %w", err)
+	}
+	file := &templateFile{modTime: info.ModTime(), size: info.Size(), data: data, hash: HashBytes(data)}
+	c.mu.Lock()
+	c.files[path] = file
+	c.mu.Unlock()
+	return file, nil
+}
+
+type valuesCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]chartutil.Values
+	order   [][sha256.Size]byte
+}
+
+var parsedValues = &valuesCache{entries: map[[sha256.Size]byte]chartutil.Values{}}
+
+func readValues(valuesData []byte) (chartutil.Values, error) {
+	key := sha256.Sum256(valuesData)
+
+	parsedValues.mu.Lock()
+	cached, ok := parsedValues.entries[key]
+	parsedValues.mu.Unlock()
+	if ok {
+		renderCacheLookupsTotal.WithLabelValues("values", "hit").Inc()
+		return copyValues(cached), nil
+	}
+	renderCacheLookupsTotal.WithLabelValues("values", "miss").Inc()
+
+	values, err := chartutil.ReadValues(valuesData)
+	if err != nil {
+		return nil, fmt.Errorf("decode values file: %w", err)
+	}
+
+	parsedValues.mu.Lock()
+	if _, exists := parsedValues.entries[key]; !exists {
+		if len(parsedValues.order) >= valuesCacheSize {
+			delete(parsedValues.entries, parsedValues.order[0])
+			parsedValues.order = parsedValues.order[1:]
+		}
+		parsedValues.entries[key] = copyValues(values)
+		parsedValues.order = append(parsedValues.order, key)
+	}
+	parsedValues.mu.Unlock()
+	return values, nil
+}
+
+func copyValues(values chartutil.Values) chartutil.Values {
+	return chartutil.Values(copyValue(map[string]any(values)).(map[string]any))
+}
+
+func copyValue(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, item := range typed {
+			out[key] = copyValue(item)
+		}
+		return out
+	case chartutil.Values:
+		return copyValue(map[string]any(typed))
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = copyValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,349 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+type ChartSource interface {
+	Chart() (*chart.Chart, error)
+	Description() string
+}
+
+var httpGetters = getter.Providers{
+	{Schemes: []string{"http", "https"}, New: getter.NewHTTPGetter},
+}
+
+type DirectoryChartSource struct {
+	dir      string
+	resolver dependencyResolver
+
+	mu    sync.Mutex
+	chart *chart.Chart
+	hash  string
+}
+
+func IsChartDirectory(path string) bool {
+	info, err := os.Stat(filepath.Join(path, "Chart.yaml"))
+	return err == nil && !info.IsDir()
+}
+
+func NewDirectoryChartSource(dir, cacheDir string, dockerConfigJSON []byte) (*DirectoryChartSource, error) {
+	if !IsChartDirectory(dir) {
+		return nil, fmt.Errorf("%s is not a chart directory", dir)
+	}
+
+	cacheDir, err := ensureCacheDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	registryClient, err := newRegistryClient(cacheDir, dockerConfigJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirectoryChartSource{
+		dir: dir,
+		resolver: dependencyResolver{
+			baseDir:        dir,
+			cacheDir:       cacheDir,
+			registryClient: registryClient,
+		},
+	}, nil
+}
+
+func (s *DirectoryChartSource) Chart() (*chart.Chart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, err := hashDirectory(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	if s.chart != nil && s.hash == hash {
+		return s.chart, nil
+	}
+
+	chartObj, err := loader.Load(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("load chart directory %s: %w", s.dir, err)
+	}
+	if err := s.resolver.resolve(chartObj); err != nil {
+		return nil, err
+	}
+
+	s.chart, s.hash = chartObj, hash
+	return s.chart, nil
+}
+
+func (s *DirectoryChartSource) Description() string {
+	return "dir:" + s.dir
+}
+
+type dependencyResolver struct {
+	baseDir        string
+	cacheDir       string
+	registryClient *registry.Client
+}
+
+func (r dependencyResolver) resolve(chartObj *chart.Chart) error {
+	if chartObj.Metadata == nil {
+		return nil
+	}
+
+	present := map[string]bool{}
+	for _, dependency := range chartObj.Dependencies() {
+		present[dependency.Name()] = true
+	}
+
+	for _, dependency := range chartObj.Metadata.Dependencies {
+		if dependency == nil || present[dependency.Name] {
+			continue
+		}
+
+		subchart, err := r.fetch(dependency)
+		if err != nil {
+			return fmt.Errorf("resolve dependency %q of chart %q: %w", dependency.Name, chartObj.Name(), err)
+		}
+		chartObj.AddDependency(subchart)
+		present[dependency.Name] = true
+		logger.Info("resolved chart dependency", "chart", chartObj.Name(), "dependency", dependency.Name, "version", dependency.Version, "repository", dependency.Repository)
+	}
+
+	return nil
+}
+
+func (r dependencyResolver) fetch(dependency *chart.Dependency) (*chart.Chart, error) {
+	repository := strings.TrimSpace(dependency.Repository)
+	switch {
+	case repository == "":
+		return nil, fmt.Errorf("not vendored in charts/ and no repository configured")
+	case strings.HasPrefix(repository, "file://"):
+		dir := strings.TrimPrefix(repository, "file://")
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.baseDir, dir)
+		}
+		subchart, err := loader.Load(dir)
+		if err != nil {
+			return nil, err
+		}
+		nested := r
+		nested.baseDir = dir
+		return subchart, nested.resolve(subchart)
+	case registry.IsOCI(repository):
+		chartRef := strings.TrimSuffix(repository, "/") + "/" + dependency.Name
+		version, err := r.ociVersion(chartRef, dependency.Version)
+		if err != nil {
+			return nil, err
+		}
+		ref := chartRef + ":" + version
+		return loadCachedArchive(r.cacheDir, ref, func() ([]byte, error) {
+			return pullOCIChart(r.registryClient, ref)
+		})
+	case strings.HasPrefix(repository, "http://"), strings.HasPrefix(repository, "https://"):
+		version := dependency.Version
+		var chartVersion *repo.ChartVersion
+		if _, err := semver.StrictNewVersion(version); err != nil {
+			if chartVersion, err = r.repoChartVersion(repository, dependency.Name, version); err != nil {
+				return nil, err
+			}
+			version = chartVersion.Version
+		}
+		key := fmt.Sprintf("%s/%s-%s", repository, dependency.Name, version)
+		return loadCachedArchive(r.cacheDir, key, func() ([]byte, error) {
+			if chartVersion == nil {
+				var err error
+				if chartVersion, err = r.repoChartVersion(repository, dependency.Name, version); err != nil {
+					return nil, err
+				}
+			}
+			if len(chartVersion.URLs) == 0 {
+				return nil, fmt.Errorf("chart %s %s has no downloadable URLs", dependency.Name, version)
+			}
+			chartURL, err := repo.ResolveReferenceURL(repository, chartVersion.URLs[0])
+			if err != nil {
+				return nil, fmt.Errorf("resolve chart url: %w", err)
+			}
+			return downloadChart(chartURL)
+		})
+	default:
+		return nil, fmt.Errorf("unsupported repository %q", repository)
+	}
+}
+
+// ociVersion returns the dependency version to pull: an exact version as is,
+// or the highest tag of chartRef matching a range such as "~1.2.0" or ">=2".
+func (r dependencyResolver) ociVersion(chartRef, version string) (string, error) {
+	if _, err := semver.StrictNewVersion(version); err == nil {
+		return version, nil
+	}
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	// Tags are sorted from the highest version down.
+	tags, err := r.registryClient.Tags(strings.TrimPrefix(chartRef, registry.OCIScheme+"://"))
+	if err != nil {
+		return "", fmt.Errorf("list tags of %s: %w", chartRef, err)
+	}
+	for _, tag := range tags {
+		if tagVersion, err := semver.NewVersion(tag); err == nil && constraint.Check(tagVersion) {
+			return tag, nil
+		}
+	}
+	return "", fmt.Errorf("no tag of %s matches %q", chartRef, version)
+}
+
+// repoChartVersion looks up the highest version of name matching version in
+// the index of an HTTP chart repository.
+func (r dependencyResolver) repoChartVersion(repository, name, version string) (*repo.ChartVersion, error) {
+	chartRepo, err := repo.NewChartRepository(&repo.Entry{Name: "index", URL: repository}, httpGetters)
+	if err != nil {
+		return nil, err
+	}
+	if chartRepo.CachePath, err = os.MkdirTemp(r.cacheDir, "index-"); err != nil {
+		return nil, fmt.Errorf("create index dir: %w", err)
+	}
+	defer os.RemoveAll(chartRepo.CachePath)
+	indexPath, err := chartRepo.DownloadIndexFile()
+	if err != nil {
+		return nil, fmt.Errorf("download index of %s: %w", repository, err)
+	}
+
+	index, err := repo.LoadIndexFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("load index of %s: %w", repository, err)
+	}
+	chartVersion, err := index.Get(name, version)
+	if err != nil {
+		return nil, fmt.Errorf("chart %q version %q not found in %s", name, version, repository)
+	}
+	return chartVersion, nil
+}
+
+func downloadChart(chartURL string) ([]byte, error) {
+	parsed, err := url.Parse(chartURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse chart url: %w", err)
+	}
+
+	chartGetter, err := httpGetters.ByScheme(parsed.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := chartGetter.Get(chartURL)
+	if err != nil {
+		return nil, fmt.Errorf("download chart %s: %w", chartURL, err)
+	}
+	return data.Bytes(), nil
+}
+
+func loadCachedArchive(cacheDir, key string, fetch func() ([]byte, error)) (*chart.Chart, error) {
+	archivePath := filepath.Join(cacheDir, unsafeCacheNameChars.ReplaceAllString(key, "_")+".tgz")
+	if _, err := os.Stat(archivePath); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("stat cached chart: %w", err)
+		}
+
+		logger.Info("fetching chart", "chart", key)
+		data, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+
+		tmpPath := archivePath + ".tmp"
+		if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+			return nil, fmt.Errorf("write cached chart: %w", err)
+		}
+		if err := os.Rename(tmpPath, archivePath); err != nil {
+			return nil, fmt.Errorf("store cached chart: %w", err)
+		}
+		logger.V(1).Info("cached chart", "chart", key, "path", archivePath)
+	} else {
+		logger.V(1).Info("using cached chart", "chart", key, "path", archivePath)
+	}
+
+	chartObj, err := loader.Load(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("load chart %s: %w", key, err)
+	}
+	return chartObj, nil
+}
+
+func ensureCacheDir(cacheDir string) (string, error) {
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "claim-controller-charts")
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", fmt.Errorf("create chart cache dir: %w", err)
+	}
+	return cacheDir, nil
+}
+
+func newRegistryClient(cacheDir string, dockerConfigJSON []byte) (*registry.Client, error) {
+	options := []registry.ClientOption{
+		registry.ClientOptWriter(logWriter{logger: logger.WithName("registry")}),
+	}
+	if len(dockerConfigJSON) > 0 {
+		credentialsFile := filepath.Join(cacheDir, "registry-config.json")
+		if err := os.WriteFile(credentialsFile, dockerConfigJSON, 0o600); err != nil {
+			return nil, fmt.Errorf("write registry credentials: %w", err)
+		}
+		options = append(options, registry.ClientOptCredentialsFile(credentialsFile))
+	}
+
+	client, err := registry.NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("create registry client: %w", err)
+	}
+	return client, nil
+}
+
+func pullOCIChart(client *registry.Client, ref string) ([]byte, error) {
+	result, err := client.Pull(ref, registry.PullOptWithChart(true))
+	if err != nil {
+		return nil, fmt.Errorf("pull chart %s: %w", ref, err)
+	}
+	if result.Chart == nil || len(result.Chart.Data) == 0 {
+		return nil, fmt.Errorf("pull chart %s: empty chart layer", ref)
+	}
+	return bytes.Clone(result.Chart.Data), nil
+}
+
+func copyChart(c *chart.Chart) *chart.Chart {
+	out := *c
+	if c.Metadata != nil {
+		metadata := *c.Metadata
+		metadata.Dependencies = make([]*chart.Dependency, 0, len(c.Metadata.Dependencies))
+		for _, dependency := range c.Metadata.Dependencies {
+			if dependency == nil {
+				continue
+			}
+			dependencyCopy := *dependency
+			metadata.Dependencies = append(metadata.Dependencies, &dependencyCopy)
+		}
+		out.Metadata = &metadata
+	}
+
+	dependencies := make([]*chart.Chart, 0, len(c.Dependencies()))
+	for _, dependency := range c.Dependencies() {
+		dependencies = append(dependencies, copyChart(dependency))
+	}
+	out.SetDependencies(dependencies...)
+
+	return &out
+}
@@ -0,0 +1,107 @@
+package template
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDirectoryChartSourceReloadsChangedChart(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Chart.yaml":            "apiVersion: v2\nname: demo\nversion: 0.1.0\n",
+		"templates/config.yaml": "kind: ConfigMap\n",
+	})
+
+	source, err := NewDirectoryChartSource(dir, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := source.Chart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := source.Chart(); err != nil || again != first {
+		t.Fatalf("unchanged chart reloaded: %v", err)
+	}
+
+	writeFiles(t, dir, map[string]string{"templates/config.yaml": "kind: Secret\n"})
+	second, err := source.Chart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashChart(first) == hashChart(second) {
+		t.Errorf("chart hash unchanged after editing a template")
+	}
+}
+
+func TestDependencyResolverResolvesHTTPRanges(t *testing.T) {
+	archives := t.TempDir()
+	var versions []string
+	saveChart := func(version string) {
+		t.Helper()
+		chartObj := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "dep", Version: version}}
+		if _, err := chartutil.Save(chartObj, archives); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, version)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.ServeFile(w, r, filepath.Join(archives, filepath.Base(r.URL.Path)))
+			return
+		}
+		index := repo.NewIndexFile()
+		for _, version := range versions {
+			if err := index.MustAdd(&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "dep", Version: version}, "dep-"+version+".tgz", "", "sha256:0"); err != nil {
+				t.Error(err)
+			}
+		}
+		index.SortEntries()
+		data, _ := yaml.Marshal(index)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	resolver := dependencyResolver{cacheDir: t.TempDir()}
+	dependency := &chart.Dependency{Name: "dep", Version: "~1.2.0", Repository: server.URL}
+
+	saveChart("1.2.0")
+	saveChart("1.2.3")
+	saveChart("1.3.0")
+	got, err := resolver.fetch(dependency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata.Version != "1.2.3" {
+		t.Errorf("resolved %s, want 1.2.3", got.Metadata.Version)
+	}
+
+	saveChart("1.2.4")
+	if got, err = resolver.fetch(dependency); err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata.Version != "1.2.4" {
+		t.Errorf("resolved %s after a new release, want 1.2.4", got.Metadata.Version)
+	}
+}
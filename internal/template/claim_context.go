@@ -0,0 +1,23 @@
+package template
+
+type ClaimContext struct {
+	ID        string
+	Name      string
+	Namespace string
+	TTL       string
+	ExpiresAt string
+	Requester string
+	Profile   string
+}
+
+func (c ClaimContext) renderValues() map[string]any {
+	return map[string]any{
+		"ID":        c.ID,
+		"Name":      c.Name,
+		"Namespace": c.Namespace,
+		"TTL":       c.TTL,
+		"ExpiresAt": c.ExpiresAt,
+		"Requester": c.Requester,
+		"Profile":   c.Profile,
+	}
+}
@@ -0,0 +1,82 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+type KustomizeRenderer struct {
+	dir string
+}
+
+func NewKustomizeRenderer(dir string) (*KustomizeRenderer, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve kustomization dir: %w", err)
+	}
+
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return nil, fmt.Errorf("stat kustomization dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("kustomization path %s must be a directory", absDir)
+	}
+
+	return &KustomizeRenderer{dir: absDir}, nil
+}
+
+func (r *KustomizeRenderer) Render(_ []byte, claim ClaimContext) (_ ResourceTemplate, err error) {
+	defer observeRender(RendererKustomize, time.Now(), &err)
+
+	overlayDir, err := os.MkdirTemp("", "claim-"+claim.ID+"-")
+	if err != nil {
+		return ResourceTemplate{}, fmt.Errorf("create kustomize overlay: %w", err)
+	}
+	defer os.RemoveAll(overlayDir)
+
+	// Kustomize refuses absolute resource paths.
+	base, err := filepath.Rel(overlayDir, r.dir)
+	if err != nil {
+		return ResourceTemplate{}, fmt.Errorf("resolve kustomization dir: %w", err)
+	}
+	overlay, err := yaml.Marshal(map[string]any{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"namespace":  claim.Namespace,
+		"nameSuffix": "-" + claim.ID,
+		"resources":  []string{filepath.ToSlash(base)},
+	})
+	if err != nil {
+		return ResourceTemplate{}, fmt.Errorf("marshal kustomize overlay: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(overlayDir, "kustomization.yaml"), overlay, 0o644); err != nil {
+		return ResourceTemplate{}, fmt.Errorf("write kustomize overlay: %w", err)
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), overlayDir)
+	if err != nil {
+		return ResourceTemplate{}, fmt.Errorf("run kustomize: %w", err)
+	}
+
+	rendered, err := resMap.AsYaml()
+	if err != nil {
+		return ResourceTemplate{}, fmt.Errorf("encode kustomize output: %w", err)
+	}
+
+	return decodeRenderedManifests(string(rendered))
+}
+
+func (r *KustomizeRenderer) Version() (string, error) {
+	return hashDirectory(r.dir)
+}
+
+func (r *KustomizeRenderer) Description() string {
+	return "kustomize:" + r.dir
+}
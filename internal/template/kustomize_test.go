@@ -0,0 +1,23 @@
+package template
+
+import "testing"
+
+func TestKustomizeRenderer(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"kustomization.yaml": "resources:\n  - config.yaml\n",
+		"config.yaml":        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n",
+	})
+	renderer, err := NewKustomizeRenderer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rendered, err := renderer.Render(nil, ClaimContext{ID: "abc", Name: "claim-abc", Namespace: "claims"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rendered.Resources) != 1 || rendered.Resources[0].Name != "settings-abc" || rendered.Resources[0].Namespace != "claims" {
+		t.Errorf("resources = %+v, want claims/settings-abc", rendered.Resources)
+	}
+}
@@ -1,12 +1,13 @@
 package template
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"helm.sh/helm/v3/pkg/chart"
@@ -41,27 +42,64 @@ func LoadResourceTemplate(namespace, templatePath, valuesPath, id string) (Resou
 		return ResourceTemplate{}, fmt.Errorf("read values file: %w", err)
 	}
 
-	return loadResourceTemplateFromData(namespace, templatePath, templateData, valuesData, id)
+	return loadResourceTemplateFromData(templatePath, templateData, valuesData, ClaimContext{ID: id, Name: "claim-" + id, Namespace: namespace})
 }
 
-func LoadResourceTemplateFromValuesData(namespace, templatePath string, valuesData []byte, id string) (ResourceTemplate, error) {
-	templateData, err := os.ReadFile(templatePath)
+func LoadResourceTemplateFromValuesData(templatePath string, valuesData []byte, claim ClaimContext) (ResourceTemplate, error) {
+	file, err := templateFiles.load(templatePath)
 	if err != nil {
-		return ResourceTemplate{}, fmt.Errorf("read template file: %w", err)
+		return ResourceTemplate{}, classifyRenderError(renderErrorRead, err)
 	}
 
-	return loadResourceTemplateFromData(namespace, templatePath, templateData, valuesData, id)
+	return loadResourceTemplateFromData(templatePath, file.data, valuesData, claim)
 }
 
-func loadResourceTemplateFromData(namespace, templatePath string, templateData, valuesData []byte, id string) (ResourceTemplate, error) {
-	values, err := chartutil.ReadValues(valuesData)
+func loadResourceTemplateFromData(templatePath string, templateData, valuesData []byte, claim ClaimContext) (ResourceTemplate, error) {
+	values, err := readValues(valuesData)
 	if err != nil {
-		return ResourceTemplate{}, fmt.Errorf("decode values file: %w", err)
+		return ResourceTemplate{}, classifyRenderError(renderErrorValues, err)
 	}
 
-	chartName := "claim-" + id
+	chartName := "claim-" + claim.ID
 	templateName := filepath.Base(templatePath)
-	chartObj := &chart.Chart{
+	chartObj := templateFileChart(chartName, templateName, templateData)
+
+	renderedMap, err := renderChart(chartObj, chartName, values, claim)
+	if err != nil {
+		return ResourceTemplate{}, err
+	}
+
+	renderedText, err := pickRenderedTemplate(renderedMap, templateName)
+	if err != nil {
+		return ResourceTemplate{}, err
+	}
+
+	return decodeRenderedManifests(renderedText)
+}
+
+const (
+	claimReleaseKey = "Claim"
+	// The prelude renders nothing and adds no line, so template error
+	// positions are unchanged.
+	claimPrelude = `{{- $_ := set . "Claim" .Release.` + claimReleaseKey + ` }}`
+)
+
+func withClaimPrelude(chartObj *chart.Chart) {
+	templates := make([]*chart.File, 0, len(chartObj.Templates))
+	for _, file := range chartObj.Templates {
+		if !strings.HasPrefix(path.Base(file.Name), "_") {
+			file = &chart.File{Name: file.Name, Data: append([]byte(claimPrelude), file.Data...)}
+		}
+		templates = append(templates, file)
+	}
+	chartObj.Templates = templates
+	for _, dependency := range chartObj.Dependencies() {
+		withClaimPrelude(dependency)
+	}
+}
+
+func templateFileChart(chartName, templateName string, templateData []byte) *chart.Chart {
+	return &chart.Chart{
 		Metadata: &chart.Metadata{
 			APIVersion: "v2",
 			Name:       chartName,
@@ -71,38 +109,65 @@ func loadResourceTemplateFromData(namespace, templatePath string, templateData,
 			{Name: filepath.ToSlash(filepath.Join("templates", templateName)), Data: templateData},
 		},
 	}
+}
+
+func LoadResourceTemplateFromChart(chartObj *chart.Chart, valuesData []byte, claim ClaimContext) (ResourceTemplate, error) {
+	if chartObj == nil {
+		return ResourceTemplate{}, fmt.Errorf("chart is required")
+	}
+
+	values, err := readValues(valuesData)
+	if err != nil {
+		return ResourceTemplate{}, classifyRenderError(renderErrorValues, err)
+	}
 
+	chartObj = copyChart(chartObj)
+	if err := chartutil.ProcessDependenciesWithMerge(chartObj, values); err != nil {
+		return ResourceTemplate{}, fmt.Errorf("process chart dependencies: %w", err)
+	}
+
+	renderedMap, err := renderChart(chartObj, "claim-"+claim.ID, values, claim)
+	if err != nil {
+		return ResourceTemplate{}, err
+	}
+
+	return decodeRenderedManifests(joinRenderedManifests(renderedMap))
+}
+
+func renderChart(chartObj *chart.Chart, releaseName string, values chartutil.Values, claim ClaimContext) (map[string]string, error) {
 	renderValues, err := chartutil.ToRenderValues(
 		chartObj,
 		values,
 		chartutil.ReleaseOptions{
-			Name:      chartName,
-			Namespace: namespace,
+			Name:      releaseName,
+			Namespace: claim.Namespace,
 			Revision:  1,
 			IsInstall: true,
 		},
 		chartutil.DefaultCapabilities,
 	)
 	if err != nil {
-		return ResourceTemplate{}, fmt.Errorf("build helm render values: %w", err)
+		return nil, fmt.Errorf("build helm render values: %w", err)
 	}
+	// The engine only hands Chart, Files, Release, Capabilities, Values and
+	// Subcharts to templates, so .Claim travels in .Release and each template
+	// copies it back to the top level.
+	renderValues["Release"].(map[string]any)[claimReleaseKey] = claim.renderValues()
+	chartObj = copyChart(chartObj)
+	withClaimPrelude(chartObj)
 
 	renderedMap, err := engine.Render(chartObj, renderValues)
 	if err != nil {
-		return ResourceTemplate{}, fmt.Errorf("render helm template: %w", err)
-	}
-
-	renderedText, err := pickRenderedTemplate(renderedMap, templateName)
-	if err != nil {
-		return ResourceTemplate{}, err
+		return nil, fmt.Errorf("render helm template: %w", err)
 	}
 
-	var rendered bytes.Buffer
-	rendered.WriteString(renderedText)
+	return renderedMap, nil
+}
 
+func DecodeManifests(renderedText string) (ResourceTemplate, error) {
 	var result ResourceTemplate
 	result.ReturnValues = map[string]string{}
-	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(rendered.Bytes()), 4096)
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(renderedText), 4096)
 	for {
 		var raw map[string]any
 		if err := decoder.Decode(&raw); err != nil {
@@ -135,6 +200,34 @@ func loadResourceTemplateFromData(namespace, templatePath string, templateData,
 	return result, nil
 }
 
+func decodeRenderedManifests(renderedText string) (ResourceTemplate, error) {
+	result, err := DecodeManifests(renderedText)
+	return result, classifyRenderError(renderErrorDecode, err)
+}
+
+func joinRenderedManifests(rendered map[string]string) string {
+	keys := make([]string, 0, len(rendered))
+	for key := range rendered {
+		switch strings.ToLower(path.Ext(key)) {
+		case ".yaml", ".yml", ".json":
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var out strings.Builder
+	for _, key := range keys {
+		content := strings.TrimSpace(rendered[key])
+		if content == "" {
+			continue
+		}
+		out.WriteString("---\n")
+		out.WriteString(content)
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
 func renderedResourceFromRaw(raw map[string]any) RenderedResource {
 	resource := RenderedResource{
 		Labels:      map[string]string{},
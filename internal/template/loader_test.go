@@ -0,0 +1,59 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestLoadResourceTemplateFromChartExposesClaim(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "sub", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/config.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Claim.Name }}-sub\n")},
+		},
+	}
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{- define "app.owner" -}}{{ .Claim.Requester }}{{- end }}`)},
+			{Name: "templates/config.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Claim.Name }}\n  annotations:\n    owner: {{ include \"app.owner\" . }}\n    profile: {{ .Claim.Profile }}\n")},
+		},
+	}
+	parent.AddDependency(subchart)
+
+	claim := ClaimContext{ID: "abc", Name: "claim-abc", Namespace: "claims", Requester: "oidc.alice", Profile: "small"}
+	rendered, err := LoadResourceTemplateFromChart(parent, nil, claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]RenderedResource{}
+	for _, resource := range rendered.Resources {
+		names[resource.Name] = resource
+	}
+	if resource, ok := names["claim-abc"]; !ok || resource.Annotations["owner"] != "oidc.alice" || resource.Annotations["profile"] != "small" {
+		t.Errorf("parent resource = %+v", resource)
+	}
+	if _, ok := names["claim-abc-sub"]; !ok {
+		t.Errorf("subchart resource missing from %v", rendered.Resources)
+	}
+	if strings.Contains(string(parent.Templates[1].Data), "Release.Claim") {
+		t.Errorf("source chart templates were modified")
+	}
+}
+
+func TestClaimPreludeKeepsErrorLines(t *testing.T) {
+	_, err := LoadResourceTemplateFromValuesData(writeTemplate(t, "kind: ConfigMap\n\n{{ .Values.missing.field }}\n"), nil, ClaimContext{ID: "abc"})
+	if err == nil || !strings.Contains(err.Error(), ":3:") {
+		t.Errorf("error = %v, want a position on line 3", err)
+	}
+}
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"template.yaml": content})
+	return dir + "/template.yaml"
+}
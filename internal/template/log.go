@@ -0,0 +1,28 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+var logger = logr.Discard()
+
+// SetLogger must be called before any chart source or renderer is used.
+func SetLogger(l logr.Logger) {
+	logger = l
+}
+
+// logWriter forwards the line-oriented output of helm clients to the logger.
+type logWriter struct {
+	logger logr.Logger
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.logger.V(1).Info(line)
+		}
+	}
+	return len(p), nil
+}
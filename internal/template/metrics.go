@@ -0,0 +1,63 @@
+package template
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	renderErrorRead   = "read"
+	renderErrorValues = "values"
+	renderErrorRender = "render"
+	renderErrorDecode = "decode"
+)
+
+var renderDurationSeconds = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "claim_controller_render_duration_seconds",
+	Help:    "Duration of claim template renders, by renderer.",
+	Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+}, []string{"renderer"})
+
+var renderFailuresTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "claim_controller_render_failures_total",
+	Help: "Total number of failed claim template renders, by renderer and error class (read, values, render or decode).",
+}, []string{"renderer", "class"})
+
+// renderError tags a render failure with the stage it happened in; untagged
+// errors are counted as render errors.
+type renderError struct {
+	class string
+	err   error
+}
+
+func (e *renderError) Error() string {
+	return e.err.Error()
+}
+
+func (e *renderError) Unwrap() error {
+	return e.err
+}
+
+func classifyRenderError(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &renderError{class: class, err: err}
+}
+
+func observeRender(renderer string, start time.Time, err *error) {
+	renderDurationSeconds.WithLabelValues(renderer).Observe(time.Since(start).Seconds())
+	if *err == nil {
+		return
+	}
+	class := renderErrorRender
+	var classified *renderError
+	if errors.As(*err, &classified) {
+		class = classified.class
+	}
+	renderFailuresTotal.WithLabelValues(renderer, class).Inc()
+}
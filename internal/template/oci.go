@@ -0,0 +1,113 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// ociTagRecheckInterval is how often a tag is resolved again, so a chart
+// republished under the same tag is picked up.
+const ociTagRecheckInterval = 5 * time.Minute
+
+var unsafeCacheNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// OCIChartSource caches pulled charts by manifest digest, so a moved tag
+// never serves the archive of its previous target.
+type OCIChartSource struct {
+	ref      string
+	resolver dependencyResolver
+
+	mu        sync.Mutex
+	chart     *chart.Chart
+	digest    string
+	checkedAt time.Time
+}
+
+func IsOCIReference(ref string) bool {
+	return registry.IsOCI(ref)
+}
+
+func NewOCIChartSource(ref, cacheDir string, dockerConfigJSON []byte) (*OCIChartSource, error) {
+	if !registry.IsOCI(ref) {
+		return nil, fmt.Errorf("template ref %q must use the %s:// scheme", ref, registry.OCIScheme)
+	}
+
+	cacheDir, err := ensureCacheDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	registryClient, err := newRegistryClient(cacheDir, dockerConfigJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OCIChartSource{
+		ref: ref,
+		resolver: dependencyResolver{
+			cacheDir:       cacheDir,
+			registryClient: registryClient,
+		},
+	}, nil
+}
+
+func (s *OCIChartSource) Chart() (*chart.Chart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chart != nil && (strings.Contains(s.ref, "@") || time.Since(s.checkedAt) < ociTagRecheckInterval) {
+		return s.chart, nil
+	}
+
+	desc, err := s.resolver.registryClient.Resolve(strings.TrimPrefix(s.ref, registry.OCIScheme+"://"))
+	if err != nil {
+		if s.chart != nil {
+			logger.Error(err, "failed to resolve chart tag, keeping the cached chart", "chart", s.ref)
+			s.checkedAt = time.Now()
+			return s.chart, nil
+		}
+		return nil, fmt.Errorf("resolve chart %s: %w", s.ref, err)
+	}
+	s.checkedAt = time.Now()
+	digest := desc.Digest.String()
+	if s.chart != nil && digest == s.digest {
+		return s.chart, nil
+	}
+
+	pinned := s.ref
+	if !strings.Contains(pinned, "@") {
+		pinned += "@" + digest
+	}
+	key := strings.TrimPrefix(pinned, registry.OCIScheme+"://")
+	chartObj, err := loadCachedArchive(s.resolver.cacheDir, key, func() ([]byte, error) {
+		return pullOCIChart(s.resolver.registryClient, pinned)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolver.resolve(chartObj); err != nil {
+		return nil, err
+	}
+
+	if s.digest != "" {
+		logger.Info("chart tag moved", "chart", s.ref, "from", s.digest, "to", digest)
+	}
+	s.chart = chartObj
+	s.digest = digest
+	return s.chart, nil
+}
+
+func (s *OCIChartSource) Description() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.digest == "" || strings.Contains(s.ref, "@") {
+		return s.ref
+	}
+	return s.ref + "@" + s.digest
+}
@@ -0,0 +1,107 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var rawVariablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z0-9_]+)\}`)
+
+type RawRenderer struct {
+	dir string
+}
+
+func NewRawRenderer(dir string) (*RawRenderer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("stat manifest dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("manifest path %s must be a directory", dir)
+	}
+
+	return &RawRenderer{dir: dir}, nil
+}
+
+func (r *RawRenderer) Render(_ []byte, claim ClaimContext) (_ ResourceTemplate, err error) {
+	defer observeRender(RendererRaw, time.Now(), &err)
+
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return ResourceTemplate{}, classifyRenderError(renderErrorRead, fmt.Errorf("read manifest dir: %w", err))
+	}
+
+	fileNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			fileNames = append(fileNames, entry.Name())
+		}
+	}
+	sort.Strings(fileNames)
+	if len(fileNames) == 0 {
+		return ResourceTemplate{}, classifyRenderError(renderErrorRead, fmt.Errorf("manifest dir %s contains no YAML or JSON files", r.dir))
+	}
+
+	variables := map[string]string{
+		"CLAIM_ID":   claim.ID,
+		"CLAIM_NAME": claim.Name,
+		"NAMESPACE":  claim.Namespace,
+	}
+
+	var rendered strings.Builder
+	for _, fileName := range fileNames {
+		data, err := os.ReadFile(filepath.Join(r.dir, fileName))
+		if err != nil {
+			return ResourceTemplate{}, classifyRenderError(renderErrorRead, fmt.Errorf("read manifest %s: %w", fileName, err))
+		}
+
+		content := substituteRawVariables(string(data), variables)
+
+		rendered.WriteString("---\n")
+		rendered.WriteString(content)
+		rendered.WriteString("\n")
+	}
+
+	result, err := decodeRenderedManifests(rendered.String())
+	if err != nil {
+		return ResourceTemplate{}, err
+	}
+	for _, resource := range result.Resources {
+		if resource.APIVersion == "" || resource.Name == "" {
+			return ResourceTemplate{}, classifyRenderError(renderErrorDecode, fmt.Errorf("manifest %s %q must define apiVersion and metadata.name", resource.Kind, resource.Name))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *RawRenderer) Version() (string, error) {
+	return hashDirectory(r.dir)
+}
+
+func (r *RawRenderer) Description() string {
+	return "raw:" + r.dir
+}
+
+// substituteRawVariables replaces known ${NAME} variables. Unknown ones are
+// kept as is, and $${NAME} escapes a known one.
+func substituteRawVariables(content string, variables map[string]string) string {
+	return rawVariablePattern.ReplaceAllStringFunc(content, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		if value, ok := variables[rawVariablePattern.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
@@ -0,0 +1,51 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubstituteRawVariables(t *testing.T) {
+	variables := map[string]string{"CLAIM_ID": "abc", "NAMESPACE": "claims"}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "known variables", content: "name: app-${CLAIM_ID}\nnamespace: ${NAMESPACE}\n", want: "name: app-abc\nnamespace: claims\n"},
+		{name: "unknown variables are kept", content: `command: ["sh", "-c", "echo ${HOME} ${PATH}"]`, want: `command: ["sh", "-c", "echo ${HOME} ${PATH}"]`},
+		{name: "escaped known variable", content: "script: echo $${NAMESPACE} in ${NAMESPACE}", want: "script: echo ${NAMESPACE} in claims"},
+		{name: "escaped unknown variable", content: "value: $${HOME}", want: "value: ${HOME}"},
+		{name: "shell defaults are untouched", content: "value: ${PORT:-8080}", want: "value: ${PORT:-8080}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := substituteRawVariables(tt.content, variables); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawRendererKeepsShellVariables(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"job.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: script-${CLAIM_ID}\ndata:\n  run.sh: echo ${HOME}\n",
+	})
+	renderer, err := NewRawRenderer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rendered, err := renderer.Render(nil, ClaimContext{ID: "abc", Name: "claim-abc", Namespace: "claims"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rendered.RenderedObjects) != 1 || !strings.Contains(string(rendered.RenderedObjects[0]), "echo ${HOME}") {
+		t.Errorf("rendered objects = %s", rendered.RenderedObjects)
+	}
+	if rendered.Resources[0].Name != "script-abc" {
+		t.Errorf("resource name = %q, want script-abc", rendered.Resources[0].Name)
+	}
+}
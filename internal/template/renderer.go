@@ -0,0 +1,116 @@
+package template
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const (
+	RendererHelm      = "helm"
+	RendererKustomize = "kustomize"
+	RendererRaw       = "raw"
+)
+
+type Renderer interface {
+	Render(valuesData []byte, claim ClaimContext) (ResourceTemplate, error)
+	Description() string
+	Version() (string, error)
+}
+
+// UsesValues reports whether r renders the values it is given; kustomize and
+// raw manifests ignore them.
+func UsesValues(r Renderer) bool {
+	switch r.(type) {
+	case *KustomizeRenderer, *RawRenderer:
+		return false
+	}
+	return true
+}
+
+type HelmRenderer struct {
+	templatePath string
+	chartSource  ChartSource
+}
+
+func NewHelmRenderer(templatePath string, chartSource ChartSource) *HelmRenderer {
+	return &HelmRenderer{templatePath: templatePath, chartSource: chartSource}
+}
+
+func (r *HelmRenderer) Render(valuesData []byte, claim ClaimContext) (_ ResourceTemplate, err error) {
+	defer observeRender(RendererHelm, time.Now(), &err)
+
+	if r.chartSource != nil {
+		chartObj, err := r.chartSource.Chart()
+		if err != nil {
+			return ResourceTemplate{}, classifyRenderError(renderErrorRead, err)
+		}
+		return LoadResourceTemplateFromChart(chartObj, valuesData, claim)
+	}
+
+	return LoadResourceTemplateFromValuesData(r.templatePath, valuesData, claim)
+}
+
+func (r *HelmRenderer) ReleaseChart(id string) (*chart.Chart, error) {
+	if r.chartSource != nil {
+		chartObj, err := r.chartSource.Chart()
+		if err != nil {
+			return nil, err
+		}
+		return copyChart(chartObj), nil
+	}
+
+	file, err := templateFiles.load(r.templatePath)
+	if err != nil {
+		return nil, err
+	}
+	return templateFileChart("claim-"+id, filepath.Base(r.templatePath), file.data), nil
+}
+
+func (r *HelmRenderer) Schema() ([]byte, error) {
+	if r.chartSource == nil {
+		return nil, nil
+	}
+
+	chartObj, err := r.chartSource.Chart()
+	if err != nil {
+		return nil, err
+	}
+	return chartObj.Schema, nil
+}
+
+func (r *HelmRenderer) Version() (string, error) {
+	if r.chartSource != nil {
+		chartObj, err := r.chartSource.Chart()
+		if err != nil {
+			return "", err
+		}
+		return hashChart(chartObj), nil
+	}
+	file, err := templateFiles.load(r.templatePath)
+	if err != nil {
+		return "", err
+	}
+	return file.hash, nil
+}
+
+func (r *HelmRenderer) Description() string {
+	if r.chartSource != nil {
+		return "helm:" + r.chartSource.Description()
+	}
+	return "helm:" + r.templatePath
+}
+
+func ParseRendererKind(kind string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(kind)); normalized {
+	case "", RendererHelm:
+		return RendererHelm, nil
+	case RendererKustomize, RendererRaw:
+		return normalized, nil
+	default:
+		return "", fmt.Errorf("unknown template renderer %q", kind)
+	}
+}
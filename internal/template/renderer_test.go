@@ -0,0 +1,70 @@
+package template
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderers(t *testing.T) {
+	claim := ClaimContext{ID: "abc", Name: "claim-abc", Namespace: "claims"}
+
+	helmDir := t.TempDir()
+	writeFiles(t, helmDir, map[string]string{
+		"template.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Claim.Name }}-{{ .Values.suffix }}\n  namespace: {{ .Claim.Namespace }}\n",
+	})
+	kustomizeDir := t.TempDir()
+	writeFiles(t, kustomizeDir, map[string]string{
+		"kustomization.yaml": "resources:\n  - config.yaml\n",
+		"config.yaml":        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n",
+	})
+	rawDir := t.TempDir()
+	writeFiles(t, rawDir, map[string]string{
+		"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings-${CLAIM_ID}\n  namespace: ${NAMESPACE}\n",
+		"notes.txt":   "ignored",
+	})
+
+	kustomize, err := NewKustomizeRenderer(kustomizeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := NewRawRenderer(rawDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		renderer      Renderer
+		values        string
+		wantName      string
+		wantNamespace string
+		wantValues    bool
+	}{
+		{name: "helm", renderer: NewHelmRenderer(filepath.Join(helmDir, "template.yaml"), nil), values: "suffix: data\n", wantName: "claim-abc-data", wantNamespace: "claims", wantValues: true},
+		{name: "kustomize", renderer: kustomize, wantName: "settings-abc", wantNamespace: "claims"},
+		{name: "raw", renderer: raw, wantName: "settings-abc", wantNamespace: "claims"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := tt.renderer.Render([]byte(tt.values), claim)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rendered.Resources) != 1 {
+				t.Fatalf("rendered %d resources, want 1", len(rendered.Resources))
+			}
+			resource := rendered.Resources[0]
+			if resource.Kind != "ConfigMap" || resource.Name != tt.wantName || resource.Namespace != tt.wantNamespace {
+				t.Errorf("resource = %+v, want ConfigMap %s/%s", resource, tt.wantNamespace, tt.wantName)
+			}
+			if got := UsesValues(tt.renderer); got != tt.wantValues {
+				t.Errorf("UsesValues() = %v, want %v", got, tt.wantValues)
+			}
+
+			version, err := tt.renderer.Version()
+			if err != nil || version == "" {
+				t.Errorf("Version() = %q, %v", version, err)
+			}
+		})
+	}
+}
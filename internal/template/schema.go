@@ -0,0 +1,95 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"sigs.k8s.io/yaml"
+)
+
+const valuesSchemaURL = "values.schema.json"
+
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type SchemaValidationError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", violation.Path, violation.Message))
+	}
+	return "values do not match schema: " + strings.Join(messages, "; ")
+}
+
+type SchemaValidator struct {
+	schema *jsonschema.Schema
+}
+
+func NewSchemaValidator(schemaJSON []byte) (*SchemaValidator, error) {
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, fmt.Errorf("decode values schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(valuesSchemaURL, document); err != nil {
+		return nil, fmt.Errorf("load values schema: %w", err)
+	}
+	schema, err := compiler.Compile(valuesSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("compile values schema: %w", err)
+	}
+
+	return &SchemaValidator{schema: schema}, nil
+}
+
+func (v *SchemaValidator) Validate(valuesData []byte) error {
+	valuesJSON, err := yaml.YAMLToJSON(valuesData)
+	if err != nil {
+		return fmt.Errorf("decode values: %w", err)
+	}
+	if len(bytes.TrimSpace(valuesJSON)) == 0 || bytes.Equal(bytes.TrimSpace(valuesJSON), []byte("null")) {
+		valuesJSON = []byte("{}")
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(valuesJSON))
+	if err != nil {
+		return fmt.Errorf("decode values: %w", err)
+	}
+
+	err = v.schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	output := validationErr.BasicOutput()
+	violations := make([]SchemaViolation, 0, len(output.Errors))
+	for _, unit := range output.Errors {
+		if unit.Error == nil {
+			continue
+		}
+		path := unit.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		violations = append(violations, SchemaViolation{Path: path, Message: unit.Error.String()})
+	}
+	if len(violations) == 0 {
+		violations = append(violations, SchemaViolation{Path: "/", Message: validationErr.Error()})
+	}
+
+	return &SchemaValidationError{Violations: violations}
+}
@@ -0,0 +1,85 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const versionHashLength = 12
+
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:versionHashLength]
+}
+
+func hashDirectory(dir string) (string, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("hash template dir %s: %w", dir, err)
+	}
+	return hashNamedFiles(files), nil
+}
+
+func hashChart(chartObj *chart.Chart) string {
+	files := map[string][]byte{}
+	collectChartFiles(chartObj, "", files)
+	return hashNamedFiles(files)
+}
+
+func collectChartFiles(chartObj *chart.Chart, prefix string, files map[string][]byte) {
+	if chartObj.Metadata != nil {
+		files[prefix+"Chart.yaml"] = []byte(chartObj.Metadata.Name + "@" + chartObj.Metadata.Version)
+	}
+	for _, file := range chartObj.Raw {
+		files[prefix+file.Name] = file.Data
+	}
+	for _, file := range chartObj.Templates {
+		files[prefix+file.Name] = file.Data
+	}
+	for _, file := range chartObj.Files {
+		files[prefix+file.Name] = file.Data
+	}
+	if chartObj.Schema != nil {
+		files[prefix+"values.schema.json"] = chartObj.Schema
+	}
+	for _, dependency := range chartObj.Dependencies() {
+		collectChartFiles(dependency, prefix+"charts/"+dependency.Name()+"/", files)
+	}
+}
+
+func hashNamedFiles(files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(files[name]))
+		hash.Write(files[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:versionHashLength]
+}
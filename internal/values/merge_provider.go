@@ -0,0 +1,91 @@
+package values
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+type MergeProvider struct {
+	providers []Provider
+}
+
+func NewMergeProvider(providers ...Provider) (*MergeProvider, error) {
+	layers := make([]Provider, 0, len(providers))
+	for _, provider := range providers {
+		if provider != nil {
+			layers = append(layers, provider)
+		}
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("at least one values provider is required")
+	}
+
+	return &MergeProvider{providers: layers}, nil
+}
+
+func (p *MergeProvider) Start(ctx context.Context) error {
+	for _, provider := range p.providers {
+		if err := provider.Start(ctx); err != nil {
+			return fmt.Errorf("start values provider %s: %w", provider.Description(), err)
+		}
+	}
+	return nil
+}
+
+func (p *MergeProvider) GetValues() ([]byte, error) {
+	merged := map[string]any{}
+	for _, provider := range p.providers {
+		data, err := provider.GetValues()
+		if err != nil {
+			return nil, fmt.Errorf("get values from %s: %w", provider.Description(), err)
+		}
+
+		layer := map[string]any{}
+		if err := yaml.Unmarshal(data, &layer); err != nil {
+			return nil, fmt.Errorf("decode values from %s: %w", provider.Description(), err)
+		}
+		merged = MergeValues(merged, layer)
+	}
+
+	return yaml.Marshal(merged)
+}
+
+func (p *MergeProvider) GetOwnerReference() *metav1.OwnerReference {
+	for i := len(p.providers) - 1; i >= 0; i-- {
+		if ref := p.providers[i].GetOwnerReference(); ref != nil {
+			return ref
+		}
+	}
+	return nil
+}
+
+func (p *MergeProvider) Description() string {
+	layers := make([]string, 0, len(p.providers))
+	for _, provider := range p.providers {
+		layers = append(layers, provider.Description())
+	}
+	return "merge[" + strings.Join(layers, ", ") + "]"
+}
+
+func MergeValues(base, overlay map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(overlay))
+	for key, value := range base {
+		out[key] = value
+	}
+
+	for key, value := range overlay {
+		overlayMap, overlayIsMap := value.(map[string]any)
+		baseMap, baseIsMap := out[key].(map[string]any)
+		if overlayIsMap && baseIsMap {
+			out[key] = MergeValues(baseMap, overlayMap)
+			continue
+		}
+		out[key] = value
+	}
+
+	return out
+}
@@ -0,0 +1,76 @@
+package values
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type staticProvider struct {
+	name string
+	data string
+	err  error
+}
+
+func (p staticProvider) Start(context.Context) error               { return nil }
+func (p staticProvider) GetValues() ([]byte, error)                { return []byte(p.data), p.err }
+func (p staticProvider) GetOwnerReference() *metav1.OwnerReference { return nil }
+func (p staticProvider) Description() string                       { return p.name }
+
+func TestMergeProviderGetValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		layers  []Provider
+		want    string
+		wantErr string
+	}{
+		{
+			name: "overlay wins and maps merge",
+			layers: []Provider{
+				staticProvider{name: "base", data: "image:\n  tag: v1\n  repo: app\n"},
+				staticProvider{name: "overlay", data: "image:\n  tag: v2\n"},
+			},
+			want: "image:\n  repo: app\n  tag: v2\n",
+		},
+		{
+			name: "a failing overlay fails the merge",
+			layers: []Provider{
+				staticProvider{name: "base", data: "a: 1\n"},
+				staticProvider{name: "overlay", err: errors.New("configmap not found")},
+			},
+			wantErr: "overlay: configmap not found",
+		},
+		{
+			name: "invalid yaml fails the merge",
+			layers: []Provider{
+				staticProvider{name: "base", data: "a: [\n"},
+			},
+			wantErr: "decode values from base",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewMergeProvider(tt.layers...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := provider.GetValues()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetValues() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GetValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
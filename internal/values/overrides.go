@@ -0,0 +1,91 @@
+package values
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+type Allowlist struct {
+	paths []string
+}
+
+func NewAllowlist(paths []string) Allowlist {
+	cleaned := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = strings.Trim(strings.TrimSpace(path), ".")
+		if path != "" {
+			cleaned = append(cleaned, path)
+		}
+	}
+	return Allowlist{paths: cleaned}
+}
+
+func (a Allowlist) Empty() bool {
+	return len(a.paths) == 0
+}
+
+func (a Allowlist) Paths() []string {
+	return append([]string(nil), a.paths...)
+}
+
+func (a Allowlist) Disallowed(overrides map[string]any) []string {
+	var out []string
+	a.collectDisallowed("", overrides, &out)
+	sort.Strings(out)
+	return out
+}
+
+func (a Allowlist) collectDisallowed(prefix string, node map[string]any, out *[]string) {
+	for key, value := range node {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if a.allows(path) {
+			continue
+		}
+
+		child, isMap := value.(map[string]any)
+		if isMap && a.hasAllowedDescendant(path) {
+			a.collectDisallowed(path, child, out)
+			continue
+		}
+
+		*out = append(*out, path)
+	}
+}
+
+func (a Allowlist) allows(path string) bool {
+	for _, allowed := range a.paths {
+		if path == allowed || strings.HasPrefix(path, allowed+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Allowlist) hasAllowedDescendant(path string) bool {
+	for _, allowed := range a.paths {
+		if strings.HasPrefix(allowed, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func ApplyOverrides(valuesData []byte, overrides map[string]any) ([]byte, error) {
+	if len(overrides) == 0 {
+		return valuesData, nil
+	}
+
+	base := map[string]any{}
+	if err := yaml.Unmarshal(valuesData, &base); err != nil {
+		return nil, fmt.Errorf("decode values: %w", err)
+	}
+
+	return yaml.Marshal(MergeValues(base, overrides))
+}
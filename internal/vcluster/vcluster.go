@@ -0,0 +1,101 @@
+package vcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	ProfileType   = "vcluster"
+	KubeconfigKey = "config"
+
+	userNamespace      = "kube-system"
+	userServiceAccount = "claim-controller-user"
+	minTokenTTL        = 10 * time.Minute
+)
+
+func SecretName(name string) string {
+	return "vc-" + name
+}
+
+func ServerURL(name, namespace string) string {
+	return fmt.Sprintf("https://%s.%s.svc:443", name, namespace)
+}
+
+func RESTConfig(secret *corev1.Secret, name, namespace string) (*rest.Config, error) {
+	raw := secret.Data[KubeconfigKey]
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("secret %s has no %s key", secret.Name, KubeconfigKey)
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse vcluster kubeconfig: %w", err)
+	}
+	cfg.Host = ServerURL(name, namespace)
+	cfg.Timeout = 5 * time.Second
+	return cfg, nil
+}
+
+func CheckReady(ctx context.Context, cfg *rest.Config) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	body, err := clientset.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("vcluster apiserver not ready: %w", err)
+	}
+	if string(body) != "ok" {
+		return fmt.Errorf("vcluster apiserver not ready: %s", body)
+	}
+	return nil
+}
+
+func IssueKubeconfig(ctx context.Context, cfg *rest.Config, contextName string, ttl time.Duration) ([]byte, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: userServiceAccount, Namespace: userNamespace}}
+	if _, err := clientset.CoreV1().ServiceAccounts(userNamespace).Create(ctx, serviceAccount, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("create vcluster service account: %w", err)
+	}
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: userServiceAccount},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: userServiceAccount, Namespace: userNamespace}},
+	}
+	if _, err := clientset.RbacV1().ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("create vcluster cluster role binding: %w", err)
+	}
+
+	expirationSeconds := int64(max(ttl, minTokenTTL).Seconds())
+	token, err := clientset.CoreV1().ServiceAccounts(userNamespace).CreateToken(ctx, userServiceAccount, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("request vcluster token: %w", err)
+	}
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[contextName] = &clientcmdapi.Cluster{
+		Server:                   cfg.Host,
+		CertificateAuthorityData: cfg.CAData,
+	}
+	kubeconfig.AuthInfos[contextName] = &clientcmdapi.AuthInfo{Token: token.Status.Token}
+	kubeconfig.Contexts[contextName] = &clientcmdapi.Context{Cluster: contextName, AuthInfo: contextName}
+	kubeconfig.CurrentContext = contextName
+	return clientcmd.Write(*kubeconfig)
+}